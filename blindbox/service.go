@@ -22,6 +22,9 @@ type SeriesConfig struct {
 	DisplayColor    string    `json:"displayColor"`
 	TextColor       string    `json:"textColor"`
 	Plushies        []Plushie `json:"plushies"        nullable:"false"`
+	// CommandAliases are extra chat commands that show the collection. The
+	// overlay has no use for them.
+	CommandAliases []string `json:"-"`
 }
 
 // Plushie is a catalog entry that can be awarded by a blind box.
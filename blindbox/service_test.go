@@ -173,7 +173,7 @@ func newBlindboxService(t *testing.T) (*blindbox.Service, *db.Queries, *sql.DB,
 			t.Errorf("failed to close database: %v", err)
 		}
 	})
-	cat, err := catalog.Load()
+	cat, err := catalog.Load("")
 	if err != nil {
 		t.Fatalf("failed to load catalog: %v", err)
 	}
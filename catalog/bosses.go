@@ -0,0 +1,111 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Boss is a foe for a boss raid. Chatters join during JoinWindow, then fight
+// it in rounds until it falls, they all fall, or Rounds run out and it
+// escapes.
+type Boss struct {
+	Key  string
+	Name string
+	// HP is the boss's hit points, plus HPPerFighter for each chatter who
+	// joins the raid.
+	HP           int64
+	HPPerFighter int64
+	Rounds       int
+	// Attacks is how many fighters the boss strikes each round. A struck
+	// fighter is knocked out unless their 1d20 plus DEX reaches Accuracy.
+	Attacks    int
+	Accuracy   int64
+	JoinWindow time.Duration
+	Reward     BossReward
+}
+
+// BossReward is what each fighter still standing gets when a boss falls:
+// a free blind box BlindBoxChance percent of the time, and otherwise between
+// StatMin and StatMax of a random stat.
+type BossReward struct {
+	StatMin        int64
+	StatMax        int64
+	BlindBoxChance int
+}
+
+type bossJSON struct {
+	Key               string         `json:"key"`
+	Name              string         `json:"name"`
+	HP                int64          `json:"hp"`
+	HPPerFighter      int64          `json:"hpPerFighter"`
+	Rounds            int            `json:"rounds"`
+	Attacks           int            `json:"attacks"`
+	Accuracy          int64          `json:"accuracy"`
+	JoinWindowSeconds int            `json:"joinWindowSeconds"`
+	Reward            bossRewardJSON `json:"reward"`
+}
+
+type bossRewardJSON struct {
+	StatMin        int64 `json:"statMin"`
+	StatMax        int64 `json:"statMax"`
+	BlindBoxChance int   `json:"blindBoxChance"`
+}
+
+func loadBosses() ([]Boss, error) {
+	var raw []bossJSON
+	if err := decodeJSON("config/bosses.json", &raw); err != nil {
+		return nil, err
+	}
+
+	bosses := make([]Boss, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, b := range raw {
+		boss, err := b.toBoss()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[boss.Key]; ok {
+			return nil, fmt.Errorf("duplicate boss %q", boss.Key)
+		}
+		seen[boss.Key] = struct{}{}
+		bosses = append(bosses, boss)
+	}
+	return bosses, nil
+}
+
+func (b bossJSON) toBoss() (Boss, error) {
+	if strings.TrimSpace(b.Key) == "" || strings.TrimSpace(b.Name) == "" {
+		return Boss{}, errors.New("boss key and name are required")
+	}
+	switch {
+	case b.HP < 1 || b.HPPerFighter < 0:
+		return Boss{}, fmt.Errorf("boss %q: hp must be positive and hpPerFighter must not be negative", b.Key)
+	case b.Rounds < 1:
+		return Boss{}, fmt.Errorf("boss %q: rounds must be positive", b.Key)
+	case b.Attacks < 0:
+		return Boss{}, fmt.Errorf("boss %q: attacks must not be negative", b.Key)
+	case b.JoinWindowSeconds < 1:
+		return Boss{}, fmt.Errorf("boss %q: joinWindowSeconds must be positive", b.Key)
+	case b.Reward.StatMin > b.Reward.StatMax:
+		return Boss{}, fmt.Errorf("boss %q: reward statMin must not be greater than statMax", b.Key)
+	case b.Reward.BlindBoxChance < 0 || b.Reward.BlindBoxChance > 100:
+		return Boss{}, fmt.Errorf("boss %q: reward blindBoxChance must be between 0 and 100", b.Key)
+	}
+	return Boss{
+		Key:          b.Key,
+		Name:         b.Name,
+		HP:           b.HP,
+		HPPerFighter: b.HPPerFighter,
+		Rounds:       b.Rounds,
+		Attacks:      b.Attacks,
+		Accuracy:     b.Accuracy,
+		JoinWindow:   time.Duration(b.JoinWindowSeconds) * time.Second,
+		Reward: BossReward{
+			StatMin:        b.Reward.StatMin,
+			StatMax:        b.Reward.StatMax,
+			BlindBoxChance: b.Reward.BlindBoxChance,
+		},
+	}, nil
+}
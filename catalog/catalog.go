@@ -8,19 +8,58 @@ import (
 	"io"
 	"io/fs"
 	"path"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/lukeramljak/charsibot/blindbox"
 	"github.com/lukeramljak/charsibot/stats"
 )
 
-//go:embed config/stats.json config/blind-box/*.json
+//go:embed config/stats.json config/rewards.json config/triggers.json config/messages.json config/bosses.json
+//go:embed config/blind-box/*.json
 var files embed.FS
 
+// Twitch limits on custom reward fields.
+const (
+	maxRewardTitleLength  = 45
+	maxRewardPromptLength = 200
+)
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
 type Catalog struct {
 	Stats  []stats.Definition
 	Series []blindbox.SeriesConfig
+	// Rewards holds every channel point reward the bot manages, including one
+	// per blind-box series.
+	Rewards  []Reward
+	Triggers []Trigger
+	Messages Messages
+	Bosses   []Boss
+}
+
+// Reward is a channel point reward the bot creates and keeps in sync on
+// Twitch. Key names the redemption handler that runs when it is redeemed.
+type Reward struct {
+	Key             string
+	Title           string
+	Prompt          string
+	Cost            int
+	Cooldown        time.Duration
+	BackgroundColor string
+	Enabled         bool
+	// Effects describe what redeeming the reward does. Rewards without
+	// effects, such as blind boxes, are handled in Go.
+	Effects []Effect
+}
+
+// BlindBoxRewardKey returns the handler key of a blind-box series' reward.
+func BlindBoxRewardKey(series string) string {
+	return "blind-box:" + series
 }
 
 type statDefinitionJSON struct {
@@ -33,16 +72,33 @@ type statDefinitionJSON struct {
 }
 
 type seriesJSON struct {
-	Series          string        `json:"series"`
-	AssetDir        string        `json:"assetDir"`
-	RedemptionTitle string        `json:"redemptionTitle"`
-	Name            string        `json:"name"`
-	RevealSound     string        `json:"revealSound"`
-	BoxFrontFace    string        `json:"boxFrontFace"`
-	BoxSideFace     string        `json:"boxSideFace"`
-	DisplayColor    string        `json:"displayColor"`
-	TextColor       string        `json:"textColor"`
-	Plushies        []plushieJSON `json:"plushies"`
+	Series          string             `json:"series"`
+	AssetDir        string             `json:"assetDir"`
+	RedemptionTitle string             `json:"redemptionTitle"`
+	CommandAliases  []string           `json:"commandAliases"`
+	Reward          rewardSettingsJSON `json:"reward"`
+	Name            string             `json:"name"`
+	RevealSound     string             `json:"revealSound"`
+	BoxFrontFace    string             `json:"boxFrontFace"`
+	BoxSideFace     string             `json:"boxSideFace"`
+	DisplayColor    string             `json:"displayColor"`
+	TextColor       string             `json:"textColor"`
+	Plushies        []plushieJSON      `json:"plushies"`
+}
+
+type rewardSettingsJSON struct {
+	Prompt          string `json:"prompt"`
+	Cost            int    `json:"cost"`
+	CooldownSeconds int    `json:"cooldownSeconds"`
+	BackgroundColor string `json:"backgroundColor"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+type rewardJSON struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	rewardSettingsJSON
+	Effects []effectJSON `json:"effects"`
 }
 
 type plushieJSON struct {
@@ -54,16 +110,41 @@ type plushieJSON struct {
 	EmptyImage string `json:"emptyImage"`
 }
 
-func Load() (Catalog, error) {
+// Load loads the embedded catalog. messagesPath optionally names a JSON file
+// of message variants that replace the defaults key by key.
+func Load(messagesPath string) (Catalog, error) {
 	stats, err := loadStats()
 	if err != nil {
 		return Catalog{}, err
 	}
-	series, err := loadSeries()
+	messages, err := loadMessages(messagesPath)
+	if err != nil {
+		return Catalog{}, err
+	}
+	series, seriesRewards, err := loadSeries()
 	if err != nil {
 		return Catalog{}, err
 	}
-	return Catalog{Stats: stats, Series: series}, nil
+	rewards, err := loadRewards(seriesRewards, stats, messages)
+	if err != nil {
+		return Catalog{}, err
+	}
+	triggers, err := loadTriggers()
+	if err != nil {
+		return Catalog{}, err
+	}
+	bosses, err := loadBosses()
+	if err != nil {
+		return Catalog{}, err
+	}
+	return Catalog{
+		Stats:    stats,
+		Series:   series,
+		Rewards:  rewards,
+		Triggers: triggers,
+		Messages: messages,
+		Bosses:   bosses,
+	}, nil
 }
 
 func loadStats() ([]stats.Definition, error) {
@@ -112,13 +193,14 @@ func loadStats() ([]stats.Definition, error) {
 	return definitions, nil
 }
 
-func loadSeries() ([]blindbox.SeriesConfig, error) {
+func loadSeries() ([]blindbox.SeriesConfig, []Reward, error) {
 	entries, err := fs.ReadDir(files, "config/blind-box")
 	if err != nil {
-		return nil, fmt.Errorf("read blind-box config dir: %w", err)
+		return nil, nil, fmt.Errorf("read blind-box config dir: %w", err)
 	}
 
 	series := make([]blindbox.SeriesConfig, 0, len(entries))
+	rewards := make([]Reward, 0, len(entries))
 	seen := make(map[string]struct{}, len(entries))
 
 	for _, entry := range entries {
@@ -127,27 +209,126 @@ func loadSeries() ([]blindbox.SeriesConfig, error) {
 		}
 		var raw seriesJSON
 		if err := decodeJSON(path.Join("config/blind-box", entry.Name()), &raw); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		cfg, err := raw.toSeriesConfig()
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+			return nil, nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		reward, err := rewardJSON{
+			Key:                BlindBoxRewardKey(raw.Series),
+			Title:              raw.RedemptionTitle,
+			rewardSettingsJSON: raw.Reward,
+		}.toReward()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", entry.Name(), err)
 		}
 		if _, ok := seen[cfg.Series]; ok {
-			return nil, fmt.Errorf("duplicate blind-box series %q", cfg.Series)
+			return nil, nil, fmt.Errorf("duplicate blind-box series %q", cfg.Series)
 		}
 		seen[cfg.Series] = struct{}{}
 		series = append(series, cfg)
+		rewards = append(rewards, reward)
 	}
 	if len(series) == 0 {
-		return nil, errors.New("at least one blind-box series is required")
+		return nil, nil, errors.New("at least one blind-box series is required")
 	}
 
 	sort.Slice(series, func(i, j int) bool {
 		return series[i].Series < series[j].Series
 	})
 
-	return series, nil
+	// A series' command aliases share the command namespace with every
+	// series name.
+	for _, cfg := range series {
+		for _, alias := range cfg.CommandAliases {
+			if _, ok := seen[alias]; ok {
+				return nil, nil, fmt.Errorf("%s: command alias %q is already in use", cfg.Series, alias)
+			}
+			seen[alias] = struct{}{}
+		}
+	}
+
+	return series, rewards, nil
+}
+
+// loadRewards combines the standalone rewards with those of the blind-box
+// series. Twitch rejects duplicate titles, ignoring case, so they must be
+// unique as well as the keys.
+func loadRewards(seriesRewards []Reward, definitions []stats.Definition, messages Messages) ([]Reward, error) {
+	var raw []rewardJSON
+	if err := decodeJSON("config/rewards.json", &raw); err != nil {
+		return nil, err
+	}
+
+	rewards := make([]Reward, 0, len(raw)+len(seriesRewards))
+	for _, r := range raw {
+		reward, err := r.toReward()
+		if err != nil {
+			return nil, err
+		}
+		if reward.Effects, err = parseEffects(r.Effects, definitions, messages); err != nil {
+			return nil, fmt.Errorf("reward %q: %w", r.Key, err)
+		}
+		rewards = append(rewards, reward)
+	}
+	rewards = append(rewards, seriesRewards...)
+
+	seenKeys := make(map[string]struct{}, len(rewards))
+	seenTitles := make(map[string]struct{}, len(rewards))
+	for _, reward := range rewards {
+		if _, ok := seenKeys[reward.Key]; ok {
+			return nil, fmt.Errorf("duplicate reward key %q", reward.Key)
+		}
+		title := strings.ToLower(reward.Title)
+		if _, ok := seenTitles[title]; ok {
+			return nil, fmt.Errorf("duplicate reward title %q", reward.Title)
+		}
+		seenKeys[reward.Key] = struct{}{}
+		seenTitles[title] = struct{}{}
+	}
+
+	sort.Slice(rewards, func(i, j int) bool {
+		return rewards[i].Key < rewards[j].Key
+	})
+
+	return rewards, nil
+}
+
+func (r rewardJSON) toReward() (Reward, error) {
+	if strings.TrimSpace(r.Key) == "" || strings.TrimSpace(r.Title) == "" {
+		return Reward{}, errors.New("reward key and title are required")
+	}
+	if utf8.RuneCountInString(r.Title) > maxRewardTitleLength {
+		return Reward{}, fmt.Errorf("reward %q: title is longer than %d characters", r.Key, maxRewardTitleLength)
+	}
+	if utf8.RuneCountInString(r.Prompt) > maxRewardPromptLength {
+		return Reward{}, fmt.Errorf("reward %q: prompt is longer than %d characters", r.Key, maxRewardPromptLength)
+	}
+	if r.Cost < 1 {
+		return Reward{}, fmt.Errorf("reward %q must have a positive cost", r.Key)
+	}
+	if r.CooldownSeconds < 0 {
+		return Reward{}, fmt.Errorf("reward %q: cooldownSeconds must not be negative", r.Key)
+	}
+	if r.BackgroundColor != "" && !hexColorPattern.MatchString(r.BackgroundColor) {
+		return Reward{}, fmt.Errorf("reward %q: backgroundColor must look like #RRGGBB", r.Key)
+	}
+
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+
+	return Reward{
+		Key:             r.Key,
+		Title:           r.Title,
+		Prompt:          r.Prompt,
+		Cost:            r.Cost,
+		Cooldown:        time.Duration(r.CooldownSeconds) * time.Second,
+		BackgroundColor: r.BackgroundColor,
+		Enabled:         enabled,
+	}, nil
 }
 
 func (s seriesJSON) toSeriesConfig() (blindbox.SeriesConfig, error) {
@@ -176,6 +357,13 @@ func (s seriesJSON) toSeriesConfig() (blindbox.SeriesConfig, error) {
 		DisplayColor:    s.DisplayColor,
 		TextColor:       s.TextColor,
 		Plushies:        make([]blindbox.Plushie, 0, len(s.Plushies)),
+		CommandAliases:  s.CommandAliases,
+	}
+
+	for _, alias := range s.CommandAliases {
+		if alias == "" || alias != strings.ToLower(alias) || strings.ContainsFunc(alias, unicode.IsSpace) {
+			return blindbox.SeriesConfig{}, fmt.Errorf("command alias %q must be a lowercase word", alias)
+		}
 	}
 
 	seen := make(map[string]struct{}, len(s.Plushies))
@@ -230,7 +418,11 @@ func decodeJSON(name string, dest any) error {
 	}
 	defer file.Close()
 
-	dec := json.NewDecoder(file)
+	return decodeJSONReader(name, file, dest)
+}
+
+func decodeJSONReader(name string, r io.Reader, dest any) error {
+	dec := json.NewDecoder(r)
 	dec.DisallowUnknownFields()
 
 	if err := dec.Decode(dest); err != nil {
@@ -1,9 +1,17 @@
 package catalog
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lukeramljak/charsibot/stats"
+)
 
 func TestLoadCatalog(t *testing.T) {
-	catalog, err := Load()
+	catalog, err := Load("")
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
@@ -28,6 +36,9 @@ func TestLoadCatalog(t *testing.T) {
 			if series.RevealSound != "/assets/blind-box/olliepops/reveal.mp3" {
 				t.Errorf("olliepop reveal sound = %q", series.RevealSound)
 			}
+			if !slices.Contains(series.CommandAliases, "olliepops") {
+				t.Errorf("olliepop command aliases = %v", series.CommandAliases)
+			}
 		}
 	}
 	if !coobubuFound {
@@ -61,6 +72,13 @@ func TestSeriesConfigValidation(t *testing.T) {
 				Plushies: []plushieJSON{{Key: "one", Weight: 1}, {Key: "two", Weight: 1}},
 			},
 		},
+		{
+			name: "requires lowercase single-word command aliases",
+			cfg: seriesJSON{
+				Series: "test", RedemptionTitle: "Test", Name: "Tests", CommandAliases: []string{"Two Words"},
+				Plushies: []plushieJSON{{Key: "one", Weight: 1, SortOrder: 1}},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -71,3 +89,313 @@ func TestSeriesConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadCatalogRewards(t *testing.T) {
+	catalog, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	rewards := make(map[string]Reward, len(catalog.Rewards))
+	for _, reward := range catalog.Rewards {
+		rewards[reward.Key] = reward
+	}
+	if _, ok := rewards["drink-a-potion"]; !ok {
+		t.Error("drink-a-potion reward missing")
+	}
+	for _, series := range catalog.Series {
+		reward, ok := rewards[BlindBoxRewardKey(series.Series)]
+		if !ok {
+			t.Errorf("reward for series %q missing", series.Series)
+			continue
+		}
+		if reward.Title != series.RedemptionTitle {
+			t.Errorf("series %q reward title = %q, want %q", series.Series, reward.Title, series.RedemptionTitle)
+		}
+	}
+}
+
+func TestRewardValidation(t *testing.T) {
+	disabled := false
+	valid := rewardJSON{Key: "test", Title: "Test", rewardSettingsJSON: rewardSettingsJSON{Cost: 1}}
+
+	reward, err := valid.toReward()
+	if err != nil {
+		t.Fatalf("toReward: %v", err)
+	}
+	if !reward.Enabled {
+		t.Error("rewards should be enabled unless configured otherwise")
+	}
+
+	off := valid
+	off.Enabled = &disabled
+	if reward, err = off.toReward(); err != nil || reward.Enabled {
+		t.Errorf("toReward with enabled=false = %+v, %v", reward, err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*rewardJSON)
+	}{
+		{name: "requires a title", modify: func(r *rewardJSON) { r.Title = "" }},
+		{name: "requires a positive cost", modify: func(r *rewardJSON) { r.Cost = 0 }},
+		{name: "limits the title length", modify: func(r *rewardJSON) { r.Title = strings.Repeat("a", 46) }},
+		{name: "rejects negative cooldowns", modify: func(r *rewardJSON) { r.CooldownSeconds = -1 }},
+		{name: "requires a hex colour", modify: func(r *rewardJSON) { r.BackgroundColor = "red" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.modify(&r)
+			if _, err := r.toReward(); err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+}
+
+func TestLoadCatalogTriggers(t *testing.T) {
+	catalog, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	idx := slices.IndexFunc(catalog.Triggers, func(trigger Trigger) bool { return trigger.Key == "no-coming" })
+	if idx < 0 {
+		t.Fatal("no-coming trigger missing")
+	}
+	trigger := catalog.Triggers[idx]
+	for message, want := range map[string]bool{
+		"I'm coming!":   true,
+		"CAME back":     true,
+		"income":        false,
+		"  No Coming  ": false,
+	} {
+		if got := trigger.Matches(message); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", message, got, want)
+		}
+	}
+}
+
+func TestTriggerValidation(t *testing.T) {
+	valid := triggerJSON{Key: "test", Words: []string{"hi"}, Chance: 50, Responses: []string{"hello {{.User}}"}}
+	trigger, err := valid.toTrigger()
+	if err != nil {
+		t.Fatalf("toTrigger: %v", err)
+	}
+	if trigger.Permission != "everyone" {
+		t.Fatalf("Permission = %q, want everyone by default", trigger.Permission)
+	}
+	moderated := valid
+	moderated.Permission = "moderator"
+	if trigger, err := moderated.toTrigger(); err != nil || trigger.Permission != "moderator" {
+		t.Fatalf("toTrigger() = %q, %v; want the moderator permission kept", trigger.Permission, err)
+	}
+	helpers := valid
+	helpers.Responses = []string{"hi {{mention .User}}"}
+	if _, err := helpers.toTrigger(); err != nil {
+		t.Fatalf("toTrigger: %v; want responses to have the message helpers", err)
+	}
+
+	tests := map[string]func(*triggerJSON){
+		"requires a key":              func(t *triggerJSON) { t.Key = "" },
+		"requires words or a pattern": func(t *triggerJSON) { t.Words = nil },
+		"rejects words and a pattern": func(t *triggerJSON) { t.Pattern = "hi" },
+		"rejects an invalid pattern":  func(t *triggerJSON) { t.Words, t.Pattern = nil, "(" },
+		"requires a chance":           func(t *triggerJSON) { t.Chance = 0 },
+		"rejects a chance above 100":  func(t *triggerJSON) { t.Chance = 101 },
+		"rejects a negative cooldown": func(t *triggerJSON) { t.CooldownSeconds = -1 },
+		"rejects unknown permissions": func(t *triggerJSON) { t.Permission = "admin" },
+		"requires a response":         func(t *triggerJSON) { t.Responses = nil },
+		"rejects unknown fields":      func(t *triggerJSON) { t.Responses = []string{"{{.Nope}}"} },
+		"rejects broken templates":    func(t *triggerJSON) { t.Responses = []string{"{{.User"} },
+		"rejects fields in skipped branches": func(t *triggerJSON) {
+			t.Responses = []string{"{{if .User}}{{.Nope}}{{end}}"}
+		},
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			trigger := valid
+			mutate(&trigger)
+			if _, err := trigger.toTrigger(); err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+}
+
+func TestLoadCatalogBosses(t *testing.T) {
+	catalog, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(catalog.Bosses) == 0 {
+		t.Fatal("no bosses loaded")
+	}
+	for _, boss := range catalog.Bosses {
+		if boss.JoinWindow <= 0 {
+			t.Errorf("boss %q join window = %s", boss.Key, boss.JoinWindow)
+		}
+	}
+}
+
+func TestBossValidation(t *testing.T) {
+	valid := bossJSON{Key: "test", Name: "the Test", HP: 10, Rounds: 3, JoinWindowSeconds: 30}
+	if _, err := valid.toBoss(); err != nil {
+		t.Fatalf("toBoss: %v", err)
+	}
+
+	tests := map[string]func(*bossJSON){
+		"requires a key":                   func(b *bossJSON) { b.Key = "" },
+		"requires a name":                  func(b *bossJSON) { b.Name = " " },
+		"requires hp":                      func(b *bossJSON) { b.HP = 0 },
+		"rejects negative hp per fighter":  func(b *bossJSON) { b.HPPerFighter = -1 },
+		"requires rounds":                  func(b *bossJSON) { b.Rounds = 0 },
+		"rejects negative attacks":         func(b *bossJSON) { b.Attacks = -1 },
+		"requires a join window":           func(b *bossJSON) { b.JoinWindowSeconds = 0 },
+		"rejects a reversed stat reward":   func(b *bossJSON) { b.Reward.StatMin = 2 },
+		"rejects a blind box chance > 100": func(b *bossJSON) { b.Reward.BlindBoxChance = 101 },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			boss := valid
+			mutate(&boss)
+			if _, err := boss.toBoss(); err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+}
+
+func TestEffectValidation(t *testing.T) {
+	definitions := []stats.Definition{{Name: "luck"}}
+	messages, err := loadMessages("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := int64(1)
+	minusOne := int64(-1)
+	tests := map[string]effectJSON{
+		"unknown type":          {Type: "explode"},
+		"modifyStat needs max":  {Type: "modifyStat", Min: &one},
+		"modifyStat min > max":  {Type: "modifyStat", Min: &one, Max: &minusOne},
+		"modifyStat known stat": {Type: "modifyStat", Stat: "charm", Min: &one, Max: &one},
+		"chance needs outcomes": {Type: "chance"},
+		"chance needs weights":  {Type: "chance", Outcomes: []outcomeJSON{{Weight: 0}}},
+		"nested effects": {
+			Type:     "chance",
+			Outcomes: []outcomeJSON{{Weight: 1, Effects: []effectJSON{{Type: "nope"}}}},
+		},
+		"message needs a key":   {Type: "message"},
+		"message reward key":    {Type: "message", Message: "stats"},
+		"message known key":     {Type: "message", Message: "reward.nope"},
+		"roll needs dice":       {Type: "roll"},
+		"critical needs tables": {Type: "critical"},
+		"critical weights": {
+			Type:            "critical",
+			CriticalFailure: []outcomeJSON{{Weight: 0}},
+		},
+	}
+	for name, effect := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := effect.toEffect(definitions, messages); err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+
+	for _, valid := range []effectJSON{
+		{Type: "modifyStat", Stat: "luck", Min: &minusOne, Max: &one},
+		{Type: "message", Message: "reward.tempt-the-dice"},
+		{Type: "roll", Dice: "1d20+LUCK"},
+		{Type: "critical", CriticalSuccess: []outcomeJSON{{Weight: 1}}},
+	} {
+		if _, err := valid.toEffect(definitions, messages); err != nil {
+			t.Fatalf("toEffect: %v", err)
+		}
+	}
+}
+
+func TestLoadMessages(t *testing.T) {
+	messages, err := loadMessages("")
+	if err != nil {
+		t.Fatalf("loadMessages: %v", err)
+	}
+	for key := range messageData() {
+		if !messages.Has(key) {
+			t.Errorf("message %q missing", key)
+		}
+	}
+
+	got, err := messages.Render(MessageStats, StatsData{
+		User:  "viewer",
+		Stats: []stats.UserStat{{ShortName: "STR", Value: 5}, {ShortName: "LUCK", Value: -2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "viewer's stats: STR: 5 | LUCK: -2"; got != want {
+		t.Errorf("stats = %q, want %q", got, want)
+	}
+	got, err = messages.Render(MessageRedemptionFailed, UserData{User: "viewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "@viewer sorry") {
+		t.Errorf("redemption failed = %q", got)
+	}
+	if _, err := messages.Render("nope", nil); err == nil {
+		t.Error("expected an error for an unknown message")
+	}
+}
+
+func TestMessageOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	writeOverrides := func(t *testing.T, overrides string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(overrides), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeOverrides(t, `{"stats.missing": ["{{mention .User}} who?", "never heard of {{.User}}"]}`)
+	messages, err := loadMessages(path)
+	if err != nil {
+		t.Fatalf("loadMessages: %v", err)
+	}
+	seen := map[string]bool{}
+	for range 100 {
+		got, err := messages.Render(MessageStatsMissing, UserData{User: "viewer"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[got] = true
+	}
+	if !seen["@viewer who?"] || !seen["never heard of viewer"] || len(seen) != 2 {
+		t.Errorf("rendered variants = %v", seen)
+	}
+	if got, _ := messages.Render(MessageCollectionsHeader, struct{}{}); !strings.HasPrefix(got, "The following") {
+		t.Errorf("collections header = %q, want the default", got)
+	}
+
+	tests := map[string]string{
+		"rejects unknown keys":               `{"stats.mising": ["hi"]}`,
+		"rejects empty variants":             `{"stats.missing": []}`,
+		"rejects unknown fields":             `{"stats.missing": ["{{.Stats}}"]}`,
+		"rejects fields in skipped branches": `{"duel.leaderboard": ["{{range .Standings}}{{.Username}}{{end}}"]}`,
+		"rejects fields of range variables":  `{"stats": ["{{range $i, $s := .Stats}}{{$s.Nmae}}{{end}}"]}`,
+		"rejects unknown helpers":            `{"stats.missing": ["{{shout .User}}"]}`,
+		"rejects broken templates":           `{"stats.missing": ["{{.User"]}`,
+	}
+	for name, overrides := range tests {
+		t.Run(name, func(t *testing.T) {
+			writeOverrides(t, overrides)
+			if _, err := loadMessages(path); err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+	if _, err := loadMessages(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected an error for a missing overrides file")
+	}
+}
@@ -0,0 +1,175 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// EffectType names what an Effect does.
+type EffectType string
+
+const (
+	// EffectModifyStat adds a random amount between Min and Max to Stat, or
+	// to a random stat when Stat is empty.
+	EffectModifyStat EffectType = "modifyStat"
+	// EffectChance runs the effects of one Outcome, picked by weight.
+	EffectChance EffectType = "chance"
+	// EffectMessage sends the catalog message named by Message to chat.
+	EffectMessage EffectType = "message"
+	// EffectShowStats sends the viewer's stats to chat.
+	EffectShowStats EffectType = "showStats"
+	// EffectOverlay tells the overlay about the redemption.
+	EffectOverlay EffectType = "overlay"
+	// EffectRoll rolls the Dice expression, like "1d20+LUCK", with the
+	// viewer's stats as modifiers and shows the roll on the overlay.
+	EffectRoll EffectType = "roll"
+	// EffectCritical runs one CriticalSuccess outcome when the last roll was a
+	// natural 20, or one CriticalFailure outcome when it was a natural 1.
+	EffectCritical EffectType = "critical"
+)
+
+// Effect is one step of a channel point reward that is described in the
+// catalog rather than in Go. A reward's effects run in order.
+type Effect struct {
+	Type     EffectType
+	Stat     string
+	Min      int64
+	Max      int64
+	Outcomes []Outcome
+	// Message is the key of a reward message, rendered with EffectData.
+	Message         string
+	Dice            string
+	CriticalSuccess []Outcome
+	CriticalFailure []Outcome
+}
+
+// Outcome is one branch of a chance effect.
+type Outcome struct {
+	Weight  int
+	Effects []Effect
+}
+
+// EffectData is what reward messages can refer to.
+type EffectData struct {
+	// User is the display name of the viewer who redeemed the reward.
+	User string
+	// Stat and Delta describe the last change made by a modifyStat effect.
+	Stat  stats.Definition
+	Delta int64
+	// Roll is the last roll made by a roll effect.
+	Roll RollData
+}
+
+// RollData describes a dice roll to messages.
+type RollData struct {
+	Expression string
+	// Detail shows each term of the roll, like "[17] + 1 (LUCK)".
+	Detail          string
+	Total           int64
+	CriticalSuccess bool
+	CriticalFailure bool
+}
+
+type effectJSON struct {
+	Type            string        `json:"type"`
+	Stat            string        `json:"stat"`
+	Min             *int64        `json:"min"`
+	Max             *int64        `json:"max"`
+	Outcomes        []outcomeJSON `json:"outcomes"`
+	Message         string        `json:"message"`
+	Dice            string        `json:"dice"`
+	CriticalSuccess []outcomeJSON `json:"criticalSuccess"`
+	CriticalFailure []outcomeJSON `json:"criticalFailure"`
+}
+
+type outcomeJSON struct {
+	Weight  int          `json:"weight"`
+	Effects []effectJSON `json:"effects"`
+}
+
+func parseEffects(raw []effectJSON, definitions []stats.Definition, messages Messages) ([]Effect, error) {
+	effects := make([]Effect, 0, len(raw))
+	for i, e := range raw {
+		effect, err := e.toEffect(definitions, messages)
+		if err != nil {
+			return nil, fmt.Errorf("effect %d: %w", i+1, err)
+		}
+		effects = append(effects, effect)
+	}
+	return effects, nil
+}
+
+func (e effectJSON) toEffect(definitions []stats.Definition, messages Messages) (Effect, error) {
+	effect := Effect{Type: EffectType(e.Type)}
+	switch effect.Type {
+	case EffectModifyStat:
+		if e.Min == nil || e.Max == nil {
+			return Effect{}, errors.New("modifyStat needs min and max")
+		}
+		if *e.Min > *e.Max {
+			return Effect{}, errors.New("modifyStat min must not be greater than max")
+		}
+		if e.Stat != "" && !slices.ContainsFunc(definitions, func(d stats.Definition) bool { return d.Name == e.Stat }) {
+			return Effect{}, fmt.Errorf("unknown stat %q", e.Stat)
+		}
+		effect.Stat, effect.Min, effect.Max = e.Stat, *e.Min, *e.Max
+	case EffectChance:
+		if len(e.Outcomes) == 0 {
+			return Effect{}, errors.New("chance needs at least one outcome")
+		}
+		outcomes, err := parseOutcomes(e.Outcomes, definitions, messages)
+		if err != nil {
+			return Effect{}, err
+		}
+		effect.Outcomes = outcomes
+	case EffectMessage:
+		if !strings.HasPrefix(e.Message, RewardMessagePrefix) {
+			return Effect{}, fmt.Errorf("message key %q must start with %q", e.Message, RewardMessagePrefix)
+		}
+		if !messages.Has(e.Message) {
+			return Effect{}, fmt.Errorf("unknown message %q", e.Message)
+		}
+		effect.Message = e.Message
+	case EffectRoll:
+		// The expression itself is parsed by the dice engine when the bot
+		// starts.
+		if strings.TrimSpace(e.Dice) == "" {
+			return Effect{}, errors.New("roll needs dice")
+		}
+		effect.Dice = e.Dice
+	case EffectCritical:
+		if len(e.CriticalSuccess) == 0 && len(e.CriticalFailure) == 0 {
+			return Effect{}, errors.New("critical needs criticalSuccess or criticalFailure outcomes")
+		}
+		var err error
+		if effect.CriticalSuccess, err = parseOutcomes(e.CriticalSuccess, definitions, messages); err != nil {
+			return Effect{}, fmt.Errorf("criticalSuccess: %w", err)
+		}
+		if effect.CriticalFailure, err = parseOutcomes(e.CriticalFailure, definitions, messages); err != nil {
+			return Effect{}, fmt.Errorf("criticalFailure: %w", err)
+		}
+	case EffectShowStats, EffectOverlay:
+	default:
+		return Effect{}, fmt.Errorf("unknown effect type %q", e.Type)
+	}
+	return effect, nil
+}
+
+func parseOutcomes(raw []outcomeJSON, definitions []stats.Definition, messages Messages) ([]Outcome, error) {
+	outcomes := make([]Outcome, 0, len(raw))
+	for i, o := range raw {
+		if o.Weight < 1 {
+			return nil, fmt.Errorf("outcome %d must have a positive weight", i+1)
+		}
+		effects, err := parseEffects(o.Effects, definitions, messages)
+		if err != nil {
+			return nil, fmt.Errorf("outcome %d: %w", i+1, err)
+		}
+		outcomes = append(outcomes, Outcome{Weight: o.Weight, Effects: effects})
+	}
+	return outcomes, nil
+}
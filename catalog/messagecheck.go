@@ -0,0 +1,236 @@
+package catalog
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"text/template"
+	"text/template/parse"
+)
+
+// checkFields walks every branch of a message template, including those that
+// rendering data would skip, and reports the first field it refers to that
+// data's type does not have. Fields of values whose type is only known when
+// the message is rendered, such as those of interfaces, are not checked.
+func checkFields(tmpl *template.Template, data any) error {
+	root := reflect.TypeOf(data)
+	c := fieldChecker{tree: tmpl.Tree, funcs: messageFuncs()}
+	return c.list(tmpl.Tree.Root, root, map[string]reflect.Type{"$": root})
+}
+
+// fieldChecker follows the type of dot and of each variable through a
+// template. A nil type is one that is not known until rendering.
+type fieldChecker struct {
+	tree  *parse.Tree
+	funcs template.FuncMap
+}
+
+func (c fieldChecker) list(list *parse.ListNode, dot reflect.Type, vars map[string]reflect.Type) error {
+	if list == nil {
+		return nil
+	}
+	for _, node := range list.Nodes {
+		if err := c.node(node, dot, vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c fieldChecker) node(node parse.Node, dot reflect.Type, vars map[string]reflect.Type) error {
+	switch node := node.(type) {
+	case *parse.ActionNode:
+		_, err := c.pipe(node.Pipe, dot, vars)
+		return err
+	case *parse.IfNode:
+		return c.branch(&node.BranchNode, dot, vars)
+	case *parse.WithNode:
+		return c.branch(&node.BranchNode, dot, vars)
+	case *parse.RangeNode:
+		return c.branch(&node.BranchNode, dot, vars)
+	case *parse.TemplateNode:
+		_, err := c.pipe(node.Pipe, dot, vars)
+		return err
+	case *parse.ListNode:
+		return c.list(node, dot, vars)
+	}
+	return nil
+}
+
+// branch checks an if, with or range and both of its branches. Variables
+// declared inside it go out of scope at its end.
+func (c fieldChecker) branch(node *parse.BranchNode, dot reflect.Type, vars map[string]reflect.Type) error {
+	scope := maps.Clone(vars)
+	typ, err := c.pipe(node.Pipe, dot, scope)
+	if err != nil {
+		return err
+	}
+	inner := dot
+	switch node.NodeType {
+	case parse.NodeWith:
+		inner = typ
+	case parse.NodeRange:
+		key, elem := rangeTypes(typ)
+		inner = elem
+		switch decl := node.Pipe.Decl; len(decl) {
+		case 1:
+			scope[decl[0].Ident[0]] = elem
+		case 2:
+			scope[decl[0].Ident[0]], scope[decl[1].Ident[0]] = key, elem
+		}
+	}
+	if err := c.list(node.List, inner, scope); err != nil {
+		return err
+	}
+	return c.list(node.ElseList, dot, maps.Clone(vars))
+}
+
+// pipe checks a pipeline and returns the type it evaluates to, declaring any
+// variables it assigns.
+func (c fieldChecker) pipe(pipe *parse.PipeNode, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type, error) {
+	if pipe == nil {
+		return nil, nil
+	}
+	var typ reflect.Type
+	for _, cmd := range pipe.Cmds {
+		var err error
+		if typ, err = c.command(cmd, dot, vars); err != nil {
+			return nil, err
+		}
+	}
+	for _, variable := range pipe.Decl {
+		vars[variable.Ident[0]] = typ
+	}
+	return typ, nil
+}
+
+func (c fieldChecker) command(
+	cmd *parse.CommandNode,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) (reflect.Type, error) {
+	var typ reflect.Type
+	for i, arg := range cmd.Args {
+		argType, err := c.arg(arg, dot, vars)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			typ = argType
+		}
+	}
+	return typ, nil
+}
+
+func (c fieldChecker) arg(node parse.Node, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type, error) {
+	switch node := node.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return c.fields(node, dot, node.Ident)
+	case *parse.VariableNode:
+		return c.fields(node, vars[node.Ident[0]], node.Ident[1:])
+	case *parse.ChainNode:
+		typ, err := c.arg(node.Node, dot, vars)
+		if err != nil {
+			return nil, err
+		}
+		return c.fields(node, typ, node.Field)
+	case *parse.PipeNode:
+		return c.pipe(node, dot, vars)
+	case *parse.IdentifierNode:
+		return c.funcResult(node.Ident), nil
+	case *parse.StringNode:
+		return reflect.TypeFor[string](), nil
+	case *parse.BoolNode:
+		return reflect.TypeFor[bool](), nil
+	}
+	return nil, nil
+}
+
+// fields follows a chain of field names from typ.
+func (c fieldChecker) fields(node parse.Node, typ reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if typ == nil {
+			return nil, nil
+		}
+		next, ok := fieldType(typ, name)
+		if !ok {
+			location, _ := c.tree.ErrorContext(node)
+			return nil, fmt.Errorf("%s: can't evaluate field %s in type %s", location, name, typ)
+		}
+		typ = next
+	}
+	return typ, nil
+}
+
+// funcResult returns the type a template function returns, when it is known.
+func (c fieldChecker) funcResult(name string) reflect.Type {
+	if fn, ok := c.funcs[name]; ok {
+		if typ := reflect.TypeOf(fn); typ.NumOut() > 0 {
+			return typ.Out(0)
+		}
+		return nil
+	}
+	switch name {
+	case "len":
+		return reflect.TypeFor[int]()
+	case "print", "printf", "println", "html", "js", "urlquery":
+		return reflect.TypeFor[string]()
+	case "not", "eq", "ne", "lt", "le", "gt", "ge":
+		return reflect.TypeFor[bool]()
+	}
+	return nil
+}
+
+// fieldType returns the type of the field, method result or map value name
+// refers to in typ, the way [text/template] looks it up.
+func fieldType(typ reflect.Type, name string) (reflect.Type, bool) {
+	method, ok := typ.MethodByName(name)
+	if !ok && typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+		method, ok = reflect.PointerTo(typ).MethodByName(name)
+	}
+	if ok {
+		if method.Type.NumOut() == 0 {
+			return nil, true
+		}
+		return method.Type.Out(0), true
+	}
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return fieldType(typ.Elem(), name)
+	case reflect.Struct:
+		field, ok := typ.FieldByName(name)
+		if !ok || !field.IsExported() {
+			return nil, false
+		}
+		return field.Type, true
+	case reflect.Map:
+		return typ.Elem(), true
+	case reflect.Interface:
+		return nil, true
+	}
+	return nil, false
+}
+
+// rangeTypes returns the key and element types of ranging over typ.
+func rangeTypes(typ reflect.Type) (reflect.Type, reflect.Type) {
+	if typ == nil {
+		return nil, nil
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeFor[int](), typ.Elem()
+	case reflect.Map:
+		return typ.Key(), typ.Elem()
+	case reflect.Chan:
+		return nil, typ.Elem()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return typ, typ
+	}
+	return nil, nil
+}
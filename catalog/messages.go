@@ -0,0 +1,490 @@
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// Keys of the messages the bot sends. Keys starting with RewardMessagePrefix
+// are sent by reward message effects.
+const (
+	MessageStats                 = "stats"
+	MessageStatsMissing          = "stats.missing"
+	MessageUnknownStat           = "stats.unknown"
+	MessageLeaderboard           = "leaderboard"
+	MessageLeaderboardFailed     = "leaderboard.failed"
+	MessageCollectionFailed      = "collection.failed"
+	MessageCollectionsHeader     = "collections.header"
+	MessageCollectionsEntry      = "collections.entry"
+	MessageCommandFailed         = "command.failed"
+	MessageCommandCooldown       = "command.cooldown"
+	MessageCommandUsage          = "command.usage"
+	MessageCommandBadInt         = "command.badInt"
+	MessageCommandUnknownUser    = "command.unknownUser"
+	MessageCommandList           = "help.commands"
+	MessageUnknownCommand        = "help.unknownCommand"
+	MessageRedemptionFailed      = "redemption.failed"
+	MessageRedemptionRefunded    = "redemption.refunded"
+	MessageUnknownSeries         = "mod.unknownSeries"
+	MessageUnknownPlushie        = "mod.unknownPlushie"
+	MessageGiveBoxFailed         = "mod.giveBoxFailed"
+	MessageGivePlushieFailed     = "mod.givePlushieFailed"
+	MessageSetStatFailed         = "mod.setStatFailed"
+	MessageResetCollectionFailed = "mod.resetCollectionFailed"
+	MessageCollectionReset       = "mod.collectionReset"
+	MessageRoll                  = "roll"
+	MessageRollNoDice            = "roll.noDice"
+	MessageRollTooManyDice       = "roll.tooManyDice"
+	MessageRollMalformed         = "roll.malformed"
+	MessageRollBadSides          = "roll.badSides"
+	MessageRollNumberTooBig      = "roll.numberTooBig"
+	MessageRollBadTerm           = "roll.badTerm"
+	MessageDuelSelf              = "duel.self"
+	MessageDuelNegativeWager     = "duel.negativeWager"
+	MessageDuelCannotAfford      = "duel.cannotAfford"
+	MessageDuelBusy              = "duel.busy"
+	MessageDuelNoChallenge       = "duel.noChallenge"
+	MessageDuelCalledOff         = "duel.calledOff"
+	MessageDuelFailed            = "duel.failed"
+	MessageDuelChallenge         = "duel.challenge"
+	MessageDuelDeclined          = "duel.declined"
+	MessageDuelExpired           = "duel.expired"
+	MessageDuelResult            = "duel.result"
+	MessageDuelLeaderboard       = "duel.leaderboard"
+	MessageDuelRecord            = "duel.record"
+	MessageBossNone              = "boss.none"
+	MessageBossUnknown           = "boss.unknown"
+	MessageBossBusy              = "boss.busy"
+	MessageBossAppeared          = "boss.appeared"
+	MessageBossUnopposed         = "boss.unopposed"
+	MessageBossRound             = "boss.round"
+	MessageBossVictory           = "boss.victory"
+	MessageBossDefeat            = "boss.defeat"
+
+	RewardMessagePrefix = "reward."
+)
+
+// messageData returns, for each message key, a zero value of the data it is
+// rendered with, so templates can be checked against its type at startup. Reward messages are
+// rendered with EffectData.
+func messageData() map[string]any {
+	return map[string]any{
+		MessageStats:                 StatsData{},
+		MessageStatsMissing:          UserData{},
+		MessageUnknownStat:           UnknownStatData{},
+		MessageLeaderboard:           LeaderboardData{},
+		MessageLeaderboardFailed:     struct{}{},
+		MessageCollectionFailed:      UserData{},
+		MessageCollectionsHeader:     struct{}{},
+		MessageCollectionsEntry:      blindbox.CompletedCollection{},
+		MessageCommandFailed:         struct{}{},
+		MessageCommandCooldown:       CooldownData{},
+		MessageCommandUsage:          UsageData{},
+		MessageCommandBadInt:         BadIntData{},
+		MessageCommandUnknownUser:    UnknownUserData{},
+		MessageCommandList:           CommandListData{},
+		MessageUnknownCommand:        UnknownCommandData{},
+		MessageRedemptionFailed:      UserData{},
+		MessageRedemptionRefunded:    UserData{},
+		MessageUnknownSeries:         UnknownSeriesData{},
+		MessageUnknownPlushie:        PlushieData{},
+		MessageGiveBoxFailed:         struct{}{},
+		MessageGivePlushieFailed:     struct{}{},
+		MessageSetStatFailed:         struct{}{},
+		MessageResetCollectionFailed: struct{}{},
+		MessageCollectionReset:       CollectionData{},
+		MessageRoll:                  RollMessageData{},
+		MessageRollNoDice:            RollErrorData{},
+		MessageRollTooManyDice:       RollErrorData{},
+		MessageRollMalformed:         RollErrorData{},
+		MessageRollBadSides:          RollErrorData{},
+		MessageRollNumberTooBig:      RollErrorData{},
+		MessageRollBadTerm:           RollErrorData{},
+		MessageDuelSelf:              struct{}{},
+		MessageDuelNegativeWager:     struct{}{},
+		MessageDuelCannotAfford:      DuelWagerData{},
+		MessageDuelBusy:              struct{}{},
+		MessageDuelNoChallenge:       struct{}{},
+		MessageDuelCalledOff:         DuelWagerData{},
+		MessageDuelFailed:            struct{}{},
+		MessageDuelChallenge:         DuelData{},
+		MessageDuelDeclined:          DuelData{},
+		MessageDuelExpired:           DuelData{},
+		MessageDuelResult:            DuelResultData{},
+		MessageDuelLeaderboard:       DuelLeaderboardData{},
+		MessageDuelRecord:            DuelRecordData{},
+		MessageBossNone:              struct{}{},
+		MessageBossUnknown:           UnknownBossData{},
+		MessageBossBusy:              struct{}{},
+		MessageBossAppeared:          BossData{},
+		MessageBossUnopposed:         BossData{},
+		MessageBossRound:             BossRoundData{},
+		MessageBossVictory:           BossResultData{},
+		MessageBossDefeat:            BossResultData{},
+	}
+}
+
+// UserData is what messages about a viewer can refer to.
+type UserData struct {
+	// User is the viewer's display name.
+	User string
+}
+
+// StatsData is what messages showing a viewer's stats can refer to.
+type StatsData struct {
+	User  string
+	Stats []stats.UserStat
+}
+
+// UnknownStatData is what messages about a stat name that matched no stat
+// can refer to.
+type UnknownStatData struct {
+	Stat string
+}
+
+// LeaderboardData is what messages listing who leads each stat can refer to.
+type LeaderboardData struct {
+	Rows []stats.LeaderboardRow
+}
+
+// CooldownData is what messages about a command on cooldown can refer to.
+// Remaining is rounded up to whole seconds, like "1m30s".
+type CooldownData struct {
+	Command   string
+	Remaining string
+}
+
+// UsageData is what messages about a command given the wrong arguments can
+// refer to. Usage is how to call the command, like "!setstat @user <stat>
+// <value>".
+type UsageData struct {
+	Usage string
+}
+
+// BadIntData is what messages about an argument that should have been a whole
+// number can refer to.
+type BadIntData struct {
+	Value string
+}
+
+// UnknownUserData is what messages about a user argument that matched no
+// Twitch user can refer to.
+type UnknownUserData struct {
+	User string
+}
+
+// CommandListData is what messages listing the commands a chatter can use
+// can refer to. Commands include the command prefix, and Help is the help
+// command.
+type CommandListData struct {
+	Commands []string
+	Help     string
+}
+
+// UnknownCommandData is what messages about a command that does not exist
+// can refer to. Commands is the command that lists the others.
+type UnknownCommandData struct {
+	Command  string
+	Commands string
+}
+
+// UnknownSeriesData is what messages about a series name that matched no
+// series can refer to. Choices are the series that exist.
+type UnknownSeriesData struct {
+	Series  string
+	Choices []string
+}
+
+// CollectionData is what messages about a viewer's blind-box collection can
+// refer to.
+type CollectionData struct {
+	User   string
+	Series blindbox.SeriesConfig
+}
+
+// PlushieData is what messages about a plushie that was asked for by key can
+// refer to.
+type PlushieData struct {
+	Key    string
+	Series blindbox.SeriesConfig
+}
+
+// RollMessageData is what messages about a chatter's dice roll can refer to.
+type RollMessageData struct {
+	User string
+	Roll RollData
+}
+
+// RollErrorData is what messages about dice that cannot be rolled can refer
+// to. Term is the part of the dice at fault, and Max the limit it broke, for
+// the messages that have them.
+type RollErrorData struct {
+	Term string
+	Max  int64
+}
+
+// DuelData is what messages about a duel can refer to. Stat is the zero
+// Definition for a duel of all stats.
+type DuelData struct {
+	Challenger string
+	Opponent   string
+	Stat       stats.Definition
+	Wager      int64
+	// Accept and Decline are the commands the opponent answers with.
+	Accept  string
+	Decline string
+}
+
+// DuelWagerData is what messages about a viewer without enough of Stat to
+// cover a duel's wager can refer to.
+type DuelWagerData struct {
+	User  string
+	Stat  stats.Definition
+	Wager int64
+}
+
+// DuelResultData is what messages about the outcome of a duel can refer to.
+type DuelResultData struct {
+	DuelData
+	Winner string
+	Loser  string
+}
+
+// DuelLeaderboardData is what messages listing the best duellists can refer
+// to.
+type DuelLeaderboardData struct {
+	Standings []DuelStanding
+}
+
+// DuelStanding is a viewer's place on the duel leaderboard.
+type DuelStanding struct {
+	User   string
+	Wins   int64
+	Losses int64
+}
+
+// DuelRecordData is what messages about a viewer's duel record can refer to.
+type DuelRecordData struct {
+	User   string
+	Wins   int64
+	Losses int64
+}
+
+// BossData is what messages about a boss raid can refer to.
+type BossData struct {
+	Boss Boss
+	// Join is the command chatters join the raid with, and Seconds how long
+	// they have to.
+	Join    string
+	Seconds int
+}
+
+// UnknownBossData is what messages about a boss key that matched no boss can
+// refer to. Choices are the keys of the bosses that exist.
+type UnknownBossData struct {
+	Boss    string
+	Choices []string
+}
+
+// BossRoundData is what messages about a round of a boss raid can refer to.
+type BossRoundData struct {
+	Boss  Boss
+	Round int
+	// Damage is what the fighters dealt this round, and HP what the boss has
+	// left of MaxHP.
+	Damage int64
+	HP     int64
+	MaxHP  int64
+	// Criticals and KnockedOut name the fighters who landed a critical hit
+	// or were knocked out this round.
+	Criticals  []string
+	KnockedOut []string
+}
+
+// BossResultData is what messages about the end of a boss raid can refer
+// to. Survivors name the fighters still standing.
+type BossResultData struct {
+	Boss      Boss
+	Rounds    int
+	Survivors []string
+	Rewards   []BossRewardData
+}
+
+// BossRewardData is what a survivor of a defeated boss won: a blind box
+// when BlindBox names one, and otherwise Delta of Stat.
+type BossRewardData struct {
+	User     string
+	Stat     stats.Definition
+	Delta    int64
+	BlindBox string
+}
+
+// messageFuncs returns the helpers available to message templates.
+func messageFuncs() template.FuncMap {
+	return template.FuncMap{
+		// mention turns a display name into a chat mention.
+		"mention": func(user string) string {
+			return "@" + user
+		},
+		// list joins names with commas.
+		"list": func(names []string) string {
+			return strings.Join(names, ", ")
+		},
+		// stats lists stat values, like "STR: 5 | INT: 3".
+		"stats": stats.FormatValues,
+		// plushies lists the names of a series' plushies.
+		"plushies": func(cfg blindbox.SeriesConfig) string {
+			names := make([]string, len(cfg.Plushies))
+			for i, plushie := range cfg.Plushies {
+				names[i] = plushie.Name
+			}
+			return strings.Join(names, ", ")
+		},
+		// plushieKeys lists the keys of a series' plushies, which is what chat
+		// commands take.
+		"plushieKeys": func(cfg blindbox.SeriesConfig) string {
+			keys := make([]string, len(cfg.Plushies))
+			for i, plushie := range cfg.Plushies {
+				keys[i] = plushie.Key
+			}
+			return strings.Join(keys, ", ")
+		},
+	}
+}
+
+// Messages are the bot's chat messages. Each key has one or more template
+// variants, one of which is picked at random each time it is sent.
+type Messages struct {
+	variants map[string][]*template.Template
+}
+
+// Has reports whether key names a message.
+func (m Messages) Has(key string) bool {
+	_, ok := m.variants[key]
+	return ok
+}
+
+// Render renders a random variant of the message key with data.
+func (m Messages) Render(key string, data any) (string, error) {
+	variants := m.variants[key]
+	if len(variants) == 0 {
+		return "", fmt.Errorf("unknown message %q", key)
+	}
+	var message strings.Builder
+	if err := variants[rand.IntN(len(variants))].Execute(&message, data); err != nil {
+		return "", fmt.Errorf("render message %q: %w", key, err)
+	}
+	return message.String(), nil
+}
+
+// loadMessages loads the default messages and replaces the variants of every
+// key set in the overrides file at overridesPath, if there is one.
+func loadMessages(overridesPath string) (Messages, error) {
+	var raw map[string][]string
+	if err := decodeJSON("config/messages.json", &raw); err != nil {
+		return Messages{}, err
+	}
+	for key := range messageData() {
+		if _, ok := raw[key]; !ok {
+			return Messages{}, fmt.Errorf("message %q is missing", key)
+		}
+	}
+
+	if overridesPath != "" {
+		overrides, err := readMessageOverrides(overridesPath)
+		if err != nil {
+			return Messages{}, err
+		}
+		for key, variants := range overrides {
+			if _, ok := raw[key]; !ok {
+				return Messages{}, fmt.Errorf("%s: unknown message %q", overridesPath, key)
+			}
+			raw[key] = variants
+		}
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := Messages{variants: make(map[string][]*template.Template, len(raw))}
+	for _, key := range keys {
+		variants, err := parseMessage(key, raw[key])
+		if err != nil {
+			return Messages{}, err
+		}
+		messages.variants[key] = variants
+	}
+	return messages, nil
+}
+
+func readMessageOverrides(name string) (map[string][]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	var overrides map[string][]string
+	if err := decodeJSONReader(name, file, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// parseMessage parses each variant of a message against the message's data.
+func parseMessage(key string, texts []string) ([]*template.Template, error) {
+	data, ok := messageData()[key]
+	if !ok {
+		if !strings.HasPrefix(key, RewardMessagePrefix) {
+			return nil, fmt.Errorf("unknown message %q", key)
+		}
+		data = EffectData{}
+	}
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("message %q needs at least one variant", key)
+	}
+
+	variants := make([]*template.Template, 0, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("message %q: variants must not be empty", key)
+		}
+		variant, err := parseTemplate(fmt.Sprintf("%s[%d]", key, i), text, data)
+		if err != nil {
+			return nil, fmt.Errorf("message %q: %w", key, err)
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// parseTemplate parses a chat template with the message helpers, checks the
+// fields of every branch against data's type and renders it once with data,
+// so that mistakes fail at startup rather than in chat.
+func parseTemplate(name, text string, data any) (*template.Template, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(messageFuncs()).
+		Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFields(tmpl, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
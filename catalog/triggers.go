@@ -0,0 +1,159 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Trigger is a running joke: a response the bot sometimes sends when a chat
+// message matches. A message matches when one of its words is in Words, or
+// when it matches Pattern, unless it is one of the Exclude messages.
+type Trigger struct {
+	Key     string
+	Words   []string
+	Pattern *regexp.Regexp
+	Exclude []string
+	// Chance is the percentage of matching messages that get a response.
+	Chance   int
+	Cooldown time.Duration
+	// Reply sends the response as a reply to the matching message.
+	Reply bool
+	// Permission is the lowest chat role whose messages can fire the trigger:
+	// everyone, subscriber, vip, moderator or broadcaster.
+	Permission string
+	// Responses are templates rendered with TriggerData; one is picked at
+	// random each time.
+	Responses []*template.Template
+}
+
+// TriggerData is what trigger response templates can refer to.
+type TriggerData struct {
+	// User is the display name of the chatter who sent the message.
+	User    string
+	Message string
+}
+
+type triggerJSON struct {
+	Key             string   `json:"key"`
+	Words           []string `json:"words"`
+	Pattern         string   `json:"pattern"`
+	Exclude         []string `json:"exclude"`
+	Chance          int      `json:"chance"`
+	CooldownSeconds int      `json:"cooldownSeconds"`
+	Reply           bool     `json:"reply"`
+	Permission      string   `json:"permission"`
+	Responses       []string `json:"responses"`
+}
+
+// Matches reports whether a chat message matches the trigger, before its
+// chance is rolled.
+func (t Trigger) Matches(message string) bool {
+	lower := strings.ToLower(strings.TrimSpace(message))
+	if slices.Contains(t.Exclude, lower) {
+		return false
+	}
+	if t.Pattern != nil {
+		return t.Pattern.MatchString(message)
+	}
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+	return slices.ContainsFunc(words, func(word string) bool {
+		return slices.Contains(t.Words, word)
+	})
+}
+
+func loadTriggers() ([]Trigger, error) {
+	var raw []triggerJSON
+	if err := decodeJSON("config/triggers.json", &raw); err != nil {
+		return nil, err
+	}
+
+	triggers := make([]Trigger, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, t := range raw {
+		trigger, err := t.toTrigger()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[trigger.Key]; ok {
+			return nil, fmt.Errorf("duplicate trigger %q", trigger.Key)
+		}
+		seen[trigger.Key] = struct{}{}
+		triggers = append(triggers, trigger)
+	}
+	return triggers, nil
+}
+
+func (t triggerJSON) toTrigger() (Trigger, error) {
+	if strings.TrimSpace(t.Key) == "" {
+		return Trigger{}, errors.New("trigger key is required")
+	}
+	if (len(t.Words) == 0) == (t.Pattern == "") {
+		return Trigger{}, fmt.Errorf("trigger %q needs either words or a pattern", t.Key)
+	}
+	if t.Chance < 1 || t.Chance > 100 {
+		return Trigger{}, fmt.Errorf("trigger %q: chance must be between 1 and 100", t.Key)
+	}
+	if t.CooldownSeconds < 0 {
+		return Trigger{}, fmt.Errorf("trigger %q: cooldownSeconds must not be negative", t.Key)
+	}
+	if len(t.Responses) == 0 {
+		return Trigger{}, fmt.Errorf("trigger %q needs at least one response", t.Key)
+	}
+	permission := t.Permission
+	switch permission {
+	case "":
+		permission = "everyone"
+	case "everyone", "subscriber", "vip", "moderator", "broadcaster":
+	default:
+		return Trigger{}, fmt.Errorf(
+			"trigger %q: unknown permission %q, want everyone, subscriber, vip, moderator or broadcaster",
+			t.Key, t.Permission,
+		)
+	}
+
+	trigger := Trigger{
+		Key:        t.Key,
+		Chance:     t.Chance,
+		Cooldown:   time.Duration(t.CooldownSeconds) * time.Second,
+		Reply:      t.Reply,
+		Permission: permission,
+		Responses:  make([]*template.Template, 0, len(t.Responses)),
+	}
+	for _, word := range t.Words {
+		trigger.Words = append(trigger.Words, strings.ToLower(word))
+	}
+	for _, message := range t.Exclude {
+		trigger.Exclude = append(trigger.Exclude, strings.ToLower(strings.TrimSpace(message)))
+	}
+	if t.Pattern != "" {
+		pattern, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return Trigger{}, fmt.Errorf("trigger %q: %w", t.Key, err)
+		}
+		trigger.Pattern = pattern
+	}
+	for i, text := range t.Responses {
+		response, err := parseTriggerResponse(fmt.Sprintf("%s[%d]", t.Key, i), text)
+		if err != nil {
+			return Trigger{}, fmt.Errorf("trigger %q: %w", t.Key, err)
+		}
+		trigger.Responses = append(trigger.Responses, response)
+	}
+	return trigger, nil
+}
+
+// parseTriggerResponse parses a response template the way chat messages are
+// parsed, against TriggerData.
+func parseTriggerResponse(name, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("responses must not be empty")
+	}
+	return parseTemplate(name, text, TriggerData{})
+}
@@ -0,0 +1,70 @@
+package charsibot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commandAliases maps each alias to the name of the command it runs. An alias
+// may not shadow a command or another alias.
+func commandAliases(commands map[string]Command) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for name, command := range commands {
+		for _, alias := range command.Aliases {
+			if _, ok := commands[alias]; ok {
+				return nil, fmt.Errorf("alias %q of !%s is already a command", alias, name)
+			}
+			if other, ok := aliases[alias]; ok && other != name {
+				return nil, fmt.Errorf("alias %q is used by both !%s and !%s", alias, other, name)
+			}
+			aliases[alias] = name
+		}
+	}
+	return aliases, nil
+}
+
+// commandPrefixes returns the configured command prefixes, or "!" when none
+// are configured.
+func (b *Bot) commandPrefixes() []string {
+	if len(b.config.CommandPrefixes) == 0 {
+		return defaultCommandPrefixes()
+	}
+	return b.config.CommandPrefixes
+}
+
+// commandPrefix is the prefix the bot uses when it refers to a command.
+func (b *Bot) commandPrefix() string {
+	return b.commandPrefixes()[0]
+}
+
+// commandName returns the lowercased command a chat message invokes, without
+// its prefix. The longest matching prefix wins, so "!!" and "!" can coexist.
+func (b *Bot) commandName(text string) (string, bool) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 {
+		return "", false
+	}
+	prefix := ""
+	for _, candidate := range b.commandPrefixes() {
+		if len(candidate) > len(prefix) && strings.HasPrefix(fields[0], candidate) {
+			prefix = candidate
+		}
+	}
+	name := strings.TrimPrefix(fields[0], prefix)
+	if prefix == "" || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// lookupCommand finds a command by name or alias and returns its name.
+func (b *Bot) lookupCommand(name string) (string, Command, bool) {
+	if command, ok := b.commands[name]; ok {
+		return name, command, true
+	}
+	if target, ok := b.aliases[name]; ok {
+		command, ok := b.commands[target]
+		return target, command, ok
+	}
+	return "", Command{}, false
+}
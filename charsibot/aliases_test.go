@@ -0,0 +1,91 @@
+package charsibot
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/blindbox"
+)
+
+func TestCommandAliasesRejectCollisions(t *testing.T) {
+	noop := func(context.Context, *Bot, twitch.EventChannelChatMessage, Args) {}
+	tests := map[string]map[string]Command{
+		"shadows command": {
+			"leaderboard": {Execute: noop, Aliases: []string{"stats"}},
+			"stats":       {Execute: noop},
+		},
+		"shared alias": {
+			"leaderboard": {Execute: noop, Aliases: []string{"top"}},
+			"stats":       {Execute: noop, Aliases: []string{"top"}},
+		},
+	}
+	for name, commands := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := commandAliases(commands); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	if _, err := commandAliases(Commands(testCatalog(t).Series)); err != nil {
+		t.Fatalf("built-in commands: %v", err)
+	}
+}
+
+func TestProcessCommandResolvesAliasesAndPrefixes(t *testing.T) {
+	runs := map[string]int{}
+	b := createTestBot(t)
+	b.config.CommandPrefixes = []string{"!", "?", "!!"}
+	b.commands = map[string]Command{
+		"olliepop": {
+			Aliases: []string{"olliepops"},
+			Execute: func(context.Context, *Bot, twitch.EventChannelChatMessage, Args) { runs["olliepop"]++ },
+		},
+		"!shout": {
+			Execute: func(context.Context, *Bot, twitch.EventChannelChatMessage, Args) { runs["!shout"]++ },
+		},
+	}
+	aliases, err := commandAliases(b.commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.aliases = aliases
+
+	for _, text := range []string{"!olliepop", "?OLLIEPOPS", "!!olliepop", "#olliepop", "!!!shout", "! olliepop"} {
+		b.processCommand(twitch.EventChannelChatMessage{Message: twitch.ChatMessage{Text: text}})
+	}
+	if runs["olliepop"] != 3 || runs["!shout"] != 1 {
+		t.Fatalf("runs = %v, want three olliepops and one shout", runs)
+	}
+}
+
+func TestHelpResolvesAliases(t *testing.T) {
+	b := createTestBot(t)
+	b.config.CommandPrefixes = []string{"?"}
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.commands = Commands([]blindbox.SeriesConfig{
+		{Series: "olliepop", Name: "Olliepops", CommandAliases: []string{"olliepops"}},
+	})
+	aliases, err := commandAliases(b.commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.aliases = aliases
+
+	event := chatFrom("u1")
+	event.MessageId = "m1"
+	event.Message.Text = "?help ?olliepops"
+	b.processCommand(event)
+
+	if len(b.chat.replies) != 1 {
+		t.Fatalf("replies = %+v", b.chat.replies)
+	}
+	got := b.chat.replies[0].Message
+	if !strings.HasPrefix(got, "?olliepop [@user]") || !strings.HasSuffix(got, "Also ?olliepops.") {
+		t.Fatalf("help = %q", got)
+	}
+}
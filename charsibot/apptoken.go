@@ -0,0 +1,149 @@
+package charsibot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// appTokenRefreshMargin is how long before expiry the app access token is renewed.
+const appTokenRefreshMargin = 5 * time.Minute
+
+// appTokenSource hands out the app access token and renews it before it expires.
+type appTokenSource struct {
+	mu        sync.Mutex
+	request   func() (string, time.Duration, error)
+	now       func() time.Time
+	token     string
+	expiresAt time.Time
+	// refreshing is the token request in flight, if any; concurrent callers
+	// wait for it instead of requesting a token of their own.
+	refreshing *appTokenCall
+}
+
+type appTokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// newAppTokenSource requests app access tokens with the client credentials grant.
+func newAppTokenSource(clientID, clientSecret string) (*appTokenSource, error) {
+	authClient, err := helix.NewClient(&helix.Options{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create auth helix client: %w", err)
+	}
+	return &appTokenSource{
+		request: func() (string, time.Duration, error) {
+			resp, err := authClient.RequestAppAccessToken(nil)
+			if err != nil {
+				return "", 0, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				return "", 0, fmt.Errorf("status %d: %s", resp.StatusCode, resp.ErrorMessage)
+			}
+			return resp.Data.AccessToken, time.Duration(resp.Data.ExpiresIn) * time.Second, nil
+		},
+		now: time.Now,
+	}, nil
+}
+
+// Token returns the current app access token, requesting a new one when there
+// is none or the current one is about to expire. Only one request is in flight
+// at a time: other callers wait for it and share its result.
+func (s *appTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	if s.token != "" && s.now().Add(appTokenRefreshMargin).Before(s.expiresAt) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	if call := s.refreshing; call != nil {
+		s.mu.Unlock()
+		<-call.done
+		return call.token, call.err
+	}
+	call := &appTokenCall{done: make(chan struct{})}
+	s.refreshing = call
+	s.mu.Unlock()
+
+	token, expiresIn, err := s.request()
+	switch {
+	case err != nil:
+		call.err = fmt.Errorf("get app access token: %w", err)
+	case token == "":
+		call.err = errors.New("get app access token: empty token")
+	default:
+		call.token = token
+	}
+
+	s.mu.Lock()
+	s.refreshing = nil
+	if call.err == nil {
+		s.token = token
+		s.expiresAt = s.now().Add(expiresIn)
+	}
+	s.mu.Unlock()
+	close(call.done)
+	return call.token, call.err
+}
+
+// Invalidate discards token if it is still the current one, forcing the next
+// call to Token to request a replacement.
+func (s *appTokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// appTokenTransport authenticates requests with the app access token and
+// retries once with a fresh token when Twitch answers 401 Unauthorized.
+type appTokenTransport struct {
+	source *appTokenSource
+	base   http.RoundTripper
+}
+
+func (t *appTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// The body has been consumed and cannot be replayed.
+		return resp, nil
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	t.source.Invalidate(token)
+	if token, err = t.source.Token(); err != nil {
+		return nil, err
+	}
+	retry := withBearer(req, token)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(retry)
+}
+
+func withBearer(req *http.Request, token string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return clone
+}
@@ -0,0 +1,149 @@
+package charsibot
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCountingTokenSource(expiresIn time.Duration) (*appTokenSource, *int) {
+	requests := 0
+	return &appTokenSource{
+		request: func() (string, time.Duration, error) {
+			requests++
+			return fmt.Sprintf("token-%d", requests), expiresIn, nil
+		},
+		now: time.Now,
+	}, &requests
+}
+
+func TestAppTokenSourceRenewsBeforeExpiry(t *testing.T) {
+	source, requests := newCountingTokenSource(time.Hour)
+	for range 3 {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "token-1" {
+			t.Fatalf("token = %q, want cached token-1", token)
+		}
+	}
+
+	source.now = func() time.Time { return time.Now().Add(time.Hour - time.Minute) }
+	token, err := source.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token-2" || *requests != 2 {
+		t.Fatalf("token = %q after %d requests, want renewed token-2", token, *requests)
+	}
+}
+
+func TestAppTokenSourceSharesOneRequestBetweenCallers(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	source := &appTokenSource{
+		request: func() (string, time.Duration, error) {
+			requests.Add(1)
+			<-release
+			return "token-1", time.Hour, nil
+		},
+		now: time.Now,
+	}
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 3)
+	for i := range tokens {
+		wg.Go(func() {
+			token, err := source.Token()
+			if err != nil {
+				t.Error(err)
+			}
+			tokens[i] = token
+		})
+	}
+	// Invalidate takes the lock, so it returning shows the lock is free while
+	// the request is in flight.
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	source.Invalidate("token-0")
+	close(release)
+	wg.Wait()
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("token requests = %d, want 1 shared request", n)
+	}
+	for i, token := range tokens {
+		if token != "token-1" {
+			t.Errorf("caller %d token = %q, want token-1", i, token)
+		}
+	}
+}
+
+func TestAppTokenTransportRetriesOnceAfterUnauthorized(t *testing.T) {
+	source, requests := newCountingTokenSource(time.Hour)
+	var bodies []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	client := &http.Client{Transport: &appTokenTransport{source: source, base: http.DefaultTransport}}
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodPost, api.URL, strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want retried request to succeed", response.StatusCode)
+	}
+	if *requests != 2 {
+		t.Errorf("token requests = %d, want 2", *requests)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"a":1}` {
+		t.Errorf("bodies = %q, want body replayed on retry", bodies)
+	}
+}
+
+func TestAppTokenTransportGivesUpAfterSecondUnauthorized(t *testing.T) {
+	source, requests := newCountingTokenSource(time.Hour)
+	calls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer api.Close()
+
+	client := &http.Client{Transport: &appTokenTransport{source: source, base: http.DefaultTransport}}
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, api.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusUnauthorized || calls != 2 || *requests != 2 {
+		t.Fatalf("status = %d after %d calls and %d token requests", response.StatusCode, calls, *requests)
+	}
+}
@@ -0,0 +1,156 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+)
+
+// ArgKind is the type a command argument is parsed as.
+type ArgKind int
+
+const (
+	// ArgWord is a single word, passed through as typed.
+	ArgWord ArgKind = iota
+	// ArgInt is a whole number.
+	ArgInt
+	// ArgUser is a chatter, written as @login or login.
+	ArgUser
+)
+
+// Arg declares one positional argument of a command. Optional arguments must
+// come after the required ones.
+type Arg struct {
+	Name     string
+	Kind     ArgKind
+	Optional bool
+}
+
+func (a Arg) usage() string {
+	name := "<" + a.Name + ">"
+	if a.Kind == ArgUser {
+		name = "@" + a.Name
+	}
+	if a.Optional {
+		return "[" + name + "]"
+	}
+	return name
+}
+
+// Args holds a command's parsed arguments by name. Optional arguments that
+// were not given are absent.
+type Args struct {
+	words map[string]string
+	ints  map[string]int64
+	users map[string]twitchUser
+}
+
+// Word returns a word argument, or "" when it was not given.
+func (a Args) Word(name string) string {
+	return a.words[name]
+}
+
+// Int returns a whole number argument, or 0 when it was not given.
+func (a Args) Int(name string) int64 {
+	return a.ints[name]
+}
+
+// User returns a user argument and whether it was given.
+func (a Args) User(name string) (twitchUser, bool) {
+	user, ok := a.users[name]
+	return user, ok
+}
+
+// argError is a problem with a command's arguments that is shown to the
+// chatter as the catalog message key, rendered with data.
+type argError struct {
+	key  string
+	data any
+}
+
+func (e *argError) Error() string {
+	return fmt.Sprintf("%s: %+v", e.key, e.data)
+}
+
+// Usage returns how to call the command, e.g. "!setstat @user <stat> <value>".
+func (c Command) Usage(prefix, name string) string {
+	parts := []string{prefix + name}
+	for _, arg := range c.Args {
+		parts = append(parts, arg.usage())
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseArgs parses the words after the command name against command.Args.
+// Commands that declare no arguments ignore whatever follows them.
+func (b *Bot) parseArgs(
+	ctx context.Context,
+	name string,
+	command Command,
+	event twitch.EventChannelChatMessage,
+) (Args, error) {
+	args := Args{
+		words: map[string]string{},
+		ints:  map[string]int64{},
+		users: map[string]twitchUser{},
+	}
+	if len(command.Args) == 0 {
+		return args, nil
+	}
+
+	words := commandArgs(event)
+	required := 0
+	for _, arg := range command.Args {
+		if !arg.Optional {
+			required++
+		}
+	}
+	if len(words) < required || len(words) > len(command.Args) {
+		return Args{}, &argError{
+			key:  catalog.MessageCommandUsage,
+			data: catalog.UsageData{Usage: command.Usage(b.commandPrefix(), name)},
+		}
+	}
+
+	for i, word := range words {
+		arg := command.Args[i]
+		switch arg.Kind {
+		case ArgWord:
+			args.words[arg.Name] = word
+		case ArgInt:
+			value, err := strconv.ParseInt(word, 10, 64)
+			if err != nil {
+				return Args{}, &argError{key: catalog.MessageCommandBadInt, data: catalog.BadIntData{Value: word}}
+			}
+			args.ints[arg.Name] = value
+		case ArgUser:
+			user, err := b.resolveUser(ctx, event, word)
+			if errors.Is(err, errUserNotFound) {
+				return Args{}, &argError{
+					key:  catalog.MessageCommandUnknownUser,
+					data: catalog.UnknownUserData{User: word},
+				}
+			}
+			if err != nil {
+				return Args{}, fmt.Errorf("look up %s: %w", word, err)
+			}
+			args.users[arg.Name] = user
+		}
+	}
+	return args, nil
+}
+
+// commandArgs returns the words of a command message after the command itself.
+func commandArgs(event twitch.EventChannelChatMessage) []string {
+	fields := strings.Fields(event.Message.Text)
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields[1:]
+}
@@ -0,0 +1,98 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/server"
+)
+
+func TestCommandUsage(t *testing.T) {
+	command := Command{Args: []Arg{
+		{Name: "user", Kind: ArgUser},
+		{Name: "stat"},
+		{Name: "value", Kind: ArgInt, Optional: true},
+	}}
+	if got, want := command.Usage("!", "setstat"), "!setstat @user <stat> [<value>]"; got != want {
+		t.Fatalf("usage = %q, want %q", got, want)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	command := Command{Args: []Arg{
+		{Name: "user", Kind: ArgUser},
+		{Name: "count", Kind: ArgInt, Optional: true},
+	}}
+	mention := func(text string) twitch.EventChannelChatMessage {
+		return twitch.EventChannelChatMessage{Message: twitch.ChatMessage{
+			Text: text,
+			Fragments: []twitch.ChatMessageFragment{
+				{Type: "text", Text: "!test "},
+				{Type: "mention", Text: "@Friend", Mention: &twitch.ChatMessageFragmentMention{
+					UserID: "friend-1", UserLogin: "friend", UserName: "Friend",
+				}},
+			},
+		}}
+	}
+	// No Helix client is set, so users must come from the fragments.
+	b := createTestBot(t)
+
+	args, err := b.parseArgs(context.Background(), "test", command, mention("!test @Friend 3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := args.User("user"); !ok || user.ID != "friend-1" || user.DisplayName != "Friend" {
+		t.Fatalf("user = %+v, %v", user, ok)
+	}
+	if args.Int("count") != 3 {
+		t.Fatalf("count = %d, want 3", args.Int("count"))
+	}
+
+	for _, text := range []string{"!test", "!test @Friend 3 4", "!test @Friend three"} {
+		_, err := b.parseArgs(context.Background(), "test", command, mention(text))
+		var argErr *argError
+		if !errors.As(err, &argErr) {
+			t.Fatalf("%q: err = %v, want an argument error", text, err)
+		}
+	}
+}
+
+func TestStatsAndSeriesCommandsTargetMentionedUser(t *testing.T) {
+	b, events := createTestBotForModCommands(t)
+	ctx := context.Background()
+	if _, err := b.statsService.GetOrCreateStats(ctx, "friend-1", "Friend"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.blindboxService.AddPlushieToCollection(ctx, "friend-1", "Friend", "coobubu", "cutey"); err != nil {
+		t.Fatal(err)
+	}
+	message := func(text string) twitch.EventChannelChatMessage {
+		event := chatFrom("viewer-1")
+		event.ChatterUserName = "viewer"
+		event.MessageId = "m1"
+		event.Message.Text = text
+		return event
+	}
+
+	b.processCommand(message("!stats @friend"))
+	if len(b.chat.replies) != 1 || !strings.HasPrefix(b.chat.replies[0].Message, "Friend") {
+		t.Fatalf("replies = %+v, want Friend's stats", b.chat.replies)
+	}
+
+	b.processCommand(message("!coobubu friend"))
+	event := <-events
+	data, ok := event.Data.(blindbox.BlindBoxDisplayData)
+	if event.Type != server.EventTypeCollectionDisplay || !ok || data.Username != "Friend" || len(data.Collection) != 1 {
+		t.Fatalf("overlay event = %+v", event)
+	}
+
+	b.processCommand(message("!stats @nobody"))
+	if len(b.chat.replies) != 2 || !strings.Contains(b.chat.replies[1].Message, "Couldn't find") {
+		t.Fatalf("replies = %+v, want a not found reply", b.chat.replies)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"log/slog"
 	"math/rand/v2"
 	"net/http"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,8 +15,15 @@ import (
 	"github.com/nicklaw5/helix/v2"
 
 	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/cooldowns"
+	"github.com/lukeramljak/charsibot/dedupe"
+	"github.com/lukeramljak/charsibot/duels"
+	"github.com/lukeramljak/charsibot/raids"
+	"github.com/lukeramljak/charsibot/rewards"
 	"github.com/lukeramljak/charsibot/server"
 	"github.com/lukeramljak/charsibot/stats"
+	"github.com/lukeramljak/charsibot/tokens"
 )
 
 type Bot struct {
@@ -25,179 +31,216 @@ type Bot struct {
 	logger *slog.Logger
 
 	commands    map[string]Command
+	aliases     map[string]string
 	redemptions map[string]RedemptionFunc
 	triggers    []Trigger
+	messages    catalog.Messages
+	// series are the blind-box series raid survivors can win a box from.
+	series []blindbox.SeriesConfig
+
+	// rewards are the catalog's channel point rewards, created on Twitch by
+	// syncRewards. rewardRegistry maps their Twitch IDs to handler keys.
+	rewards        []catalog.Reward
+	rewardRegistry *rewards.Service
 
 	statsService    *stats.Service
 	blindboxService *blindbox.Service
-
-	twitchClient *twitch.Client
-	helixClient  *helix.Client
-	conduitID    string
-
-	broadcast    func(server.OverlayEvent)
+	dedupe          *dedupe.Service
+	cooldowns       *cooldowns.Tracker
+	duels           *duels.Service
+	raids           *raids.Service
+	tokens          *tokens.Service
+
+	helixClient *helix.Client
+	chat        *chatQueue
+	twitchAPI   *twitchAPI
+	// streamerAPI calls Helix with the broadcaster's user token. It is nil
+	// when no token storage is configured.
+	streamerAPI *twitchAPI
+	conduitID   string
+	catchUp     chan struct{}
+	// redeeming holds the IDs of the redemptions being handled.
+	redeemingMu sync.Mutex
+	redeeming   map[string]struct{}
+
+	subscriptions *subscriptionReconciler
+	shardsMu      sync.Mutex
+	shards        []*eventSubShard
+
+	// raid is the boss raid state machine, and bosses the bosses a raid can
+	// summon.
+	raid           raid
+	bosses         []catalog.Boss
+	raidRoundDelay time.Duration
+
+	broadcast func(server.OverlayEvent)
+	// ctx is done once the bot shuts down, and cancel shuts it down.
+	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
 	shuttingDown atomic.Bool
 }
 
-const (
-	reconnectDelay = 10 * time.Second
-	handlerTimeout = 10 * time.Second
-)
-
-var errReconnectRequested = errors.New("reconnect requested")
+const handlerTimeout = 10 * time.Second
 
 type SendMessageParams struct {
 	Message              string
 	ReplyParentMessageID string
 }
 
-// New creates a Bot with commands and redemptions registered from the catalog.
-// broadcast is called for each overlay event the bot emits.
-func New(
-	cfg Config,
-	logger *slog.Logger,
-	statsService *stats.Service,
-	blindboxService *blindbox.Service,
-	seriesConfigs []blindbox.SeriesConfig,
-	broadcast func(server.OverlayEvent),
-) (*Bot, error) {
-	return &Bot{
-		config:          cfg,
-		logger:          logger,
-		commands:        Commands(seriesConfigs),
-		redemptions:     Redemptions(seriesConfigs),
-		triggers:        Triggers(),
-		statsService:    statsService,
-		blindboxService: blindboxService,
-		broadcast:       broadcast,
-	}, nil
+// Deps are the services and catalog content a Bot is built from.
+type Deps struct {
+	StatsService    *stats.Service
+	BlindBoxService *blindbox.Service
+	Dedupe          *dedupe.Service
+	Tokens          *tokens.Service
+	// RewardRegistry maps channel point reward IDs to redemption handlers.
+	RewardRegistry *rewards.Service
+	// Cooldowns tracks command cooldowns. An in-memory tracker is used when
+	// it is nil.
+	Cooldowns *cooldowns.Tracker
+	Duels     *duels.Service
+	Raids     *raids.Service
+
+	Series   []blindbox.SeriesConfig
+	Rewards  []catalog.Reward
+	Triggers []catalog.Trigger
+	Bosses   []catalog.Boss
+	Messages catalog.Messages
+	// Broadcast is called for each overlay event the bot emits.
+	Broadcast func(server.OverlayEvent)
 }
 
-func (b *Bot) Start() error {
-	ctx, cancel := context.WithCancel(context.Background())
-	b.cancel = cancel
+// New creates a Bot with commands and redemptions registered from the catalog.
+func New(cfg Config, deps Deps, logger *slog.Logger) (*Bot, error) {
+	redemptions := Redemptions(deps.Series, deps.Rewards)
+	for _, reward := range deps.Rewards {
+		if _, ok := redemptions[reward.Key]; !ok {
+			return nil, fmt.Errorf("reward %q has no redemption handler", reward.Key)
+		}
+	}
 
-	if err := b.initHelixClient(); err != nil {
-		return fmt.Errorf("init helix client: %w", err)
+	var definitions []stats.Definition
+	if deps.StatsService != nil {
+		definitions = deps.StatsService.Definitions()
+	}
+	if err := checkRewardDice(deps.Rewards, definitions); err != nil {
+		return nil, err
 	}
 
-	conduitID, err := getOrCreateConduit(b.config.ClientID, b.helixClient.GetAppAccessToken())
+	commands := Commands(deps.Series)
+	aliases, err := commandAliases(commands)
 	if err != nil {
-		return fmt.Errorf("get or create conduit: %w", err)
+		return nil, err
 	}
-	b.conduitID = conduitID
-	b.logger.Info("conduit ready", "conduit_id", conduitID)
-
-	url := "wss://eventsub.wss.twitch.tv/ws"
 
-	if b.config.UseMockServer {
-		url = "ws://localhost:8080/ws"
+	cooldownTracker := deps.Cooldowns
+	if cooldownTracker == nil {
+		cooldownTracker = cooldowns.NewTracker(nil, logger)
 	}
 
-	for {
-		if err := b.connectOnce(url); err != nil {
-			if b.shuttingDown.Load() {
-				return nil
-			}
-			b.logger.Error("eventsub disconnected, reconnecting", "err", err, "delay", reconnectDelay)
-			select {
-			case <-time.After(reconnectDelay):
-			case <-ctx.Done():
-				return nil
-			}
-			continue
-		}
-		return nil
+	b := &Bot{
+		config:          cfg,
+		logger:          logger,
+		commands:        commands,
+		aliases:         aliases,
+		redemptions:     redemptions,
+		triggers:        Triggers(deps.Triggers),
+		messages:        deps.Messages,
+		series:          deps.Series,
+		rewards:         deps.Rewards,
+		rewardRegistry:  deps.RewardRegistry,
+		statsService:    deps.StatsService,
+		blindboxService: deps.BlindBoxService,
+		dedupe:          deps.Dedupe,
+		cooldowns:       cooldownTracker,
+		duels:           deps.Duels,
+		raids:           deps.Raids,
+		bosses:          deps.Bosses,
+		raidRoundDelay:  raidRoundDelay,
+		tokens:          deps.Tokens,
+		catchUp:         make(chan struct{}, 1),
+		broadcast:       deps.Broadcast,
 	}
+	b.subscriptions = newSubscriptionReconciler(b.desiredSubscriptions(), logger)
+	b.chat = newChatQueue(b.sendChatMessage, b.chatRateLimit(), logger)
+	return b, nil
 }
 
-func (b *Bot) connectOnce(url string) error {
-	client := twitch.NewClientWithUrl(url)
-	b.twitchClient = client
-	reconnectCh := make(chan error, 1)
-
-	client.OnError(func(err error) {
-		b.logger.Error("twitch client error", "err", err)
-	})
-
-	client.OnWelcome(func(message twitch.WelcomeMessage) {
-		b.logger.Info("connected to twitch eventsub", "session_id", message.Payload.Session.ID)
-		if err := b.subscribeEvents(message.Payload.Session.ID); err != nil {
-			b.logger.Error("failed to subscribe to events", "err", err)
-			select {
-			case reconnectCh <- fmt.Errorf("subscribe events: %w", err):
-			default:
-			}
-			if err := client.Close(); err != nil {
-				b.logger.Error("error closing client after subscribe failure", "err", err)
-			}
-		}
-	})
+// chatRateLimit returns how many messages the bot may send per
+// chatRateWindow, which is higher for moderators and the broadcaster.
+func (b *Bot) chatRateLimit() int {
+	if b.config.BotIsModerator || b.config.BotUserID == b.config.ChannelUserID {
+		return chatModRateLimit
+	}
+	return chatRateLimit
+}
 
-	client.OnNotification(func(message twitch.NotificationMessage) {
-		b.logger.Debug("eventsub notification", "type", message.Payload.Subscription.Type)
-	})
+// SubscriptionStatus reports the state of each EventSub subscription the bot
+// keeps on its conduit.
+func (b *Bot) SubscriptionStatus() []server.EventSubSubscription {
+	return b.subscriptions.Snapshot()
+}
 
-	client.OnRevoke(func(message twitch.RevokeMessage) {
-		b.logger.Warn("subscription revoked", "subscription", message.Payload.Subscription)
-	})
+func (b *Bot) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.ctx, b.cancel = ctx, cancel
 
-	client.OnReconnect(func(message twitch.ReconnectMessage) {
-		b.logger.Debug("client reconnected", "msg", message)
-	})
+	if err := b.initHelixClient(); err != nil {
+		return fmt.Errorf("init helix client: %w", err)
+	}
 
-	client.OnEventConduitShardDisabled(func(event twitch.EventConduitShardDisabled) {
-		b.logger.Warn("conduit shard disabled, reconnecting",
-			"conduit_id", event.ConduitId,
-			"shard_id", event.ShardId,
-			"status", event.Status,
-		)
-		select {
-		case reconnectCh <- fmt.Errorf("%w: conduit shard disabled (%s)", errReconnectRequested, event.Status):
-		default:
-		}
-		if err := client.Close(); err != nil {
-			b.logger.Error("error closing client after shard disabled", "err", err)
-		}
-	})
+	shardCount := b.shardCount()
+	conduitID, err := b.twitchAPI.getOrCreateConduit(ctx, shardCount)
+	if err != nil {
+		return fmt.Errorf("get or create conduit: %w", err)
+	}
+	b.conduitID = conduitID
+	b.logger.Info("conduit ready", "conduit_id", conduitID, "shard_count", shardCount)
 
-	client.OnEventChannelChatMessage(func(event twitch.EventChannelChatMessage) {
+	b.subscriptions.api = b.twitchAPI
+	b.subscriptions.conduitID = conduitID
+	if !b.config.UseMockServer {
 		b.wg.Go(func() {
-			b.onMessage(event)
+			b.subscriptions.Run(ctx)
 		})
+	}
+	b.wg.Go(func() {
+		b.runRedemptionCatchUp(ctx)
 	})
-
-	client.OnEventChannelChannelPointsCustomRewardRedemptionAdd(
-		func(event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) {
-			b.wg.Go(func() {
-				b.onChannelPointRedemption(event)
-			})
-		},
-	)
-
-	client.OnEventChannelRaid(func(event twitch.EventChannelRaid) {
-		b.onChannelRaid(event)
+	b.wg.Go(func() {
+		b.chat.Run(ctx)
 	})
 
-	return b.resolveConnectResult(client.Connect(), reconnectCh)
+	url := eventSubURL
+	if b.config.UseMockServer {
+		url = mockEventSubURL
+	}
+
+	// Each shard runs its own session so one dropped socket only interrupts
+	// the events Twitch routes to that shard.
+	shards := newEventSubShards(shardCount)
+	b.shardsMu.Lock()
+	b.shards = shards
+	b.shardsMu.Unlock()
+
+	var shardWG sync.WaitGroup
+	for _, shard := range shards {
+		shardWG.Go(func() {
+			b.runShard(ctx, shard, url)
+		})
+	}
+	shardWG.Wait()
+	return nil
 }
 
-func (b *Bot) resolveConnectResult(connectErr error, reconnectCh <-chan error) error {
-	select {
-	case reconnectErr := <-reconnectCh:
-		if connectErr == nil {
-			return reconnectErr
-		}
-		b.logger.Warn("preferring reconnect request over connect result",
-			"reconnect_err", reconnectErr,
-			"connect_err", connectErr,
-		)
-		return reconnectErr
-	default:
-		return connectErr
+// runContext returns the context work that outlives a single event runs
+// under, such as timers, so that it stops when the bot shuts down.
+func (b *Bot) runContext() context.Context {
+	if b.ctx == nil {
+		return context.Background()
 	}
+	return b.ctx
 }
 
 func (b *Bot) Shutdown() {
@@ -205,11 +248,6 @@ func (b *Bot) Shutdown() {
 
 	b.shuttingDown.Store(true)
 	b.cancel()
-	if b.twitchClient != nil {
-		if err := b.twitchClient.Close(); err != nil {
-			b.logger.Debug("error closing twitch client", "err", err)
-		}
-	}
 
 	b.wg.Wait()
 	b.logger.Info("bot stopped")
@@ -219,6 +257,9 @@ func (b *Bot) onMessage(event twitch.EventChannelChatMessage) {
 	if event.ChatterUserId == b.config.BotUserID {
 		return
 	}
+	if !b.firstDelivery(dedupe.KindChatMessage, event.MessageId) {
+		return
+	}
 	if b.statsService != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
 		if err := b.statsService.RecordActivity(ctx, event.ChatterUserId, event.ChatterUserName); err != nil {
@@ -237,21 +278,11 @@ func (b *Bot) onMessage(event twitch.EventChannelChatMessage) {
 }
 
 func (b *Bot) processCommand(event twitch.EventChannelChatMessage) {
-	if !strings.HasPrefix(event.Message.Text, "!") {
-		return
-	}
-
-	fields := strings.Fields(strings.ToLower(event.Message.Text))
-	if len(fields) == 0 {
-		return
-	}
-
-	cmd := strings.TrimPrefix(fields[0], "!")
-	if cmd == "" {
+	name, ok := b.commandName(event.Message.Text)
+	if !ok {
 		return
 	}
-
-	command, ok := b.commands[cmd]
+	cmd, command, ok := b.lookupCommand(name)
 	if !ok {
 		return
 	}
@@ -262,15 +293,39 @@ func (b *Bot) processCommand(event twitch.EventChannelChatMessage) {
 		"message", event.Message.Text,
 	)
 
-	b.logger.Info("executing command", "command", cmd, "user", event.ChatterUserName)
+	if !b.allowed(command.Permission, event) {
+		b.logger.Debug("command not permitted",
+			"command", cmd,
+			"user", event.ChatterUserName,
+			"required", command.Permission,
+		)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
 	defer cancel()
-	command.Execute(ctx, b, event)
+	args, err := b.parseArgs(ctx, cmd, command, event)
+	if err != nil {
+		var argErr *argError
+		if !errors.As(err, &argErr) {
+			b.logger.Error("failed to parse command arguments", "err", err, "command", cmd)
+			b.replyWith(event, catalog.MessageCommandFailed, struct{}{})
+			return
+		}
+		b.replyWith(event, argErr.key, argErr.data)
+		return
+	}
+	if !b.acquireCommandCooldown(ctx, cmd, command, event) {
+		return
+	}
+
+	b.logger.Info("executing command", "command", cmd, "user", event.ChatterUserName)
+	command.Execute(ctx, b, event, args)
 }
 
 func (b *Bot) processTriggers(event twitch.EventChannelChatMessage) {
 	for _, t := range b.triggers {
-		if !t.ShouldTrigger(event) {
+		if !b.allowed(t.Permission, event) || !t.ShouldTrigger(event) {
 			continue
 		}
 
@@ -284,36 +339,208 @@ func (b *Bot) processTriggers(event twitch.EventChannelChatMessage) {
 			}
 		}
 
+		ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+		if t.Cooldown > 0 && b.cooldowns != nil {
+			cooldown := cooldowns.Cooldown{Key: "trigger:" + t.Key, Duration: t.Cooldown}
+			if ok, remaining := b.cooldowns.Acquire(ctx, cooldown); !ok {
+				b.logger.Debug("trigger on cooldown", "trigger", t.Key, "remaining", remaining)
+				cancel()
+				continue
+			}
+		}
+
 		b.logger.Info("executing trigger",
+			"trigger", t.Key,
 			"user", event.ChatterUserName,
 			"message", event.Message.Text,
 		)
-		ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
 		t.Execute(ctx, b, event)
 		cancel()
 	}
 }
 
 func (b *Bot) onChannelPointRedemption(event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) {
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+	defer cancel()
+	b.handleRedemption(ctx, event)
+}
+
+// handleRedemption runs the redemption and settles it with Twitch: FULFILLED
+// when the handler succeeds, CANCELED (refunding the points) when it fails. A
+// redemption whose handler already ran but whose status update failed only has
+// the update retried, and one whose handler was interrupted, such as by a
+// crash, is refunded.
+func (b *Bot) handleRedemption(ctx context.Context, event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) {
+	// Only one delivery of a redemption is handled at a time, so one still in
+	// progress below is never one that is running.
+	if !b.startRedeeming(event.ID) {
+		return
+	}
+	defer b.stopRedeeming(event.ID)
+
+	switch status := b.owedRedemptionStatus(ctx, event.ID); status {
+	case "":
+	case redemptionInProgress:
+		b.logger.Warn("refunding interrupted redemption", "user", event.UserName, "reward", event.Reward.Title)
+		key := catalog.MessageRedemptionFailed
+		if b.resolveRedemption(ctx, event, redemptionCanceled) {
+			key = catalog.MessageRedemptionRefunded
+		}
+		b.say(key, catalog.UserData{User: event.UserName})
+		return
+	default:
+		b.logger.Info("retrying redemption status", "status", status, "user", event.UserName)
+		b.resolveRedemption(ctx, event, status)
+		return
+	}
+
+	handled, err := b.runRedemption(ctx, event)
+	if !handled {
+		return
+	}
+	status := redemptionFulfilled
+	if err != nil {
+		b.logger.Error("redemption failed", "err", err, "user", event.UserName, "reward", event.Reward.Title)
+		status = redemptionCanceled
+	}
+	refunded := b.resolveRedemption(ctx, event, status)
+	if err == nil {
+		return
+	}
+	key := catalog.MessageRedemptionFailed
+	if refunded {
+		key = catalog.MessageRedemptionRefunded
+	}
+	b.say(key, catalog.UserData{User: event.UserName})
+}
+
+// resolveRedemption gives the redemption status on Twitch and reports whether
+// the points were refunded. Twitch only accepts this for rewards created by the
+// bot's client ID. Until Twitch accepts it, the status is kept as owed so the
+// next delivery or catch-up retries the update instead of the handler.
+func (b *Bot) resolveRedemption(
+	ctx context.Context,
+	event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd,
+	status string,
+) bool {
+	if b.streamerAPI == nil {
+		b.setOwedRedemptionStatus(ctx, event.ID, "")
+		return false
+	}
+	if err := b.streamerAPI.updateRedemptionStatus(
+		ctx,
+		b.config.ChannelUserID,
+		event.Reward.ID,
+		event.ID,
+		status,
+	); err != nil {
+		b.logger.Error("failed to update redemption status", "err", err, "status", status, "user", event.UserName)
+		b.setOwedRedemptionStatus(ctx, event.ID, status)
+		return false
+	}
+	b.setOwedRedemptionStatus(ctx, event.ID, "")
+	return status == redemptionCanceled
+}
+
+// owedRedemptionStatus returns the status a redemption is still owed on Twitch,
+// redemptionInProgress if its handler never finished, or an empty string when
+// it owes nothing.
+func (b *Bot) owedRedemptionStatus(ctx context.Context, id string) string {
+	if b.dedupe == nil || b.streamerAPI == nil {
+		return ""
+	}
+	status, err := b.dedupe.Outcome(ctx, dedupe.KindRedemption, id)
+	if err != nil {
+		b.logger.Error("check redemption outcome", "err", err, "id", id)
+	}
+	return status
+}
+
+func (b *Bot) setOwedRedemptionStatus(ctx context.Context, id, status string) {
+	if b.dedupe == nil {
+		return
+	}
+	if err := b.dedupe.SetOutcome(ctx, dedupe.KindRedemption, id, status); err != nil {
+		b.logger.Error("store redemption outcome", "err", err, "id", id, "status", status)
+	}
+}
+
+// runRedemption records the viewer's activity and runs the reward's handler.
+// It reports false without running anything when the redemption was already
+// processed or the reward has no handler.
+func (b *Bot) runRedemption(
+	ctx context.Context,
+	event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd,
+) (bool, error) {
 	b.logger.Info("channel point redemption",
 		"user", event.UserName,
 		"reward", event.Reward.Title,
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
-	defer cancel()
+	if !b.claimDelivery(dedupe.KindRedemption, event.ID, redemptionInProgress) {
+		return false, nil
+	}
+
 	if b.statsService != nil {
 		if err := b.statsService.RecordActivity(ctx, event.UserID, event.UserName); err != nil {
 			b.logger.Error("record redemption activity", "err", err, "user", event.UserName)
 		}
 	}
 
-	fn, ok := b.redemptions[event.Reward.Title]
+	fn, ok := b.redemptionHandler(ctx, event.Reward)
 	if !ok {
-		return
+		b.setOwedRedemptionStatus(ctx, event.ID, "")
+		return false, nil
+	}
+
+	return true, fn(ctx, b, event)
+}
+
+// startRedeeming marks the redemption as being handled, and reports false if
+// it already was.
+func (b *Bot) startRedeeming(id string) bool {
+	b.redeemingMu.Lock()
+	defer b.redeemingMu.Unlock()
+	if _, ok := b.redeeming[id]; ok {
+		return false
+	}
+	if b.redeeming == nil {
+		b.redeeming = make(map[string]struct{})
 	}
+	b.redeeming[id] = struct{}{}
+	return true
+}
+
+func (b *Bot) stopRedeeming(id string) {
+	b.redeemingMu.Lock()
+	defer b.redeemingMu.Unlock()
+	delete(b.redeeming, id)
+}
 
-	fn(ctx, b, event)
+// firstDelivery reports whether the event has not been handled before. If the
+// check itself fails the event is handled, since dropping it is worse than the
+// rare duplicate while the database is unavailable.
+func (b *Bot) firstDelivery(kind dedupe.Kind, id string) bool {
+	return b.claimDelivery(kind, id, "")
+}
+
+// claimDelivery is firstDelivery, also recording the outcome the event owes
+// until it is settled.
+func (b *Bot) claimDelivery(kind dedupe.Kind, id, outcome string) bool {
+	if b.dedupe == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+	defer cancel()
+	first, err := b.dedupe.ClaimWithOutcome(ctx, kind, id, outcome)
+	if err != nil {
+		b.logger.Error("check processed event", "err", err, "kind", kind, "id", id)
+		return true
+	}
+	if !first {
+		b.logger.Info("skipping duplicate event", "kind", kind, "id", id)
+	}
+	return first
 }
 
 func (b *Bot) onChannelRaid(event twitch.EventChannelRaid) {
@@ -328,91 +555,63 @@ func (b *Bot) onChannelRaid(event twitch.EventChannelRaid) {
 }
 
 func (b *Bot) initHelixClient() error {
+	tokenSource, err := newAppTokenSource(b.config.ClientID, b.config.ClientSecret)
+	if err != nil {
+		return err
+	}
+	if _, err = tokenSource.Token(); err != nil {
+		return err
+	}
+
+	// The Helix client and the conduit requests share one HTTP client so both
+	// renew the app access token the same way.
+	httpClient := &http.Client{
+		Transport: &appTokenTransport{source: tokenSource, base: http.DefaultTransport},
+	}
+	b.twitchAPI = newTwitchAPI(httpClient, b.config.TwitchAPIBaseURL, b.config.ClientID)
+	if b.tokens != nil {
+		b.streamerAPI = newTwitchAPI(&http.Client{
+			Transport: &userTokenTransport{tokens: b.tokens, account: tokens.AccountStreamer, base: http.DefaultTransport},
+		}, b.config.TwitchAPIBaseURL, b.config.ClientID)
+	}
 	client, err := helix.NewClient(&helix.Options{
-		ClientID:     b.config.ClientID,
-		ClientSecret: b.config.ClientSecret,
+		ClientID:   b.config.ClientID,
+		HTTPClient: httpClient,
+		APIBaseURL: b.twitchAPI.baseURL,
 	})
 	if err != nil {
 		return fmt.Errorf("create app helix client: %w", err)
 	}
 
-	resp, err := client.RequestAppAccessToken(nil)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("get app access token: %w", err)
-	}
-	client.SetAppAccessToken(resp.Data.AccessToken)
-
 	b.helixClient = client
 	return nil
 }
 
-func (b *Bot) subscribeEvents(sessionID string) error {
+// subscribeEvents points the conduit shard at the new session and asks the
+// reconciler to bring the conduit's subscriptions up to date.
+func (b *Bot) subscribeEvents(ctx context.Context, shardID, sessionID string) error {
 	if b.config.UseMockServer {
 		return nil
 	}
 
-	appToken := b.helixClient.GetAppAccessToken()
-
-	if err := updateConduitShard(b.config.ClientID, appToken, b.conduitID, sessionID); err != nil {
+	if err := b.twitchAPI.updateConduitShard(ctx, b.conduitID, shardID, sessionID); err != nil {
 		return err
 	}
-
-	type sub struct {
-		subType   twitch.EventSubscription
-		version   string
-		condition map[string]string
-	}
-
-	subs := []sub{
-		{
-			subType: twitch.SubChannelChatMessage,
-			version: "1",
-			condition: map[string]string{
-				"broadcaster_user_id": b.config.ChannelUserID,
-				"user_id":             b.config.BotUserID,
-			},
-		},
-		{
-			subType: twitch.SubChannelChannelPointsCustomRewardRedemptionAdd,
-			version: "1",
-			condition: map[string]string{
-				"broadcaster_user_id": b.config.ChannelUserID,
-			},
-		},
-		{
-			subType: twitch.SubChannelRaid,
-			version: "1",
-			condition: map[string]string{
-				"to_broadcaster_user_id": b.config.ChannelUserID,
-			},
-		},
-		{
-			subType: twitch.SubConduitShardDisabled,
-			version: "1",
-			condition: map[string]string{
-				"client_id": b.config.ClientID,
-			},
-		},
-	}
-
-	for _, s := range subs {
-		b.logger.Info("subscribing to event via conduit", "type", s.subType)
-		if err := createConduitSubscription(
-			b.config.ClientID,
-			appToken,
-			b.conduitID,
-			string(s.subType),
-			s.version,
-			s.condition,
-		); err != nil {
-			return fmt.Errorf("subscribe to %s: %w", s.subType, err)
-		}
-	}
+	b.subscriptions.Trigger()
 
 	return nil
 }
 
+// SendMessage queues a chat message. Messages are sent in the background
+// within Twitch's rate limits; see chatQueue.
 func (b *Bot) SendMessage(params SendMessageParams) {
+	if b.chat == nil {
+		return
+	}
+	b.chat.Enqueue(params)
+}
+
+func (b *Bot) sendChatMessage(_ context.Context, params SendMessageParams) {
 	if b.helixClient == nil {
 		return
 	}
@@ -0,0 +1,93 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+// triggerRedemptionCatchUp asks the catch-up worker to look for redemptions
+// missed while no EventSub session was connected.
+func (b *Bot) triggerRedemptionCatchUp() {
+	select {
+	case b.catchUp <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bot) runRedemptionCatchUp(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.catchUp:
+		}
+		if err := b.syncRewards(ctx); err != nil && ctx.Err() == nil {
+			b.logger.Error("channel point reward sync failed", "err", err)
+		}
+		if err := b.catchUpRedemptions(ctx); err != nil && ctx.Err() == nil {
+			b.logger.Error("redemption catch-up failed", "err", err)
+		}
+	}
+}
+
+// catchUpRedemptions runs every UNFULFILLED redemption of the registered
+// rewards. Twitch keeps a redemption in that state until its owner resolves it,
+// so anything still there was never processed. Only rewards created by this
+// client ID can be queried.
+func (b *Bot) catchUpRedemptions(ctx context.Context) error {
+	if b.streamerAPI == nil || b.rewardRegistry == nil {
+		return nil
+	}
+	rewards, err := b.streamerAPI.listCustomRewards(ctx, b.config.ChannelUserID, true)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, reward := range rewards {
+		if _, ok := b.rewardRegistry.Lookup(reward.ID); !ok {
+			continue
+		}
+		redemptions, err := b.streamerAPI.listRedemptions(ctx, b.config.ChannelUserID, reward.ID, redemptionUnfulfilled)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", reward.Title, err))
+			continue
+		}
+		if len(redemptions) > 0 {
+			b.logger.Info("catching up on missed redemptions", "reward", reward.Title, "count", len(redemptions))
+		}
+		for _, redemption := range redemptions {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			b.catchUpRedemption(ctx, redemption)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Bot) catchUpRedemption(ctx context.Context, redemption rewardRedemption) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+	b.handleRedemption(ctx, redemption.event(b.config.ChannelUserID))
+}
+
+func (r rewardRedemption) event(broadcasterID string) twitch.EventChannelChannelPointsCustomRewardRedemptionAdd {
+	return twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
+		Broadcaster: twitch.Broadcaster{BroadcasterUserId: broadcasterID},
+		User:        twitch.User{UserID: r.UserID, UserLogin: r.UserLogin, UserName: r.UserName},
+		ID:          r.ID,
+		UserInput:   r.UserInput,
+		Status:      r.Status,
+		Reward: twitch.CustomChannelPointReward{
+			ID:     r.Reward.ID,
+			Title:  r.Reward.Title,
+			Cost:   r.Reward.Cost,
+			Prompt: r.Reward.Prompt,
+		},
+		RedeemedAt: r.RedeemedAt,
+	}
+}
@@ -0,0 +1,255 @@
+package charsibot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/dedupe"
+)
+
+func TestCatchUpRedemptionsFulfilsOrRefunds(t *testing.T) {
+	var mu sync.Mutex
+	var statuses []string
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/channel_points/custom_rewards":
+			if query.Get("only_manageable_rewards") != "true" {
+				t.Errorf("rewards query = %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"data":[
+				{"id":"reward-1","title":"Mystery Box"},
+				{"id":"reward-2","title":"Unregistered Reward"}
+			]}`))
+		case r.Method == http.MethodGet:
+			if query.Get("reward_id") != "reward-1" || query.Get("status") != redemptionUnfulfilled {
+				t.Errorf("unexpected redemptions query %s", r.URL.RawQuery)
+			}
+			if query.Get("after") == "" {
+				_, _ = w.Write([]byte(`{"data":[
+					{"id":"redemption-1","user_id":"u1","user_name":"Ok","reward":{"id":"reward-1","title":"Mystery Box"}}
+				],"pagination":{"cursor":"page-2"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":[
+				{"id":"redemption-2","user_id":"u2","user_name":"Broken","reward":{"id":"reward-1","title":"Mystery Box"}}
+			],"pagination":{}}`))
+		case r.Method == http.MethodPatch:
+			var body redemptionStatusRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode status: %v", err)
+			}
+			mu.Lock()
+			statuses = append(statuses, query.Get("id")+"="+body.Status)
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		}
+	})
+
+	b := createTestBotForRedemption(t)
+	b.streamerAPI = api
+	var handled []string
+	b.rewardRegistry = newTestRewardRegistry(t, map[string]catalog.Reward{"reward-1": {Key: "mystery-box", Title: "Mystery Box"}})
+	b.redemptions = map[string]RedemptionFunc{
+		"mystery-box": func(_ context.Context, _ *Bot, event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
+			handled = append(handled, event.UserName)
+			if event.UserName == "Broken" {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}
+
+	if err := b.catchUpRedemptions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(handled, []string{"Ok", "Broken"}) {
+		t.Fatalf("handled = %v, want oldest first", handled)
+	}
+	want := []string{"redemption-1=" + redemptionFulfilled, "redemption-2=" + redemptionCanceled}
+	if !slices.Equal(statuses, want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestCatchUpRedemptionsWithoutStreamerToken(t *testing.T) {
+	b := createTestBotForRedemption(t)
+	if err := b.catchUpRedemptions(context.Background()); err != nil {
+		t.Fatalf("catchUpRedemptions() = %v, want nil when no streamer token is configured", err)
+	}
+}
+
+func TestChannelPointRedemptionFulfilsOrRefunds(t *testing.T) {
+	var statuses []string
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Query().Get("reward_id") != "reward-1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		var body redemptionStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode status: %v", err)
+		}
+		statuses = append(statuses, r.URL.Query().Get("id")+"="+body.Status)
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	})
+
+	b := createTestBotForRedemption(t)
+	b.streamerAPI = api
+	b.rewardRegistry = newTestRewardRegistry(t, map[string]catalog.Reward{
+		"reward-1": {Key: "drink-a-potion", Title: "Drink a Potion"},
+	})
+	b.redemptions = map[string]RedemptionFunc{
+		"drink-a-potion": func(
+			_ context.Context,
+			_ *Bot,
+			event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd,
+		) error {
+			if event.ID == "redemption-2" {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}
+
+	for _, id := range []string{"redemption-1", "redemption-2"} {
+		b.onChannelPointRedemption(twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
+			ID:     id,
+			User:   twitch.User{UserID: "u1", UserName: "Viewer"},
+			Reward: twitch.CustomChannelPointReward{ID: "reward-1", Title: "Drink a Potion"},
+		})
+	}
+
+	want := []string{"redemption-1=" + redemptionFulfilled, "redemption-2=" + redemptionCanceled}
+	if !slices.Equal(statuses, want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestFailedRedemptionStatusIsRetriedWithoutRerunningHandler(t *testing.T) {
+	var statuses []string
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		var body redemptionStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode status: %v", err)
+		}
+		statuses = append(statuses, body.Status)
+		if len(statuses) == 1 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	})
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	dedupeService, err := dedupe.NewService(queries, dedupe.DefaultRetention, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	b := createTestBotForRedemption(t)
+	b.streamerAPI = api
+	b.dedupe = dedupeService
+	b.rewardRegistry = newTestRewardRegistry(t, map[string]catalog.Reward{
+		"reward-1": {Key: "mystery-box", Title: "Mystery Box"},
+	})
+	b.redemptions = map[string]RedemptionFunc{
+		"mystery-box": func(context.Context, *Bot, twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
+			calls++
+			return nil
+		},
+	}
+
+	event := twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
+		ID:     "redemption-1",
+		User:   twitch.User{UserID: "u1", UserName: "Viewer"},
+		Reward: twitch.CustomChannelPointReward{ID: "reward-1", Title: "Mystery Box"},
+	}
+	for range 3 {
+		b.handleRedemption(t.Context(), event)
+	}
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	want := []string{redemptionFulfilled, redemptionFulfilled}
+	if !slices.Equal(statuses, want) {
+		t.Fatalf("statuses = %v, want the failed update retried once", statuses)
+	}
+}
+
+func TestCatchUpRefundsInterruptedRedemption(t *testing.T) {
+	var statuses []string
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/channel_points/custom_rewards":
+			_, _ = w.Write([]byte(`{"data":[{"id":"reward-1","title":"Mystery Box"}]}`))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"data":[
+				{"id":"redemption-1","user_id":"u1","user_name":"Viewer","reward":{"id":"reward-1","title":"Mystery Box"}}
+			],"pagination":{}}`))
+		case r.Method == http.MethodPatch:
+			var body redemptionStatusRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode status: %v", err)
+			}
+			statuses = append(statuses, r.URL.Query().Get("id")+"="+body.Status)
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		}
+	})
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	dedupeService, err := dedupe.NewService(queries, dedupe.DefaultRetention, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The redemption was claimed by a bot that stopped before settling it.
+	if _, err := dedupeService.ClaimWithOutcome(
+		t.Context(), dedupe.KindRedemption, "redemption-1", redemptionInProgress,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	b := createTestBotForRedemption(t)
+	b.streamerAPI = api
+	b.dedupe = dedupeService
+	b.rewardRegistry = newTestRewardRegistry(t, map[string]catalog.Reward{
+		"reward-1": {Key: "mystery-box", Title: "Mystery Box"},
+	})
+	b.redemptions = map[string]RedemptionFunc{
+		"mystery-box": func(context.Context, *Bot, twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
+			calls++
+			return nil
+		},
+	}
+
+	if err := b.catchUpRedemptions(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 0 {
+		t.Fatalf("handler ran %d times, want an interrupted redemption refunded rather than rerun", calls)
+	}
+	if want := []string{"redemption-1=" + redemptionCanceled}; !slices.Equal(statuses, want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+	outcome, err := dedupeService.Outcome(t.Context(), dedupe.KindRedemption, "redemption-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome != "" {
+		t.Fatalf("outcome = %q, want it cleared once the refund is accepted", outcome)
+	}
+}
@@ -0,0 +1,187 @@
+package charsibot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Redemption statuses accepted by Helix.
+const (
+	redemptionUnfulfilled = "UNFULFILLED"
+	redemptionFulfilled   = "FULFILLED"
+	redemptionCanceled    = "CANCELED"
+)
+
+// redemptionInProgress is the outcome a redemption is claimed with while its
+// handler runs. A redemption still owing it after the handler stopped running
+// was interrupted, and is refunded.
+const redemptionInProgress = "IN_PROGRESS"
+
+type customReward struct {
+	ID                    string                `json:"id"`
+	Title                 string                `json:"title"`
+	Prompt                string                `json:"prompt"`
+	Cost                  int                   `json:"cost"`
+	IsEnabled             bool                  `json:"is_enabled"`
+	BackgroundColor       string                `json:"background_color"`
+	GlobalCooldownSetting globalCooldownSetting `json:"global_cooldown_setting"`
+	SkipRequestQueue      bool                  `json:"should_redemptions_skip_request_queue"`
+}
+
+type globalCooldownSetting struct {
+	IsEnabled bool `json:"is_enabled"`
+	Seconds   int  `json:"global_cooldown_seconds"`
+}
+
+// customRewardRequest is the body for creating or updating a custom reward.
+// Redemptions never skip the request queue, since a skipped redemption is
+// fulfilled straight away and can no longer be refunded.
+type customRewardRequest struct {
+	Title                   string `json:"title"`
+	Prompt                  string `json:"prompt"`
+	Cost                    int    `json:"cost"`
+	IsEnabled               bool   `json:"is_enabled"`
+	BackgroundColor         string `json:"background_color,omitempty"`
+	IsGlobalCooldownEnabled bool   `json:"is_global_cooldown_enabled"`
+	GlobalCooldownSeconds   int    `json:"global_cooldown_seconds,omitempty"`
+	SkipRequestQueue        bool   `json:"should_redemptions_skip_request_queue"`
+}
+
+type customRewardListResponse struct {
+	Data []customReward `json:"data"`
+}
+
+type rewardRedemption struct {
+	ID         string       `json:"id"`
+	UserID     string       `json:"user_id"`
+	UserLogin  string       `json:"user_login"`
+	UserName   string       `json:"user_name"`
+	UserInput  string       `json:"user_input"`
+	Status     string       `json:"status"`
+	RedeemedAt time.Time    `json:"redeemed_at"`
+	Reward     customReward `json:"reward"`
+}
+
+type redemptionListResponse struct {
+	Data       []rewardRedemption `json:"data"`
+	Pagination struct {
+		Cursor string `json:"cursor"`
+	} `json:"pagination"`
+}
+
+type redemptionStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// listCustomRewards returns the broadcaster's custom rewards. With
+// onlyManageable it returns just those created by this client ID, the only
+// ones it may update or whose redemptions it may resolve.
+func (c *twitchAPI) listCustomRewards(
+	ctx context.Context,
+	broadcasterID string,
+	onlyManageable bool,
+) ([]customReward, error) {
+	query := url.Values{
+		"broadcaster_id":          {broadcasterID},
+		"only_manageable_rewards": {strconv.FormatBool(onlyManageable)},
+	}
+	var list customRewardListResponse
+	if _, err := c.Do(ctx, http.MethodGet, "/channel_points/custom_rewards?"+query.Encode(), nil, &list); err != nil {
+		return nil, fmt.Errorf("list custom rewards: %w", err)
+	}
+	return list.Data, nil
+}
+
+// createCustomReward creates a reward owned by this client ID, which lets the
+// bot manage it and its redemptions later.
+func (c *twitchAPI) createCustomReward(
+	ctx context.Context,
+	broadcasterID string,
+	reward customRewardRequest,
+) (customReward, error) {
+	query := url.Values{"broadcaster_id": {broadcasterID}}
+	var list customRewardListResponse
+	endpoint := "/channel_points/custom_rewards?" + query.Encode()
+	if _, err := c.Do(ctx, http.MethodPost, endpoint, reward, &list); err != nil {
+		return customReward{}, fmt.Errorf("create custom reward %q: %w", reward.Title, err)
+	}
+	if len(list.Data) == 0 {
+		return customReward{}, fmt.Errorf("create custom reward %q: empty response", reward.Title)
+	}
+	return list.Data[0], nil
+}
+
+// updateCustomReward overwrites the settings of a reward owned by this client ID.
+func (c *twitchAPI) updateCustomReward(
+	ctx context.Context,
+	broadcasterID, rewardID string,
+	reward customRewardRequest,
+) (customReward, error) {
+	query := url.Values{
+		"broadcaster_id": {broadcasterID},
+		"id":             {rewardID},
+	}
+	var list customRewardListResponse
+	endpoint := "/channel_points/custom_rewards?" + query.Encode()
+	if _, err := c.Do(ctx, http.MethodPatch, endpoint, reward, &list); err != nil {
+		return customReward{}, fmt.Errorf("update custom reward %q: %w", reward.Title, err)
+	}
+	if len(list.Data) == 0 {
+		return customReward{}, fmt.Errorf("update custom reward %q: empty response", reward.Title)
+	}
+	return list.Data[0], nil
+}
+
+// listRedemptions returns a reward's redemptions with the given status, oldest
+// first, following pagination.
+func (c *twitchAPI) listRedemptions(
+	ctx context.Context,
+	broadcasterID, rewardID, status string,
+) ([]rewardRedemption, error) {
+	var redemptions []rewardRedemption
+	cursor := ""
+	for {
+		query := url.Values{
+			"broadcaster_id": {broadcasterID},
+			"reward_id":      {rewardID},
+			"status":         {status},
+			"sort":           {"OLDEST"},
+			"first":          {"50"},
+		}
+		if cursor != "" {
+			query.Set("after", cursor)
+		}
+		var page redemptionListResponse
+		endpoint := "/channel_points/custom_rewards/redemptions?" + query.Encode()
+		if _, err := c.Do(ctx, http.MethodGet, endpoint, nil, &page); err != nil {
+			return nil, fmt.Errorf("list redemptions: %w", err)
+		}
+		redemptions = append(redemptions, page.Data...)
+		if page.Pagination.Cursor == "" || len(page.Data) == 0 {
+			return redemptions, nil
+		}
+		cursor = page.Pagination.Cursor
+	}
+}
+
+// updateRedemptionStatus marks an unfulfilled redemption FULFILLED or
+// CANCELED. Cancelling refunds the viewer's points.
+func (c *twitchAPI) updateRedemptionStatus(
+	ctx context.Context,
+	broadcasterID, rewardID, redemptionID, status string,
+) error {
+	query := url.Values{
+		"id":             {redemptionID},
+		"broadcaster_id": {broadcasterID},
+		"reward_id":      {rewardID},
+	}
+	endpoint := "/channel_points/custom_rewards/redemptions?" + query.Encode()
+	if _, err := c.Do(ctx, http.MethodPatch, endpoint, redemptionStatusRequest{Status: status}, nil); err != nil {
+		return fmt.Errorf("mark redemption %s %s: %w", redemptionID, status, err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package charsibot
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Twitch chat limits. A bot may send 20 messages per 30 seconds, or 100 when
+// it is a moderator or the broadcaster, and each message may be at most 500
+// characters.
+const (
+	maxChatMessageLength = 500
+	chatRateWindow       = 30 * time.Second
+	chatRateLimit        = 20
+	chatModRateLimit     = 100
+	// chatQueueSize bounds the messages waiting to be sent, replies included;
+	// the oldest non-reply is dropped first when it is full.
+	chatQueueSize = 100
+	// chatDuplicateWindow is how long an identical message is suppressed.
+	// Twitch silently drops a repeat within 30 seconds anyway.
+	chatDuplicateWindow = 30 * time.Second
+)
+
+// chatSeparators returns the boundaries long messages are split on, most
+// preferred first.
+func chatSeparators() []string {
+	return []string{" | ", ", ", " "}
+}
+
+// chatQueue sends chat messages in the background within Twitch's rate
+// limits. Replies jump ahead of other messages, long messages are split, and
+// a message identical to the one before it is dropped.
+type chatQueue struct {
+	send   func(ctx context.Context, params SendMessageParams)
+	logger *slog.Logger
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+
+	mu       sync.Mutex
+	limiter  tokenBucket
+	replies  []SendMessageParams
+	messages []SendMessageParams
+	last     SendMessageParams
+	lastAt   time.Time
+	wake     chan struct{}
+}
+
+func newChatQueue(
+	send func(ctx context.Context, params SendMessageParams),
+	limit int,
+	logger *slog.Logger,
+) *chatQueue {
+	now := time.Now
+	return &chatQueue{
+		send:    send,
+		logger:  logger,
+		now:     now,
+		sleep:   sleepContext,
+		limiter: newTokenBucket(limit, chatRateWindow, now()),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Enqueue queues a message for sending, split into parts that fit in chat.
+func (q *chatQueue) Enqueue(params SendMessageParams) {
+	if strings.TrimSpace(params.Message) == "" {
+		return
+	}
+
+	q.mu.Lock()
+	now := q.now()
+	if params == q.last && now.Sub(q.lastAt) < chatDuplicateWindow {
+		q.mu.Unlock()
+		q.logger.Debug("dropped duplicate chat message", "message", params.Message)
+		return
+	}
+	q.last, q.lastAt = params, now
+
+	for _, part := range splitMessage(params.Message, maxChatMessageLength) {
+		message := SendMessageParams{Message: part, ReplyParentMessageID: params.ReplyParentMessageID}
+		if message.ReplyParentMessageID != "" {
+			q.replies = append(q.replies, message)
+		} else {
+			q.messages = append(q.messages, message)
+		}
+	}
+	if excess := len(q.replies) + len(q.messages) - chatQueueSize; excess > 0 {
+		messages := min(excess, len(q.messages))
+		q.messages = q.messages[messages:]
+		replies := excess - messages
+		q.replies = q.replies[replies:]
+		q.logger.Warn("chat queue is full, dropped oldest messages", "messages", messages, "replies", replies)
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends queued messages until ctx is done.
+func (q *chatQueue) Run(ctx context.Context) {
+	for {
+		if !q.pending() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+				continue
+			}
+		}
+
+		q.mu.Lock()
+		wait := q.limiter.take(q.now())
+		q.mu.Unlock()
+		if wait > 0 {
+			if err := q.sleep(ctx, wait); err != nil {
+				return
+			}
+			continue
+		}
+
+		// Pop only once a token is held so a reply queued during the wait
+		// still goes first.
+		if params, ok := q.pop(); ok {
+			q.send(ctx, params)
+		}
+	}
+}
+
+func (q *chatQueue) pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.replies)+len(q.messages) > 0
+}
+
+func (q *chatQueue) pop() (SendMessageParams, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var params SendMessageParams
+	switch {
+	case len(q.replies) > 0:
+		params, q.replies = q.replies[0], q.replies[1:]
+	case len(q.messages) > 0:
+		params, q.messages = q.messages[0], q.messages[1:]
+	default:
+		return SendMessageParams{}, false
+	}
+	return params, true
+}
+
+// tokenBucket allows capacity messages at once, refilling evenly over window.
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	perToken time.Duration
+	last     time.Time
+}
+
+func newTokenBucket(capacity int, window time.Duration, now time.Time) tokenBucket {
+	return tokenBucket{
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		perToken: window / time.Duration(capacity),
+		last:     now,
+	}
+}
+
+// take consumes a token and returns zero, or returns how long until one is
+// available without consuming anything.
+func (t *tokenBucket) take(now time.Time) time.Duration {
+	if elapsed := now.Sub(t.last); elapsed > 0 {
+		t.tokens = min(t.capacity, t.tokens+float64(elapsed)/float64(t.perToken))
+		t.last = now
+	}
+	if t.tokens >= 1 {
+		t.tokens--
+		return 0
+	}
+	return time.Duration((1 - t.tokens) * float64(t.perToken))
+}
+
+// splitMessage breaks message into parts of at most limit characters,
+// cutting at the last separator that fits and only mid-word when none does.
+func splitMessage(message string, limit int) []string {
+	message = strings.TrimSpace(message)
+	var parts []string
+	for utf8.RuneCountInString(message) > limit {
+		end, next := splitPoint(message, limit)
+		parts = append(parts, strings.TrimSpace(message[:end]))
+		message = strings.TrimSpace(message[next:])
+	}
+	if message != "" {
+		parts = append(parts, message)
+	}
+	return parts
+}
+
+// splitPoint returns where the first part of message ends and the rest
+// begins, skipping the separator between them.
+func splitPoint(message string, limit int) (int, int) {
+	prefixEnd := len(message)
+	runes := 0
+	for i := range message {
+		if runes == limit {
+			prefixEnd = i
+			break
+		}
+		runes++
+	}
+	// The separator may straddle the limit, so look at one separator's worth
+	// beyond it.
+	for _, separator := range chatSeparators() {
+		window := message[:min(len(message), prefixEnd+len(separator)-1)]
+		if i := strings.LastIndex(window, separator); i > 0 {
+			return i, i + len(separator)
+		}
+	}
+	return prefixEnd, prefixEnd
+}
@@ -0,0 +1,157 @@
+package charsibot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		limit   int
+		want    []string
+	}{
+		{name: "short message is unchanged", message: "hello", limit: 10, want: []string{"hello"}},
+		{
+			name:    "prefers the stat separator",
+			message: "STR: 1 | INT: 2 | CHA: 3",
+			limit:   16,
+			want:    []string{"STR: 1 | INT: 2", "CHA: 3"},
+		},
+		{
+			name:    "separator straddling the limit",
+			message: "aaaa | bbbb",
+			limit:   6,
+			want:    []string{"aaaa", "bbbb"},
+		},
+		{name: "falls back to commas", message: "one two, three four", limit: 12, want: []string{"one two", "three four"}},
+		{name: "falls back to spaces", message: "one two three", limit: 8, want: []string{"one two", "three"}},
+		{name: "cuts words that do not fit", message: "abcdefghij", limit: 4, want: []string{"abcd", "efgh", "ij"}},
+		{name: "counts characters not bytes", message: "ééééé", limit: 4, want: []string{"éééé", "é"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.message, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("splitMessage(%q, %d) = %q, want %q", tt.message, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessageFitsChat(t *testing.T) {
+	message := strings.Repeat("LUCK: 12 | ", 100) + "DEX: 3"
+	for _, part := range splitMessage(message, maxChatMessageLength) {
+		if n := utf8.RuneCountInString(part); n > maxChatMessageLength {
+			t.Fatalf("part has %d characters", n)
+		}
+		if strings.HasPrefix(part, "|") || strings.HasSuffix(part, "|") {
+			t.Fatalf("part %q was not split on a separator", part)
+		}
+	}
+}
+
+// newTestChatQueue returns a queue with a fake clock that only advances when
+// the queue sleeps.
+func newTestChatQueue(limit int) (*chatQueue, *[]SendMessageParams, *[]time.Duration) {
+	var sent []SendMessageParams
+	var slept []time.Duration
+	now := time.Unix(0, 0)
+	q := newChatQueue(func(_ context.Context, params SendMessageParams) {
+		sent = append(sent, params)
+	}, limit, slog.New(slog.DiscardHandler))
+	q.now = func() time.Time { return now }
+	q.limiter = newTokenBucket(limit, chatRateWindow, now)
+	q.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		now = now.Add(d)
+		return nil
+	}
+	return q, &sent, &slept
+}
+
+// drain runs the queue until everything queued has been sent.
+func drain(q *chatQueue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.send = func(send func(context.Context, SendMessageParams)) func(context.Context, SendMessageParams) {
+		return func(ctx context.Context, params SendMessageParams) {
+			send(ctx, params)
+			if !q.pending() {
+				cancel()
+			}
+		}
+	}(q.send)
+	q.Run(ctx)
+}
+
+func TestChatQueueSendsRepliesFirst(t *testing.T) {
+	q, sent, _ := newTestChatQueue(chatRateLimit)
+	q.Enqueue(SendMessageParams{Message: "first"})
+	q.Enqueue(SendMessageParams{Message: "second"})
+	q.Enqueue(SendMessageParams{Message: "reply", ReplyParentMessageID: "msg-1"})
+	drain(q)
+
+	var got []string
+	for _, params := range *sent {
+		got = append(got, params.Message)
+	}
+	if want := []string{"reply", "first", "second"}; !slices.Equal(got, want) {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+	if (*sent)[0].ReplyParentMessageID != "msg-1" {
+		t.Fatalf("reply lost its parent: %+v", (*sent)[0])
+	}
+}
+
+func TestChatQueueDropsConsecutiveDuplicates(t *testing.T) {
+	q, sent, _ := newTestChatQueue(chatRateLimit)
+	q.Enqueue(SendMessageParams{Message: "same"})
+	q.Enqueue(SendMessageParams{Message: "same"})
+	q.Enqueue(SendMessageParams{Message: "other"})
+	q.Enqueue(SendMessageParams{Message: "same"})
+	drain(q)
+
+	if len(*sent) != 3 {
+		t.Fatalf("sent %d messages, want 3: %+v", len(*sent), *sent)
+	}
+}
+
+func TestChatQueueCapsRepliesAndMessages(t *testing.T) {
+	q, _, _ := newTestChatQueue(chatRateLimit)
+	q.Enqueue(SendMessageParams{Message: "message"})
+	for i := range chatQueueSize + 10 {
+		q.Enqueue(SendMessageParams{Message: fmt.Sprintf("reply %d", i), ReplyParentMessageID: "msg-1"})
+	}
+
+	if len(q.messages) != 0 || len(q.replies) != chatQueueSize {
+		t.Fatalf("queued %d messages and %d replies, want 0 and %d", len(q.messages), len(q.replies), chatQueueSize)
+	}
+	if got := q.replies[0].Message; got != "reply 10" {
+		t.Fatalf("oldest queued reply = %q, want the oldest replies dropped", got)
+	}
+}
+
+func TestChatQueueRateLimits(t *testing.T) {
+	const limit = 2
+	q, sent, slept := newTestChatQueue(limit)
+	for _, message := range []string{"one", "two", "three"} {
+		q.Enqueue(SendMessageParams{Message: message})
+	}
+	drain(q)
+
+	if len(*sent) != 3 {
+		t.Fatalf("sent %d messages, want 3", len(*sent))
+	}
+	// The bucket starts full, so only the third message waits for a token.
+	if want := []time.Duration{chatRateWindow / limit}; !slices.Equal(*slept, want) {
+		t.Fatalf("slept %v, want %v", *slept, want)
+	}
+}
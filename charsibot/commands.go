@@ -3,57 +3,99 @@ package charsibot
 import (
 	"context"
 	"fmt"
-	"strings"
+	"maps"
+	"time"
 
 	"github.com/joeyak/go-twitch-eventsub/v3"
 
 	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/catalog"
 	"github.com/lukeramljak/charsibot/server"
-	"github.com/lukeramljak/charsibot/stats"
 )
 
 type Command struct {
-	Execute func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage)
+	Execute func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args)
+
+	// Description says what the command does, for !help.
+	Description string
+	// Aliases are other names the command answers to.
+	Aliases []string
+
+	// Args are the positional arguments the command takes, parsed before it
+	// runs. The chatter is shown the usage when they do not match.
+	Args []Arg
+
+	// Permission is the lowest chat role allowed to run the command.
+	Permission Permission
+
+	// Cooldown is how long the command is unavailable to everyone after it
+	// runs, and UserCooldown how long it is unavailable to the same chatter.
+	Cooldown     time.Duration
+	UserCooldown time.Duration
+	// OnCooldown is what happens when the command is used while cooling down.
+	OnCooldown CooldownBehavior
 }
 
+// CooldownBehavior decides how the bot answers a command used on cooldown.
+type CooldownBehavior int
+
+const (
+	// CooldownSilent ignores the command.
+	CooldownSilent CooldownBehavior = iota
+	// CooldownReply tells the chatter how long is left, at most once per
+	// cooldown so the notice cannot be spammed either.
+	CooldownReply
+)
+
 // Commands returns the full map of chat commands keyed by trigger word.
 func Commands(seriesConfigs []blindbox.SeriesConfig) map[string]Command {
 	cmds := map[string]Command{
 		"collections": {
-			Execute: func(ctx context.Context, b *Bot, _ twitch.EventChannelChatMessage) {
+			Description: "List the chatters who have completed each blind box collection.",
+			Cooldown:    time.Minute,
+			Execute: func(ctx context.Context, b *Bot, _ twitch.EventChannelChatMessage, _ Args) {
 				collections, err := b.blindboxService.GetCompletedCollections(ctx)
 				if err != nil {
 					b.logger.Error("failed to get completed collections", "err", err)
 					return
 				}
-				b.SendMessage(
-					SendMessageParams{
-						Message: "The following chatters have completed the below blind box collections:",
-					},
-				)
+				b.say(catalog.MessageCollectionsHeader, struct{}{})
 				for _, row := range collections {
-					b.SendMessage(SendMessageParams{Message: fmt.Sprintf("%s: %s", row.SeriesName, row.Usernames)})
+					b.say(catalog.MessageCollectionsEntry, row)
 				}
 			},
 		},
 		"leaderboard": {
-			Execute: func(ctx context.Context, b *Bot, _ twitch.EventChannelChatMessage) {
+			Description: "Show who leads each stat.",
+			Aliases:     []string{"lb"},
+			Cooldown:    30 * time.Second,
+			Execute: func(ctx context.Context, b *Bot, _ twitch.EventChannelChatMessage, _ Args) {
 				rows, err := b.statsService.GetStatLeaderboard(ctx)
 				if err != nil {
 					b.logger.Error("failed to get leaderboard", "err", err)
-					b.SendMessage(SendMessageParams{Message: "Failed to get leaderboard"})
+					b.say(catalog.MessageLeaderboardFailed, struct{}{})
 					return
 				}
-				parts := make([]string, len(rows))
-				for i, row := range rows {
-					parts[i] = fmt.Sprintf("%s %s (%d)", row.Emoji, row.Username, row.Value)
-				}
-				b.SendMessage(SendMessageParams{Message: strings.Join(parts, " | ")})
+				b.say(catalog.MessageLeaderboard, catalog.LeaderboardData{Rows: rows})
 			},
 		},
 		"stats": {
-			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage) {
-				if len(strings.Fields(event.Message.Text)) != 1 {
+			Description:  "Show your stats, or another chatter's.",
+			UserCooldown: 30 * time.Second,
+			OnCooldown:   CooldownReply,
+			Args:         []Arg{{Name: "user", Kind: ArgUser, Optional: true}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				if user, ok := args.User("user"); ok && user.ID != event.ChatterUserId {
+					userStats, err := b.statsService.GetUserStats(ctx, user.ID)
+					if err != nil {
+						b.logger.Error("failed to get stats", "err", err, "user", user.Login)
+						return
+					}
+					if len(userStats) == 0 {
+						b.replyWith(event, catalog.MessageStatsMissing, catalog.UserData{User: user.DisplayName})
+						return
+					}
+					b.replyWith(event, catalog.MessageStats, catalog.StatsData{User: user.DisplayName, Stats: userStats})
 					return
 				}
 				userStats, err := b.statsService.GetOrCreateStats(ctx, event.ChatterUserId, event.ChatterUserName)
@@ -61,35 +103,42 @@ func Commands(seriesConfigs []blindbox.SeriesConfig) map[string]Command {
 					b.logger.Error("failed to get stats", "err", err, "user", event.ChatterUserName)
 					return
 				}
-				b.SendMessage(
-					SendMessageParams{
-						Message:              stats.FormatStats(event.ChatterUserName, userStats),
-						ReplyParentMessageID: event.MessageId,
-					},
-				)
+				b.replyWith(event, catalog.MessageStats, catalog.StatsData{User: event.ChatterUserName, Stats: userStats})
 			},
 		},
 	}
 
+	maps.Copy(cmds, modCommands(seriesConfigs))
+	maps.Copy(cmds, helpCommands())
+	maps.Copy(cmds, rollCommands())
+	maps.Copy(cmds, duelCommands())
+	maps.Copy(cmds, raidCommands())
+
 	for _, cfg := range seriesConfigs {
+		// Collections take over the overlay, so they also cool down globally.
 		cmds[cfg.Series] = Command{
-			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage) {
-				if len(strings.Fields(event.Message.Text)) != 1 {
-					return
+			Description:  fmt.Sprintf("Show your %s collection on stream, or another chatter's.", cfg.Name),
+			Aliases:      cfg.CommandAliases,
+			Cooldown:     10 * time.Second,
+			UserCooldown: time.Minute,
+			OnCooldown:   CooldownReply,
+			Args:         []Arg{{Name: "user", Kind: ArgUser, Optional: true}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				userID, username := event.ChatterUserId, event.ChatterUserName
+				if user, ok := args.User("user"); ok {
+					userID, username = user.ID, user.DisplayName
 				}
-				slots, err := b.blindboxService.GetCollection(ctx, event.ChatterUserId, cfg.Series)
+				slots, err := b.blindboxService.GetCollection(ctx, userID, cfg.Series)
 				if err != nil {
-					b.logger.Error("failed to get collection", "err", err, "user", event.ChatterUserName)
-					b.SendMessage(
-						SendMessageParams{Message: fmt.Sprintf("Failed to get %s's collection", event.ChatterUserName)},
-					)
+					b.logger.Error("failed to get collection", "err", err, "user", username)
+					b.say(catalog.MessageCollectionFailed, catalog.UserData{User: username})
 					return
 				}
 				b.broadcast(
 					server.OverlayEvent{
 						Type: server.EventTypeCollectionDisplay,
 						Data: blindbox.BlindBoxDisplayData{
-							Username:   event.ChatterUserName,
+							Username:   username,
 							Collection: slots,
 							Config:     cfg,
 						},
@@ -98,7 +147,7 @@ func Commands(seriesConfigs []blindbox.SeriesConfig) map[string]Command {
 				b.logger.Info(
 					"displaying collection",
 					"user",
-					event.ChatterUserName,
+					username,
 					"series",
 					cfg.Series,
 					"size",
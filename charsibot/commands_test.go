@@ -2,21 +2,23 @@ package charsibot
 
 import (
 	"context"
-	"errors"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/joeyak/go-twitch-eventsub/v3"
 
 	"github.com/lukeramljak/charsibot/blindbox"
 	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/cooldowns"
 	"github.com/lukeramljak/charsibot/db"
 	"github.com/lukeramljak/charsibot/server"
 )
 
 func testCatalog(t *testing.T) catalog.Catalog {
 	t.Helper()
-	cat, err := catalog.Load()
+	cat, err := catalog.Load("")
 	if err != nil {
 		t.Fatalf("failed to load catalog: %v", err)
 	}
@@ -27,7 +29,7 @@ func TestProcessCommand(t *testing.T) {
 	executed := false
 	b := createTestBot(t)
 	b.commands = map[string]Command{
-		"test": {Execute: func(_ context.Context, _ *Bot, _ twitch.EventChannelChatMessage) { executed = true }},
+		"test": {Execute: func(_ context.Context, _ *Bot, _ twitch.EventChannelChatMessage, _ Args) { executed = true }},
 	}
 
 	b.processCommand(twitch.EventChannelChatMessage{Message: twitch.ChatMessage{Text: "!TEST arg"}})
@@ -36,6 +38,46 @@ func TestProcessCommand(t *testing.T) {
 	}
 }
 
+func TestProcessCommandCooldowns(t *testing.T) {
+	runs := map[string]int{}
+	b := createTestBot(t)
+	b.cooldowns = cooldowns.NewTracker(nil, slog.New(slog.DiscardHandler))
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.commands = map[string]Command{
+		"test": {
+			Cooldown:     time.Hour,
+			UserCooldown: time.Hour,
+			OnCooldown:   CooldownReply,
+			Execute: func(_ context.Context, _ *Bot, event twitch.EventChannelChatMessage, _ Args) {
+				runs[event.ChatterUserId]++
+			},
+		},
+	}
+	use := func(userID, messageID string) {
+		b.processCommand(twitch.EventChannelChatMessage{
+			Chatter:   twitch.Chatter{ChatterUserId: userID},
+			MessageId: messageID,
+			Message:   twitch.ChatMessage{Text: "!test"},
+		})
+	}
+
+	use("u1", "m1")
+	use("u1", "m2")
+	use("u2", "m3")
+	use("u1", "m4")
+
+	if runs["u1"] != 1 || runs["u2"] != 0 {
+		t.Fatalf("runs = %v, want the command to run once", runs)
+	}
+	// Each chatter is told about the cooldown only once.
+	if len(b.chat.replies) != 2 {
+		t.Fatalf("cooldown notices = %+v, want one per chatter", b.chat.replies)
+	}
+	if reply := b.chat.replies[0]; reply.ReplyParentMessageID != "m2" || !strings.Contains(reply.Message, "!test") {
+		t.Fatalf("cooldown notice = %+v", reply)
+	}
+}
+
 func TestProcessCommandIgnoresUnknownAndEmptyCommands(t *testing.T) {
 	b := createTestBot(t)
 	b.commands = map[string]Command{}
@@ -44,32 +86,12 @@ func TestProcessCommandIgnoresUnknownAndEmptyCommands(t *testing.T) {
 	}
 }
 
-func TestResolveConnectResult(t *testing.T) {
-	t.Run("returns connect error when no reconnect requested", func(t *testing.T) {
-		b := createTestBot(t)
-		reconnectCh := make(chan error, 1)
-		connectErr := errors.New("connect failed")
-		if got := b.resolveConnectResult(connectErr, reconnectCh); !errors.Is(got, connectErr) {
-			t.Fatalf("resolveConnectResult() = %v, want %v", got, connectErr)
-		}
-	})
-
-	t.Run("prefers reconnect request over connect result", func(t *testing.T) {
-		b := createTestBot(t)
-		reconnectCh := make(chan error, 1)
-		reconnectErr := errors.New("reconnect")
-		reconnectCh <- reconnectErr
-		if got := b.resolveConnectResult(errors.New("connection closed"), reconnectCh); !errors.Is(got, reconnectErr) {
-			t.Fatalf("resolveConnectResult() = %v, want %v", got, reconnectErr)
-		}
-	})
-}
-
 func createTestBot(t *testing.T) *Bot {
 	t.Helper()
 	return &Bot{
-		config: Config{BotUserID: "bot123", ChannelUserID: "channel456"},
-		logger: slog.New(slog.DiscardHandler),
+		config:   Config{BotUserID: "bot123", ChannelUserID: "channel456"},
+		logger:   slog.New(slog.DiscardHandler),
+		messages: testCatalog(t).Messages,
 	}
 }
 
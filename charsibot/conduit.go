@@ -1,17 +1,13 @@
 package charsibot
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 )
 
-const twitchAPIBase = "https://api.twitch.tv/helix"
-
 type conduitData struct {
 	ID         string `json:"id"`
 	ShardCount int    `json:"shard_count"`
@@ -25,6 +21,11 @@ type createConduitRequest struct {
 	ShardCount int `json:"shard_count"`
 }
 
+type updateConduitRequest struct {
+	ID         string `json:"id"`
+	ShardCount int    `json:"shard_count"`
+}
+
 type shardTransport struct {
 	Method    string `json:"method"`
 	SessionID string `json:"session_id"`
@@ -63,66 +64,52 @@ type conduitSubscriptionRequest struct {
 	Transport conduitTransport  `json:"transport"`
 }
 
-func twitchRequest(method, clientID, token, endpoint string, payload, result any) (int, error) {
-	var bodyReader io.Reader
-	if payload != nil {
-		b, err := json.Marshal(payload)
-		if err != nil {
-			return 0, fmt.Errorf("marshal request body: %w", err)
-		}
-		bodyReader = bytes.NewReader(b)
-	}
-
-	req, err := http.NewRequestWithContext(context.Background(), method, twitchAPIBase+endpoint, bodyReader)
-	if err != nil {
-		return 0, err
-	}
-	req.Header.Set("Client-Id", clientID)
-	req.Header.Set("Authorization", "Bearer "+token)
-	if payload != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return resp.StatusCode, fmt.Errorf("decode response: %w", err)
-		}
-	}
-
-	if resp.StatusCode >= http.StatusBadRequest {
-		return resp.StatusCode, fmt.Errorf("%s %s failed (%d): %s", method, endpoint, resp.StatusCode, respBody)
-	}
+type eventSubSubscription struct {
+	ID        string            `json:"id"`
+	Status    string            `json:"status"`
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Condition map[string]string `json:"condition"`
+	Transport conduitTransport  `json:"transport"`
+}
 
-	return resp.StatusCode, nil
+type subscriptionListResponse struct {
+	Data       []eventSubSubscription `json:"data"`
+	Pagination struct {
+		Cursor string `json:"cursor"`
+	} `json:"pagination"`
 }
 
-// getOrCreateConduit returns the ID of the first existing conduit, or creates
-// a new single-shard conduit if none exist.
-func getOrCreateConduit(clientID, appToken string) (string, error) {
+// getOrCreateConduit returns the ID of the first existing conduit, resized to
+// shardCount if needed, or creates a new conduit with that many shards.
+func (c *twitchAPI) getOrCreateConduit(ctx context.Context, shardCount int) (string, error) {
 	var list conduitListResponse
-	if _, err := twitchRequest(http.MethodGet, clientID, appToken, "/eventsub/conduits", nil, &list); err != nil {
+	if _, err := c.Do(ctx, http.MethodGet, "/eventsub/conduits", nil, &list); err != nil {
 		return "", fmt.Errorf("list conduits: %w", err)
 	}
 
 	if len(list.Data) > 0 {
-		return list.Data[0].ID, nil
+		conduit := list.Data[0]
+		if conduit.ShardCount != shardCount {
+			if _, err := c.Do(
+				ctx,
+				http.MethodPatch,
+				"/eventsub/conduits",
+				updateConduitRequest{ID: conduit.ID, ShardCount: shardCount},
+				nil,
+			); err != nil {
+				return "", fmt.Errorf("resize conduit: %w", err)
+			}
+		}
+		return conduit.ID, nil
 	}
 
 	var created conduitListResponse
-	if _, err := twitchRequest(
+	if _, err := c.Do(
+		ctx,
 		http.MethodPost,
-		clientID,
-		appToken,
 		"/eventsub/conduits",
-		createConduitRequest{ShardCount: 1},
+		createConduitRequest{ShardCount: shardCount},
 		&created,
 	); err != nil {
 		return "", fmt.Errorf("create conduit: %w", err)
@@ -134,13 +121,13 @@ func getOrCreateConduit(clientID, appToken string) (string, error) {
 	return created.Data[0].ID, nil
 }
 
-// updateConduitShard points shard 0 of the conduit at the given WebSocket session.
-func updateConduitShard(clientID, appToken, conduitID, sessionID string) error {
+// updateConduitShard points one shard of the conduit at the given WebSocket session.
+func (c *twitchAPI) updateConduitShard(ctx context.Context, conduitID, shardID, sessionID string) error {
 	payload := updateShardsRequest{
 		ConduitID: conduitID,
 		Shards: []shardData{
 			{
-				ID: "0",
+				ID: shardID,
 				Transport: shardTransport{
 					Method:    "websocket",
 					SessionID: sessionID,
@@ -150,15 +137,14 @@ func updateConduitShard(clientID, appToken, conduitID, sessionID string) error {
 	}
 
 	var result updateShardsResponse
-	if _, err := twitchRequest(
+	if _, err := c.Do(
+		ctx,
 		http.MethodPatch,
-		clientID,
-		appToken,
 		"/eventsub/conduits/shards",
 		payload,
 		&result,
 	); err != nil {
-		return fmt.Errorf("update conduit shard: %w", err)
+		return fmt.Errorf("update conduit shard %s: %w", shardID, err)
 	}
 
 	if len(result.Errors) > 0 {
@@ -169,11 +155,13 @@ func updateConduitShard(clientID, appToken, conduitID, sessionID string) error {
 }
 
 // createConduitSubscription creates an EventSub subscription using conduit
-// transport. A 409 Conflict (already exists) is treated as success.
-func createConduitSubscription(
-	clientID, appToken, conduitID, subType, version string,
+// transport and returns its ID. A 409 Conflict (already exists) is treated as
+// success with an empty ID.
+func (c *twitchAPI) createConduitSubscription(
+	ctx context.Context,
+	conduitID, subType, version string,
 	condition map[string]string,
-) error {
+) (string, error) {
 	payload := conduitSubscriptionRequest{
 		Type:      subType,
 		Version:   version,
@@ -184,10 +172,53 @@ func createConduitSubscription(
 		},
 	}
 
-	status, err := twitchRequest(http.MethodPost, clientID, appToken, "/eventsub/subscriptions", payload, nil)
-	if err != nil && status != http.StatusConflict {
-		return err
+	var created subscriptionListResponse
+	status, err := c.Do(ctx, http.MethodPost, "/eventsub/subscriptions", payload, &created)
+	if err != nil {
+		if status == http.StatusConflict {
+			return "", nil
+		}
+		return "", err
+	}
+	if len(created.Data) == 0 {
+		return "", nil
+	}
+
+	return created.Data[0].ID, nil
+}
+
+// listConduitSubscriptions returns every EventSub subscription delivered to
+// the given conduit, following pagination.
+func (c *twitchAPI) listConduitSubscriptions(ctx context.Context, conduitID string) ([]eventSubSubscription, error) {
+	var subs []eventSubSubscription
+	cursor := ""
+	for {
+		endpoint := "/eventsub/subscriptions"
+		if cursor != "" {
+			endpoint += "?after=" + url.QueryEscape(cursor)
+		}
+		var page subscriptionListResponse
+		if _, err := c.Do(ctx, http.MethodGet, endpoint, nil, &page); err != nil {
+			return nil, fmt.Errorf("list subscriptions: %w", err)
+		}
+		for _, sub := range page.Data {
+			if sub.Transport.Method == "conduit" && sub.Transport.ConduitID == conduitID {
+				subs = append(subs, sub)
+			}
+		}
+		if page.Pagination.Cursor == "" {
+			return subs, nil
+		}
+		cursor = page.Pagination.Cursor
 	}
+}
 
+// deleteSubscription removes an EventSub subscription. A 404 means it is
+// already gone and is treated as success.
+func (c *twitchAPI) deleteSubscription(ctx context.Context, id string) error {
+	status, err := c.Do(ctx, http.MethodDelete, "/eventsub/subscriptions?id="+url.QueryEscape(id), nil, nil)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("delete subscription %s: %w", id, err)
+	}
 	return nil
 }
@@ -5,17 +5,47 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 )
 
+// defaultCommandPrefixes returns the command prefixes used when none are
+// configured.
+func defaultCommandPrefixes() []string {
+	return []string{"!"}
+}
+
 type Config struct {
 	ClientID     string
 	ClientSecret string
 
 	BotUserID     string
 	ChannelUserID string
+	// BotIsModerator raises the chat rate limit to the one Twitch allows
+	// moderators.
+	BotIsModerator bool
+
+	// CommandPrefixes are the prefixes chat commands may start with, such as
+	// "!". The first one is used when the bot refers to a command.
+	CommandPrefixes []string
+
+	// ConduitShardCount is the number of conduit shards, each served by its own
+	// EventSub WebSocket session.
+	ConduitShardCount int
+
+	// TwitchAPIBaseURL overrides the Helix base URL, e.g. for the Twitch CLI mock API.
+	TwitchAPIBaseURL string
 
 	OAuthRedirectURI string
 	DBPath           string
+	// MessagesPath names an optional JSON file of chat message variants that
+	// replace the catalog defaults key by key.
+	MessagesPath string
+
+	AdminUserIDs       []string
+	AdminSessionSecret string
+
+	// TokenEncryptionKey is the base64-encoded AES-256 key for stored OAuth tokens.
+	TokenEncryptionKey string
 
 	UseMockServer bool
 	ServerPort    int
@@ -30,6 +60,13 @@ func LoadConfig() Config {
 		}
 	}
 
+	shardCount := 1
+	if v := os.Getenv("CONDUIT_SHARD_COUNT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			shardCount = n
+		}
+	}
+
 	dbPath := os.Getenv("DB_PATH")
 	if dbPath == "" {
 		dbPath = "charsibot.db"
@@ -47,15 +84,35 @@ func LoadConfig() Config {
 		}
 	}
 
+	commandPrefixes := strings.Fields(strings.ReplaceAll(os.Getenv("COMMAND_PREFIXES"), ",", " "))
+	if len(commandPrefixes) == 0 {
+		commandPrefixes = defaultCommandPrefixes()
+	}
+
+	var adminUserIDs []string
+	for id := range strings.SplitSeq(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			adminUserIDs = append(adminUserIDs, id)
+		}
+	}
+
 	return Config{
-		ClientID:         os.Getenv("TWITCH_CLIENT_ID"),
-		ClientSecret:     os.Getenv("TWITCH_CLIENT_SECRET"),
-		BotUserID:        os.Getenv("TWITCH_BOT_USER_ID"),
-		ChannelUserID:    os.Getenv("TWITCH_CHANNEL_USER_ID"),
-		OAuthRedirectURI: redirectURI,
-		DBPath:           dbPath,
-		UseMockServer:    os.Getenv("USE_MOCK_SERVER") == "true",
-		ServerPort:       serverPort,
-		LogLevel:         logLevel,
+		ClientID:           os.Getenv("TWITCH_CLIENT_ID"),
+		ClientSecret:       os.Getenv("TWITCH_CLIENT_SECRET"),
+		BotUserID:          os.Getenv("TWITCH_BOT_USER_ID"),
+		ChannelUserID:      os.Getenv("TWITCH_CHANNEL_USER_ID"),
+		BotIsModerator:     os.Getenv("TWITCH_BOT_IS_MODERATOR") == "true",
+		CommandPrefixes:    commandPrefixes,
+		ConduitShardCount:  shardCount,
+		TwitchAPIBaseURL:   os.Getenv("TWITCH_API_BASE_URL"),
+		OAuthRedirectURI:   redirectURI,
+		DBPath:             dbPath,
+		MessagesPath:       os.Getenv("MESSAGES_PATH"),
+		AdminUserIDs:       adminUserIDs,
+		AdminSessionSecret: os.Getenv("ADMIN_SESSION_SECRET"),
+		TokenEncryptionKey: os.Getenv("TOKEN_ENCRYPTION_KEY"),
+		UseMockServer:      os.Getenv("USE_MOCK_SERVER") == "true",
+		ServerPort:         serverPort,
+		LogLevel:           logLevel,
 	}
 }
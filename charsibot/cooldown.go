@@ -0,0 +1,51 @@
+package charsibot
+
+import (
+	"context"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/cooldowns"
+)
+
+// acquireCommandCooldown starts the command's cooldowns and reports whether
+// it may run. When it may not, the chatter gets a throttled notice if the
+// command asks for one.
+func (b *Bot) acquireCommandCooldown(
+	ctx context.Context,
+	name string,
+	command Command,
+	event twitch.EventChannelChatMessage,
+) bool {
+	if b.cooldowns == nil || (command.Cooldown <= 0 && command.UserCooldown <= 0) {
+		return true
+	}
+	key := "command:" + name
+	ok, remaining := b.cooldowns.Acquire(ctx,
+		cooldowns.Cooldown{Key: key, Duration: command.Cooldown},
+		cooldowns.Cooldown{Key: key + ":user:" + event.ChatterUserId, Duration: command.UserCooldown},
+	)
+	if ok {
+		return true
+	}
+
+	b.logger.Debug("command on cooldown", "command", name, "user", event.ChatterUserName, "remaining", remaining)
+	if command.OnCooldown != CooldownReply {
+		return false
+	}
+	notice := cooldowns.Cooldown{Key: key + ":notice:" + event.ChatterUserId, Duration: remaining}
+	if noticeOK, _ := b.cooldowns.Acquire(ctx, notice); noticeOK {
+		b.replyWith(event, catalog.MessageCommandCooldown, catalog.CooldownData{
+			Command:   b.commandPrefix() + name,
+			Remaining: formatCooldown(remaining),
+		})
+	}
+	return false
+}
+
+// formatCooldown rounds d up to whole seconds, e.g. "45s" or "1m30s".
+func formatCooldown(d time.Duration) string {
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
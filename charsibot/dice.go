@@ -0,0 +1,333 @@
+package charsibot
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// Limits on dice expressions, which chatters can type with !roll.
+const (
+	maxDice     = 20
+	maxDieSides = 1000
+	maxDiceTerm = 1000
+)
+
+const (
+	criticalSuccess = "success"
+	criticalFailure = "failure"
+)
+
+var (
+	diceTermPattern = regexp.MustCompile(`^(\d*)[dD](\d+)$`)
+	statTermPattern = regexp.MustCompile(`^[A-Za-z]+$`)
+)
+
+// diceExpr is a parsed dice expression such as "1d20+STR-1": dice, stats and
+// numbers added or subtracted in order.
+type diceExpr struct {
+	text  string
+	terms []diceTerm
+}
+
+// diceTerm is one part of a dice expression. Count and sides are set for
+// dice, stat for a stat modifier, and value for a plain number.
+type diceTerm struct {
+	negative bool
+	count    int64
+	sides    int64
+	stat     string
+	value    int64
+}
+
+// diceProblem is what is wrong with a dice expression.
+type diceProblem int
+
+const (
+	diceNone diceProblem = iota
+	diceTooMany
+	diceMalformed
+	diceBadSides
+	diceNumberTooBig
+	diceBadTerm
+	diceUnknownStat
+)
+
+// diceError is a problem with a dice expression. term is the part of the
+// expression at fault, for the problems that have one.
+type diceError struct {
+	problem diceProblem
+	term    string
+}
+
+func (e *diceError) Error() string {
+	switch e.problem {
+	case diceNone:
+		return "no dice to roll"
+	case diceTooMany:
+		return fmt.Sprintf("more than %d dice", maxDice)
+	case diceMalformed:
+		return "empty term"
+	case diceBadSides:
+		return fmt.Sprintf("dice need between 2 and %d sides", maxDieSides)
+	case diceNumberTooBig:
+		return fmt.Sprintf("numbers must be at most %d", maxDiceTerm)
+	case diceBadTerm:
+		return fmt.Sprintf("%q is not a die, stat or number", e.term)
+	case diceUnknownStat:
+		return fmt.Sprintf("unknown stat %q", e.term)
+	default:
+		return "bad dice"
+	}
+}
+
+// diceRoll is the result of rolling a diceExpr.
+type diceRoll struct {
+	expr  diceExpr
+	terms []rolledTerm
+	total int64
+	// critical is criticalSuccess or criticalFailure when the roll was a
+	// single d20 that came up 20 or 1.
+	critical string
+}
+
+// rolledTerm is what one term of an expression came to.
+type rolledTerm struct {
+	term diceTerm
+	// dice are the faces rolled for a dice term.
+	dice []int64
+	// name is the short name of a stat term.
+	name  string
+	value int64
+}
+
+// parseDice parses a dice expression. Its errors are diceErrors.
+func parseDice(text string) (diceExpr, error) {
+	expr := diceExpr{text: text}
+	rest := text
+	dice := int64(0)
+	for first := true; first || rest != ""; first = false {
+		negative := false
+		switch {
+		case strings.HasPrefix(rest, "+"):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "-"):
+			negative, rest = true, rest[1:]
+		}
+		end := strings.IndexAny(rest, "+-")
+		if end < 0 {
+			end = len(rest)
+		}
+		term, err := parseDiceTerm(rest[:end])
+		if err != nil {
+			return diceExpr{}, err
+		}
+		term.negative = negative
+		dice += term.count
+		expr.terms = append(expr.terms, term)
+		rest = rest[end:]
+	}
+	if dice == 0 {
+		return diceExpr{}, &diceError{problem: diceNone}
+	}
+	if dice > maxDice {
+		return diceExpr{}, &diceError{problem: diceTooMany}
+	}
+	return expr, nil
+}
+
+func parseDiceTerm(text string) (diceTerm, error) {
+	if text == "" {
+		return diceTerm{}, &diceError{problem: diceMalformed}
+	}
+	if match := diceTermPattern.FindStringSubmatch(text); match != nil {
+		count := int64(1)
+		if match[1] != "" {
+			count, _ = strconv.ParseInt(match[1], 10, 64)
+		}
+		sides, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil || sides < 2 || sides > maxDieSides {
+			return diceTerm{}, &diceError{problem: diceBadSides, term: text}
+		}
+		if count < 1 || count > maxDice {
+			return diceTerm{}, &diceError{problem: diceTooMany}
+		}
+		return diceTerm{count: count, sides: sides}, nil
+	}
+	if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		if value > maxDiceTerm {
+			return diceTerm{}, &diceError{problem: diceNumberTooBig, term: text}
+		}
+		return diceTerm{value: value}, nil
+	}
+	if statTermPattern.MatchString(text) {
+		return diceTerm{stat: text}, nil
+	}
+	return diceTerm{}, &diceError{problem: diceBadTerm, term: text}
+}
+
+// checkStats reports a stat term that names none of definitions as a
+// diceError.
+func (e diceExpr) checkStats(definitions []stats.Definition) error {
+	for _, term := range e.terms {
+		if term.stat == "" {
+			continue
+		}
+		if _, ok := findStat(definitions, term.stat); !ok {
+			return &diceError{problem: diceUnknownStat, term: term.stat}
+		}
+	}
+	return nil
+}
+
+// statModifier is what a stat adds to a roll: half its value, rounded down,
+// so a new viewer's 3 is worth +1.
+func statModifier(value int64) int64 {
+	modifier := value / 2
+	if value < 0 && value%2 != 0 {
+		modifier--
+	}
+	return modifier
+}
+
+// rollDie returns a random face of a die with the given number of sides.
+func rollDie(sides int64) int64 {
+	return 1 + rand.Int64N(sides)
+}
+
+// roll rolls the expression with modifiers from userStats. die rolls a single
+// die.
+func (e diceExpr) roll(userStats []stats.UserStat, die func(sides int64) int64) (diceRoll, error) {
+	roll := diceRoll{expr: e, terms: make([]rolledTerm, 0, len(e.terms))}
+	for _, term := range e.terms {
+		rolled := rolledTerm{term: term}
+		switch {
+		case term.count > 0:
+			for range term.count {
+				face := die(term.sides)
+				rolled.dice = append(rolled.dice, face)
+				rolled.value += face
+			}
+		case term.stat != "":
+			stat, ok := findUserStat(userStats, term.stat)
+			if !ok {
+				return diceRoll{}, fmt.Errorf("unknown stat %q", term.stat)
+			}
+			rolled.name, rolled.value = stat.ShortName, statModifier(stat.Value)
+		default:
+			rolled.value = term.value
+		}
+		if term.negative {
+			rolled.value = -rolled.value
+		}
+		roll.total += rolled.value
+		roll.terms = append(roll.terms, rolled)
+	}
+
+	// Only a roll of a single die can be critical, and only when it is a d20.
+	if dice := roll.dice(); len(dice) == 1 && dice[0].Sides == 20 && dice[0].Value > 0 {
+		switch dice[0].Value {
+		case 20:
+			roll.critical = criticalSuccess
+		case 1:
+			roll.critical = criticalFailure
+		}
+	}
+	return roll, nil
+}
+
+// findUserStat matches a stat by name or short name, ignoring case.
+func findUserStat(userStats []stats.UserStat, name string) (stats.UserStat, bool) {
+	for _, stat := range userStats {
+		if strings.EqualFold(stat.Name, name) || strings.EqualFold(stat.ShortName, name) {
+			return stat, true
+		}
+	}
+	return stats.UserStat{}, false
+}
+
+// detail shows each term of the roll, like "[17] + 1 (LUCK)".
+func (r diceRoll) detail() string {
+	var detail strings.Builder
+	for i, rolled := range r.terms {
+		// Dice keep the sign they were written with; modifiers show the sign
+		// of what they add, so a low stat reads as "- 1 (LUCK)".
+		negative := rolled.value < 0
+		if rolled.term.count > 0 {
+			negative = rolled.term.negative
+		}
+		switch {
+		case negative:
+			detail.WriteString(" - ")
+		case i > 0:
+			detail.WriteString(" + ")
+		}
+		value := rolled.value
+		if value < 0 {
+			value = -value
+		}
+		switch {
+		case rolled.term.count > 0:
+			faces := make([]string, len(rolled.dice))
+			for j, face := range rolled.dice {
+				faces[j] = strconv.FormatInt(face, 10)
+			}
+			detail.WriteString("[" + strings.Join(faces, ", ") + "]")
+		case rolled.name != "":
+			fmt.Fprintf(&detail, "%d (%s)", value, rolled.name)
+		default:
+			detail.WriteString(strconv.FormatInt(value, 10))
+		}
+	}
+	return strings.TrimSpace(detail.String())
+}
+
+// data describes the roll to catalog messages.
+func (r diceRoll) data() catalog.RollData {
+	return catalog.RollData{
+		Expression:      r.expr.text,
+		Detail:          r.detail(),
+		Total:           r.total,
+		CriticalSuccess: r.critical == criticalSuccess,
+		CriticalFailure: r.critical == criticalFailure,
+	}
+}
+
+// overlayEvent describes the roll to the overlay.
+func (r diceRoll) overlayEvent(username string) server.OverlayEvent {
+	data := server.DiceRollData{
+		Username:   username,
+		Expression: r.expr.text,
+		Dice:       []server.DieRoll{},
+		Modifiers:  []server.RollModifier{},
+		Total:      r.total,
+		Critical:   r.critical,
+	}
+	data.Dice = append(data.Dice, r.dice()...)
+	for _, rolled := range r.terms {
+		if rolled.term.count == 0 {
+			data.Modifiers = append(data.Modifiers, server.RollModifier{Name: rolled.name, Value: rolled.value})
+		}
+	}
+	return server.OverlayEvent{Type: server.EventTypeDiceRoll, Data: data}
+}
+
+// dice returns every die rolled, with subtracted dice as negative values.
+func (r diceRoll) dice() []server.DieRoll {
+	var dice []server.DieRoll
+	for _, rolled := range r.terms {
+		for _, face := range rolled.dice {
+			if rolled.term.negative {
+				face = -face
+			}
+			dice = append(dice, server.DieRoll{Sides: rolled.term.sides, Value: face})
+		}
+	}
+	return dice
+}
@@ -0,0 +1,230 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// fixedDice returns a die roller that returns faces in order.
+func fixedDice(faces ...int64) func(int64) int64 {
+	return func(int64) int64 {
+		face := faces[0]
+		faces = faces[1:]
+		return face
+	}
+}
+
+func TestParseDice(t *testing.T) {
+	valid := []string{"1d20", "d20", "1D20+STR", "2d6-1", "1d20+luck+2", "-1d4+1d8", "1d20-DEX"}
+	for _, text := range valid {
+		if _, err := parseDice(text); err != nil {
+			t.Errorf("parseDice(%q) = %v", text, err)
+		}
+	}
+
+	invalid := []string{
+		"", "STR", "5", "1d20+", "1d1", "1d1001", "21d6", "11d6+10d6", "1d20+ST4R", "1d20++2", "1d20+1001",
+	}
+	for _, text := range invalid {
+		_, err := parseDice(text)
+		var diceErr *diceError
+		if !errors.As(err, &diceErr) {
+			t.Errorf("parseDice(%q) = %v, want a diceError", text, err)
+		}
+	}
+}
+
+func TestStatModifier(t *testing.T) {
+	tests := map[int64]int64{0: 0, 1: 0, 3: 1, 10: 5, -1: -1, -2: -1, -3: -2}
+	for value, want := range tests {
+		if got := statModifier(value); got != want {
+			t.Errorf("statModifier(%d) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestDiceRoll(t *testing.T) {
+	userStats := []stats.UserStat{
+		{Name: "strength", ShortName: "STR", Value: 7},
+		{Name: "luck", ShortName: "LUCK", Value: -3},
+	}
+	tests := []struct {
+		text     string
+		faces    []int64
+		total    int64
+		detail   string
+		critical string
+	}{
+		{text: "1d20+STR", faces: []int64{12}, total: 15, detail: "[12] + 3 (STR)"},
+		{
+			text: "1d20+luck-1", faces: []int64{20}, total: 17,
+			detail: "[20] - 2 (LUCK) - 1", critical: criticalSuccess,
+		},
+		{text: "d20", faces: []int64{1}, total: 1, detail: "[1]", critical: criticalFailure},
+		{text: "2d20", faces: []int64{20, 20}, total: 40, detail: "[20, 20]"},
+		{text: "1d6-1d4", faces: []int64{6, 4}, total: 2, detail: "[6] - [4]"},
+		{text: "1d20-STR", faces: []int64{20}, total: 17, detail: "[20] - 3 (STR)", critical: criticalSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			expr, err := parseDice(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roll, err := expr.roll(userStats, fixedDice(tt.faces...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if roll.total != tt.total || roll.detail() != tt.detail || roll.critical != tt.critical {
+				t.Fatalf("roll = %d %q %q, want %d %q %q",
+					roll.total, roll.detail(), roll.critical, tt.total, tt.detail, tt.critical)
+			}
+		})
+	}
+
+	expr, _ := parseDice("1d20+CHA")
+	if _, err := expr.roll(userStats, fixedDice(10)); err == nil {
+		t.Fatal("expected an error for a stat the user does not have")
+	}
+}
+
+func TestDiceRollOverlayEvent(t *testing.T) {
+	expr, _ := parseDice("2d6-1d4+STR+1")
+	roll, err := expr.roll([]stats.UserStat{{Name: "strength", ShortName: "STR", Value: 4}}, fixedDice(3, 5, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := roll.overlayEvent("Viewer")
+	data, ok := event.Data.(server.DiceRollData)
+	if event.Type != server.EventTypeDiceRoll || !ok {
+		t.Fatalf("event = %+v", event)
+	}
+	wantDice := []server.DieRoll{{Sides: 6, Value: 3}, {Sides: 6, Value: 5}, {Sides: 4, Value: -2}}
+	wantModifiers := []server.RollModifier{{Name: "STR", Value: 2}, {Value: 1}}
+	if !slices.Equal(data.Dice, wantDice) || !slices.Equal(data.Modifiers, wantModifiers) || data.Total != 9 {
+		t.Fatalf("data = %+v", data)
+	}
+}
+
+func createTestBotForRoll(t *testing.T) (*Bot, chan server.OverlayEvent) {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	appCatalog := testCatalog(t)
+	statsService, err := stats.NewService(queries, appCatalog.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broadcast, events := newBroadcast()
+	b := createTestBot(t)
+	b.statsService = statsService
+	b.broadcast = broadcast
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.commands = Commands(appCatalog.Series)
+	return b, events
+}
+
+func TestRollCommand(t *testing.T) {
+	b, events := createTestBotForRoll(t)
+	message := func(text string) {
+		event := chatFrom("u1")
+		event.ChatterUserName = "Viewer"
+		event.MessageId = "m1"
+		event.Message.Text = text
+		b.processCommand(event)
+	}
+
+	message("!roll 1d20+str")
+	if len(b.chat.replies) != 1 || !strings.HasPrefix(b.chat.replies[0].Message, "Viewer rolled 1d20+str: [") {
+		t.Fatalf("replies = %+v", b.chat.replies)
+	}
+	event := <-events
+	if data, ok := event.Data.(server.DiceRollData); !ok || data.Username != "Viewer" || len(data.Dice) != 1 {
+		t.Fatalf("overlay event = %+v", event)
+	}
+
+	for text, want := range map[string]string{
+		"!roll 1d20+charm": `Unknown stat "charm".`,
+		"!roll 21d6":       "Roll at most 20 dice.",
+		"!roll 1d1":        "Dice need between 2 and 1000 sides.",
+		"!roll 1d20+ST4R":  `"ST4R" is not a die, stat or number.`,
+		"!roll 1d20+":      "Dice look like 1d20+STR.",
+		"!roll 5":          "Roll at least one die, like 1d20.",
+		"!roll 1d20+1001":  "Numbers in a roll must be at most 1000.",
+	} {
+		message(text)
+		if got := b.chat.replies[len(b.chat.replies)-1].Message; got != want {
+			t.Errorf("%s: reply = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestCriticalEffectFollowsTheLastRoll(t *testing.T) {
+	b, _ := createTestBotForRoll(t)
+	ctx := context.Background()
+	if _, err := b.statsService.GetOrCreateStats(ctx, "u1", "Viewer"); err != nil {
+		t.Fatal(err)
+	}
+	critical := catalog.Effect{
+		Type: catalog.EffectCritical,
+		CriticalSuccess: []catalog.Outcome{
+			{Weight: 1, Effects: []catalog.Effect{{Type: catalog.EffectModifyStat, Stat: "luck", Min: 1, Max: 1}}},
+		},
+		CriticalFailure: []catalog.Outcome{
+			{Weight: 1, Effects: []catalog.Effect{{Type: catalog.EffectModifyStat, Stat: "luck", Min: -2, Max: -2}}},
+		},
+	}
+	for _, roll := range []catalog.RollData{{CriticalSuccess: true}, {}, {CriticalFailure: true}} {
+		run := &effectRun{userID: "u1", data: catalog.EffectData{User: "Viewer", Roll: roll}}
+		if err := b.runEffects(ctx, run, []catalog.Effect{critical}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	values, err := b.statsService.GetUserStats(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := slices.IndexFunc(values, func(stat stats.UserStat) bool { return stat.Name == "luck" })
+	if idx < 0 || values[idx].Value != 2 {
+		t.Fatalf("stats = %+v, want luck 3 + 1 - 2", values)
+	}
+}
+
+func TestNewRejectsBadRewardDice(t *testing.T) {
+	catalogRewards := []catalog.Reward{{
+		Key:     "bad-dice",
+		Title:   "Bad Dice",
+		Cost:    1,
+		Effects: []catalog.Effect{{Type: catalog.EffectRoll, Dice: "1d20+"}},
+	}}
+	_, err := New(Config{}, Deps{Rewards: catalogRewards}, slog.New(slog.DiscardHandler))
+	if err == nil {
+		t.Fatal("expected an error for a reward with bad dice")
+	}
+}
+
+func TestRollEffectSetsRollData(t *testing.T) {
+	b, events := createTestBotForRoll(t)
+	run := &effectRun{userID: "u1", data: catalog.EffectData{User: "Viewer"}}
+	err := b.runEffects(context.Background(), run, []catalog.Effect{{Type: catalog.EffectRoll, Dice: "1d20+LUCK"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A new viewer's luck of 3 adds 1.
+	if run.data.Roll.Expression != "1d20+LUCK" || run.data.Roll.Total < 2 || run.data.Roll.Total > 21 {
+		t.Fatalf("roll = %+v", run.data.Roll)
+	}
+	if event := <-events; event.Type != server.EventTypeDiceRoll {
+		t.Fatalf("overlay event = %+v", event)
+	}
+}
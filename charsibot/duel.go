@@ -0,0 +1,225 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/duels"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+const duelLeaderboardSize = 5
+
+// duelCommands returns !duel and the commands to answer a duel and see how
+// viewers have fared.
+func duelCommands() map[string]Command {
+	return map[string]Command{
+		"duel": {
+			Description:  "Challenge a chatter to a duel of one stat or all of them, optionally wagering some of that stat.",
+			UserCooldown: 10 * time.Second,
+			OnCooldown:   CooldownReply,
+			Args: []Arg{
+				{Name: "user", Kind: ArgUser},
+				{Name: "stat", Optional: true},
+				{Name: "wager", Kind: ArgInt, Optional: true},
+			},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				opponent, _ := args.User("user")
+				if opponent.ID == event.ChatterUserId {
+					b.replyWith(event, catalog.MessageDuelSelf, struct{}{})
+					return
+				}
+				var definition stats.Definition
+				if name := args.Word("stat"); name != "" {
+					var ok bool
+					if definition, ok = findStat(b.statsService.Definitions(), name); !ok {
+						b.replyWith(event, catalog.MessageUnknownStat, catalog.UnknownStatData{Stat: name})
+						return
+					}
+				}
+				wager := args.Int("wager")
+				if wager < 0 {
+					b.replyWith(event, catalog.MessageDuelNegativeWager, struct{}{})
+					return
+				}
+				if wager > 0 {
+					userStats, err := b.statsService.GetOrCreateStats(ctx, event.ChatterUserId, event.ChatterUserName)
+					if err != nil {
+						b.logger.Error("failed to get stats", "err", err, "user", event.ChatterUserName)
+						return
+					}
+					if duels.Strength(userStats, definition.Name) < wager {
+						b.replyWith(event, catalog.MessageDuelCannotAfford, catalog.DuelWagerData{
+							User:  event.ChatterUserName,
+							Stat:  definition,
+							Wager: wager,
+						})
+						return
+					}
+				}
+
+				challenge, err := b.duels.Challenge(duels.Challenge{
+					ChallengerID:   event.ChatterUserId,
+					ChallengerName: event.ChatterUserName,
+					OpponentID:     opponent.ID,
+					OpponentName:   opponent.DisplayName,
+					Stat:           definition.Name,
+					Wager:          wager,
+				})
+				if errors.Is(err, duels.ErrBusy) {
+					b.replyWith(event, catalog.MessageDuelBusy, struct{}{})
+					return
+				}
+				if err != nil {
+					b.logger.Error("failed to start duel", "err", err, "user", event.ChatterUserName)
+					return
+				}
+				b.wg.Go(func() {
+					b.expireDuel(challenge)
+				})
+				b.say(catalog.MessageDuelChallenge, b.duelData(*challenge))
+			},
+		},
+		"accept": {
+			Description: "Accept the duel you were challenged to.",
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, _ Args) {
+				challenge, ok := b.duels.Answer(event.ChatterUserId)
+				if !ok {
+					b.replyWith(event, catalog.MessageDuelNoChallenge, struct{}{})
+					return
+				}
+				b.fightDuel(ctx, event, challenge)
+			},
+		},
+		"decline": {
+			Description: "Decline the duel you were challenged to.",
+			Execute: func(_ context.Context, b *Bot, event twitch.EventChannelChatMessage, _ Args) {
+				challenge, ok := b.duels.Answer(event.ChatterUserId)
+				if !ok {
+					b.replyWith(event, catalog.MessageDuelNoChallenge, struct{}{})
+					return
+				}
+				b.say(catalog.MessageDuelDeclined, b.duelData(challenge))
+			},
+		},
+		"duels": {
+			Description:  "Show the best duellists, or a chatter's duel record.",
+			UserCooldown: 30 * time.Second,
+			OnCooldown:   CooldownReply,
+			Args:         []Arg{{Name: "user", Kind: ArgUser, Optional: true}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				user, ok := args.User("user")
+				if !ok {
+					standings, err := b.duels.Leaderboard(ctx, duelLeaderboardSize)
+					if err != nil {
+						b.logger.Error("failed to get duel leaderboard", "err", err)
+						return
+					}
+					data := catalog.DuelLeaderboardData{Standings: make([]catalog.DuelStanding, len(standings))}
+					for i, standing := range standings {
+						data.Standings[i] = catalog.DuelStanding{
+							User:   standing.Username,
+							Wins:   standing.Wins,
+							Losses: standing.Losses,
+						}
+					}
+					b.replyWith(event, catalog.MessageDuelLeaderboard, data)
+					return
+				}
+				standing, err := b.duels.Standing(ctx, user.ID)
+				if err != nil {
+					b.logger.Error("failed to get duel record", "err", err, "user", user.Login)
+					return
+				}
+				b.replyWith(event, catalog.MessageDuelRecord, catalog.DuelRecordData{
+					User:   user.DisplayName,
+					Wins:   standing.Wins,
+					Losses: standing.Losses,
+				})
+			},
+		},
+	}
+}
+
+// fightDuel resolves an accepted duel, moves the wager from the loser to the
+// winner and records the result.
+func (b *Bot) fightDuel(ctx context.Context, event twitch.EventChannelChatMessage, c duels.Challenge) {
+	challengerStats, err := b.statsService.GetOrCreateStats(ctx, c.ChallengerID, c.ChallengerName)
+	if err != nil {
+		b.logger.Error("failed to get stats", "err", err, "user", c.ChallengerName)
+		return
+	}
+	opponentStats, err := b.statsService.GetOrCreateStats(ctx, c.OpponentID, c.OpponentName)
+	if err != nil {
+		b.logger.Error("failed to get stats", "err", err, "user", c.OpponentName)
+		return
+	}
+	challengerStrength := duels.Strength(challengerStats, c.Stat)
+	opponentStrength := duels.Strength(opponentStats, c.Stat)
+	data := b.duelData(c)
+
+	// Stats can change while a duel waits, so both sides are checked again.
+	for _, side := range []struct {
+		name     string
+		strength int64
+	}{{c.ChallengerName, challengerStrength}, {c.OpponentName, opponentStrength}} {
+		if c.Wager > 0 && side.strength < c.Wager {
+			b.replyWith(event, catalog.MessageDuelCalledOff, catalog.DuelWagerData{
+				User:  side.name,
+				Stat:  data.Stat,
+				Wager: c.Wager,
+			})
+			return
+		}
+	}
+
+	challengerWon := duels.ChallengerWins(challengerStrength, opponentStrength)
+	result := catalog.DuelResultData{DuelData: data, Winner: c.OpponentName, Loser: c.ChallengerName}
+	winnerID, loserID := c.OpponentID, c.ChallengerID
+	if challengerWon {
+		result.Winner, result.Loser = c.ChallengerName, c.OpponentName
+		winnerID, loserID = c.ChallengerID, c.OpponentID
+	}
+
+	if c.Wager > 0 {
+		if err := b.statsService.TransferStatValue(ctx, loserID, winnerID, c.Stat, c.Wager); err != nil {
+			b.logger.Error("failed to pay duel wager", "err", err,
+				"winner", result.Winner, "loser", result.Loser, "stat", c.Stat)
+			b.replyWith(event, catalog.MessageDuelFailed, struct{}{})
+			return
+		}
+	}
+	if err := b.duels.Record(ctx, c, challengerWon); err != nil {
+		b.logger.Error("failed to record duel", "err", err, "challenger", c.ChallengerName, "opponent", c.OpponentName)
+	}
+	b.logger.Info("duel fought", "winner", result.Winner, "loser", result.Loser, "stat", c.Stat, "wager", c.Wager)
+	b.say(catalog.MessageDuelResult, result)
+}
+
+// expireDuel calls off the challenge if it is still unanswered once
+// duels.Timeout has passed, unless the bot shuts down first.
+func (b *Bot) expireDuel(challenge *duels.Challenge) {
+	if err := sleepContext(b.runContext(), duels.Timeout); err != nil {
+		return
+	}
+	if b.duels.Expire(challenge) {
+		b.say(catalog.MessageDuelExpired, b.duelData(*challenge))
+	}
+}
+
+// duelData returns what duel messages can refer to about c.
+func (b *Bot) duelData(c duels.Challenge) catalog.DuelData {
+	definition, _ := findStat(b.statsService.Definitions(), c.Stat)
+	return catalog.DuelData{
+		Challenger: c.ChallengerName,
+		Opponent:   c.OpponentName,
+		Stat:       definition,
+		Wager:      c.Wager,
+		Accept:     b.commandPrefix() + "accept",
+		Decline:    b.commandPrefix() + "decline",
+	}
+}
@@ -0,0 +1,215 @@
+package charsibot
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/duels"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+func createTestBotForDuels(t *testing.T) *Bot {
+	t.Helper()
+	b, _ := createTestBotForDuelsWithDB(t)
+	return b
+}
+
+func createTestBotForDuelsWithDB(t *testing.T) (*Bot, *sql.DB) {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	appCatalog := testCatalog(t)
+	statsService, err := stats.NewService(queries, appCatalog.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	duelService, err := duels.NewService(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := createTestBot(t)
+	b.statsService = statsService
+	b.duels = duelService
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.commands = Commands(appCatalog.Series)
+	return b, sqlDB
+}
+
+// duelMessage is a chat message from a viewer that mentions the other one,
+// so no Helix lookup is needed.
+func duelMessage(from, text string) twitch.EventChannelChatMessage {
+	event := chatFrom("id-" + from)
+	event.ChatterUserName = from
+	event.MessageId = "message-" + from
+	event.Message.Text = text
+	for _, name := range []string{"Alice", "Bob"} {
+		event.Message.Fragments = append(event.Message.Fragments, twitch.ChatMessageFragment{
+			Type: "mention",
+			Text: "@" + name,
+			Mention: &twitch.ChatMessageFragmentMention{
+				UserID: "id-" + name, UserLogin: strings.ToLower(name), UserName: name,
+			},
+		})
+	}
+	return event
+}
+
+func lastReply(b *Bot) string {
+	if len(b.chat.replies) == 0 {
+		return ""
+	}
+	return b.chat.replies[len(b.chat.replies)-1].Message
+}
+
+func TestDuelMovesTheWagerToTheWinner(t *testing.T) {
+	b := createTestBotForDuels(t)
+	ctx := context.Background()
+
+	b.processCommand(duelMessage("Alice", "!duel @Bob str 2"))
+	if len(b.chat.messages) != 1 || !strings.HasPrefix(b.chat.messages[0].Message, "@Bob, Alice challenges you") {
+		t.Fatalf("messages = %+v", b.chat.messages)
+	}
+	b.processCommand(duelMessage("Bob", "!accept"))
+	if len(b.chat.messages) != 2 || !strings.Contains(b.chat.messages[1].Message, "takes 2 STR") {
+		t.Fatalf("messages = %+v", b.chat.messages)
+	}
+
+	strength := map[string]int64{}
+	for _, id := range []string{"id-Alice", "id-Bob"} {
+		userStats, err := b.statsService.GetUserStats(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		strength[id] = duels.Strength(userStats, "strength")
+	}
+	if strength["id-Alice"]+strength["id-Bob"] != 6 || strength["id-Alice"] == 3 {
+		t.Fatalf("strength = %v, want the 2 STR wager moved from 3 each", strength)
+	}
+
+	alice, err := b.duels.Standing(ctx, "id-Alice")
+	if err != nil || alice.Wins+alice.Losses != 1 {
+		t.Fatalf("Standing() = %+v, %v", alice, err)
+	}
+	b.processCommand(duelMessage("Bob", "!accept"))
+	if got := lastReply(b); got != "Nobody has challenged you to a duel." {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+func TestFailedWagerLeavesStatsAndRecordUntouched(t *testing.T) {
+	b, sqlDB := createTestBotForDuelsWithDB(t)
+	ctx := t.Context()
+	if _, err := sqlDB.ExecContext(ctx, `
+CREATE TRIGGER refuse_credit BEFORE UPDATE ON user_stats WHEN NEW.value > OLD.value
+BEGIN SELECT RAISE(ABORT, 'refused'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	b.processCommand(duelMessage("Alice", "!duel @Bob str 2"))
+	b.processCommand(duelMessage("Bob", "!accept"))
+
+	if got := lastReply(b); got != "The duel failed. Please try again." {
+		t.Fatalf("reply = %q", got)
+	}
+	for _, id := range []string{"id-Alice", "id-Bob"} {
+		userStats, err := b.statsService.GetUserStats(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := duels.Strength(userStats, "strength"); got != 3 {
+			t.Fatalf("%s strength = %d, want the wager left where it was", id, got)
+		}
+	}
+	alice, err := b.duels.Standing(ctx, "id-Alice")
+	if err != nil || alice.Wins+alice.Losses != 0 {
+		t.Fatalf("Standing() = %+v, %v; want no duel recorded", alice, err)
+	}
+}
+
+func TestDuelDecline(t *testing.T) {
+	b := createTestBotForDuels(t)
+
+	b.processCommand(duelMessage("Alice", "!duel @Bob"))
+	b.processCommand(duelMessage("Alice", "!duel @Bob"))
+	if got := lastReply(b); got != "One of you is already waiting on a duel." {
+		t.Fatalf("reply = %q", got)
+	}
+	b.processCommand(duelMessage("Bob", "!decline"))
+	if got := b.chat.messages[len(b.chat.messages)-1].Message; got != "Bob declined Alice's duel." {
+		t.Fatalf("message = %q", got)
+	}
+}
+
+func TestDuelExpiryStopsAtShutdown(t *testing.T) {
+	b := createTestBotForDuels(t)
+	b.ctx, b.cancel = context.WithCancel(t.Context())
+
+	b.processCommand(duelMessage("Alice", "!duel @Bob"))
+	b.cancel()
+	waitForBot(t, b)
+	if got := b.chat.messages[len(b.chat.messages)-1].Message; strings.Contains(got, "didn't answer") {
+		t.Fatalf("message = %q, want no expiry after shutdown", got)
+	}
+}
+
+func TestDuelRejectsBadChallenges(t *testing.T) {
+	b := createTestBotForDuels(t)
+
+	tests := map[string]string{
+		"!duel @Alice":          "You can't duel yourself.",
+		"!duel @Bob charm":      `Unknown stat "charm".`,
+		"!duel @Bob str -1":     "The wager can't be negative.",
+		"!duel @Bob luck 4":     "You don't have 4 LUCK to wager.",
+		"!duel @Bob str plenty": `"plenty" is not a whole number.`,
+	}
+	for text, want := range tests {
+		b.processCommand(duelMessage("Alice", text))
+		if got := lastReply(b); got != want {
+			t.Errorf("%s: reply = %q, want %q", text, got, want)
+		}
+	}
+	if len(b.chat.messages) != 0 {
+		t.Fatalf("messages = %+v, want no challenge", b.chat.messages)
+	}
+}
+
+func TestDuelsCommand(t *testing.T) {
+	b := createTestBotForDuels(t)
+
+	b.processCommand(duelMessage("Alice", "!duels"))
+	if got := lastReply(b); got != "No duels have been fought yet." {
+		t.Fatalf("reply = %q", got)
+	}
+	b.processCommand(duelMessage("Alice", "!duel @Bob"))
+	b.processCommand(duelMessage("Bob", "!accept"))
+	b.processCommand(duelMessage("Alice", "!duels"))
+	if got := lastReply(b); !strings.HasPrefix(got, "Top duellists: ") || !strings.Contains(got, "Alice") {
+		t.Fatalf("reply = %q", got)
+	}
+	b.processCommand(duelMessage("Bob", "!duels @Alice"))
+	if got := lastReply(b); !strings.HasPrefix(got, "Alice has won ") {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+// waitForBot waits for the bot's background work to stop.
+func waitForBot(t *testing.T, b *Bot) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("background work kept running after shutdown")
+	}
+}
@@ -0,0 +1,178 @@
+package charsibot
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// effectRedemption returns the handler of a reward described by catalog
+// effects.
+func effectRedemption(reward catalog.Reward) RedemptionFunc {
+	return func(ctx context.Context, b *Bot, event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
+		if _, err := b.statsService.GetOrCreateStats(ctx, event.UserID, event.UserName); err != nil {
+			return fmt.Errorf("get or create stats: %w", err)
+		}
+		run := &effectRun{
+			reward: reward,
+			userID: event.UserID,
+			data:   catalog.EffectData{User: event.UserName},
+		}
+		if err := b.runEffects(ctx, run, reward.Effects); err != nil {
+			// The redemption is refunded, so it must not keep what it did.
+			b.undoEffects(ctx, run)
+			return err
+		}
+		return nil
+	}
+}
+
+// effectRun carries what earlier effects of a redemption did to later ones.
+type effectRun struct {
+	reward catalog.Reward
+	userID string
+	data   catalog.EffectData
+	// applied are the stat changes made so far, in order.
+	applied []statChange
+}
+
+type statChange struct {
+	stat  string
+	delta int64
+}
+
+// runEffects applies effects in order. Only stat changes return errors,
+// since a viewer whose stat did not change should be refunded; once it has
+// changed, failing to tell chat about it is logged instead.
+func (b *Bot) runEffects(ctx context.Context, run *effectRun, effects []catalog.Effect) error {
+	for _, effect := range effects {
+		switch effect.Type {
+		case catalog.EffectModifyStat:
+			if err := b.modifyStatEffect(ctx, run, effect); err != nil {
+				return err
+			}
+		case catalog.EffectChance:
+			if err := b.runEffects(ctx, run, pickOutcome(effect.Outcomes).Effects); err != nil {
+				return err
+			}
+		case catalog.EffectMessage:
+			b.say(effect.Message, run.data)
+		case catalog.EffectShowStats:
+			userStats, err := b.statsService.GetUserStats(ctx, run.userID)
+			if err != nil {
+				b.logger.Error("failed to get stats", "err", err, "user", run.data.User)
+				continue
+			}
+			b.say(catalog.MessageStats, catalog.StatsData{User: run.data.User, Stats: userStats})
+		case catalog.EffectRoll:
+			if err := b.rollEffect(ctx, run, effect); err != nil {
+				return err
+			}
+		case catalog.EffectCritical:
+			var outcomes []catalog.Outcome
+			switch {
+			case run.data.Roll.CriticalSuccess:
+				outcomes = effect.CriticalSuccess
+			case run.data.Roll.CriticalFailure:
+				outcomes = effect.CriticalFailure
+			}
+			if len(outcomes) == 0 {
+				continue
+			}
+			if err := b.runEffects(ctx, run, pickOutcome(outcomes).Effects); err != nil {
+				return err
+			}
+		case catalog.EffectOverlay:
+			b.broadcast(server.OverlayEvent{
+				Type: server.EventTypeRewardEffect,
+				Data: server.RewardEffectData{
+					Username: run.data.User,
+					Reward:   run.reward.Key,
+					Title:    run.reward.Title,
+					Stat:     run.data.Stat.Name,
+					Delta:    run.data.Delta,
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (b *Bot) modifyStatEffect(ctx context.Context, run *effectRun, effect catalog.Effect) error {
+	definition, err := b.effectStat(ctx, effect.Stat)
+	if err != nil {
+		return err
+	}
+	delta := effect.Min + rand.Int64N(effect.Max-effect.Min+1)
+	if err := b.statsService.ModifyStatValue(ctx, run.userID, definition.Name, delta); err != nil {
+		return fmt.Errorf("modify stat: %w", err)
+	}
+	run.data.Stat, run.data.Delta = definition, delta
+	run.applied = append(run.applied, statChange{stat: definition.Name, delta: delta})
+	return nil
+}
+
+// undoEffects reverts the stat changes of a redemption that failed part way,
+// newest first. It runs even when ctx is done, since that may be why the
+// redemption failed.
+func (b *Bot) undoEffects(ctx context.Context, run *effectRun) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), handlerTimeout)
+	defer cancel()
+	for _, change := range slices.Backward(run.applied) {
+		if err := b.statsService.ModifyStatValue(ctx, run.userID, change.stat, -change.delta); err != nil {
+			b.logger.Error("failed to undo stat change", "err", err, "user", run.data.User, "stat", change.stat)
+		}
+	}
+}
+
+func (b *Bot) rollEffect(ctx context.Context, run *effectRun, effect catalog.Effect) error {
+	expr, err := parseDice(effect.Dice)
+	if err != nil {
+		return fmt.Errorf("parse dice: %w", err)
+	}
+	roll, err := b.rollDice(ctx, run.userID, run.data.User, expr)
+	if err != nil {
+		return err
+	}
+	run.data.Roll = roll.data()
+	return nil
+}
+
+// effectStat returns the named stat, or a random one when name is empty.
+func (b *Bot) effectStat(ctx context.Context, name string) (stats.Definition, error) {
+	if name == "" {
+		definition, err := b.statsService.GetRandomStatDefinition(ctx)
+		if err != nil {
+			return stats.Definition{}, fmt.Errorf("get random stat definition: %w", err)
+		}
+		return definition, nil
+	}
+	definition, ok := findStat(b.statsService.Definitions(), name)
+	if !ok {
+		return stats.Definition{}, fmt.Errorf("unknown stat %q", name)
+	}
+	return definition, nil
+}
+
+// pickOutcome picks an outcome with probability proportional to its weight.
+func pickOutcome(outcomes []catalog.Outcome) catalog.Outcome {
+	total := 0
+	for _, outcome := range outcomes {
+		total += outcome.Weight
+	}
+	roll := rand.IntN(total)
+	for _, outcome := range outcomes {
+		if roll < outcome.Weight {
+			return outcome
+		}
+		roll -= outcome.Weight
+	}
+	return outcomes[len(outcomes)-1]
+}
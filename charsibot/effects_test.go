@@ -0,0 +1,112 @@
+package charsibot
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+func TestEffectRedemption(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	svc, err := stats.NewService(queries, testCatalog(t).Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broadcast, events := newBroadcast()
+	b := createTestBot(t)
+	b.statsService = svc
+	b.broadcast = broadcast
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+
+	reward := catalog.Reward{Key: "luck-boost", Title: "Luck Boost", Effects: []catalog.Effect{
+		{Type: catalog.EffectChance, Outcomes: []catalog.Outcome{
+			{Weight: 1, Effects: []catalog.Effect{{Type: catalog.EffectModifyStat, Stat: "luck", Min: 2, Max: 2}}},
+		}},
+		{Type: catalog.EffectMessage, Message: "reward.drink-a-potion"},
+		{Type: catalog.EffectShowStats},
+		{Type: catalog.EffectOverlay},
+	}}
+	err = effectRedemption(reward)(context.Background(), b, twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
+		User: twitch.User{UserID: "u1", UserName: "Viewer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := svc.GetUserStats(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stat := range values {
+		if stat.Name == "luck" && stat.Value != 5 {
+			t.Fatalf("luck = %d, want 5", stat.Value)
+		}
+	}
+	if len(b.chat.messages) != 2 ||
+		!strings.HasSuffix(b.chat.messages[0].Message, "Viewer gained Luck") ||
+		!strings.HasPrefix(b.chat.messages[1].Message, "Viewer's stats: STR: ") {
+		t.Fatalf("messages = %+v", b.chat.messages)
+	}
+	event := <-events
+	data, ok := event.Data.(server.RewardEffectData)
+	if event.Type != server.EventTypeRewardEffect || !ok || data.Stat != "luck" || data.Delta != 2 {
+		t.Fatalf("overlay event = %+v", event)
+	}
+}
+
+func TestFailedEffectRedemptionUndoesStatChanges(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	svc, err := stats.NewService(queries, testCatalog(t).Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := createTestBot(t)
+	b.statsService = svc
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+
+	reward := catalog.Reward{Key: "luck-boost", Title: "Luck Boost", Effects: []catalog.Effect{
+		{Type: catalog.EffectModifyStat, Stat: "luck", Min: 2, Max: 2},
+		{Type: catalog.EffectModifyStat, Stat: "missing", Min: 1, Max: 1},
+	}}
+	err = effectRedemption(reward)(context.Background(), b, twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
+		User: twitch.User{UserID: "u1", UserName: "Viewer"},
+	})
+	if err == nil {
+		t.Fatal("expected the unknown stat to fail the redemption")
+	}
+
+	values, err := svc.GetUserStats(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stat := range values {
+		if stat.Name == "luck" && stat.Value != 3 {
+			t.Fatalf("luck = %d, want the refunded redemption's change undone", stat.Value)
+		}
+	}
+}
+
+func TestPickOutcomeFollowsWeights(t *testing.T) {
+	outcomes := []catalog.Outcome{{Weight: 1}, {Weight: 3}}
+	counts := [2]int{}
+	for range 4000 {
+		if pickOutcome(outcomes).Weight == 1 {
+			counts[0]++
+		} else {
+			counts[1]++
+		}
+	}
+	if counts[0] < 800 || counts[0] > 1200 {
+		t.Fatalf("counts = %v, want roughly 1:3", counts)
+	}
+}
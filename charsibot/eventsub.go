@@ -0,0 +1,320 @@
+package charsibot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/dedupe"
+)
+
+const (
+	eventSubURL     = "wss://eventsub.wss.twitch.tv/ws"
+	mockEventSubURL = "ws://localhost:8080/ws"
+
+	eventSubWelcomeTimeout = 10 * time.Second
+	eventSubKeepaliveGrace = 5 * time.Second
+	eventSubDefaultTimeout = 10 * time.Second
+	eventSubReadLimit      = 1 << 20
+)
+
+var errReconnectRequested = errors.New("reconnect requested")
+
+// eventSubSession is one EventSub WebSocket connection that has received its
+// session_welcome.
+type eventSubSession struct {
+	id        string
+	keepalive time.Duration
+	conn      *websocket.Conn
+}
+
+// eventSubFrame is a message, or the terminal read error, from a session.
+type eventSubFrame struct {
+	session *eventSubSession
+	data    []byte
+	err     error
+}
+
+// dialEventSub connects to url and waits for the session_welcome message.
+func dialEventSub(ctx context.Context, url string) (*eventSubSession, error) {
+	welcomeCtx, cancel := context.WithTimeout(ctx, eventSubWelcomeTimeout)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(welcomeCtx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", url, err)
+	}
+	conn.SetReadLimit(eventSubReadLimit)
+
+	_, data, err := conn.Read(welcomeCtx)
+	if err != nil {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("read welcome: %w", err)
+	}
+	var welcome twitch.WelcomeMessage
+	if err := json.Unmarshal(data, &welcome); err != nil {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("decode welcome: %w", err)
+	}
+	if welcome.Metadata.MessageType != "session_welcome" {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("expected session_welcome, got %q", welcome.Metadata.MessageType)
+	}
+
+	keepalive := time.Duration(welcome.Payload.Session.KeepaliveTimeoutSeconds) * time.Second
+	if keepalive <= 0 {
+		keepalive = eventSubDefaultTimeout
+	}
+	return &eventSubSession{id: welcome.Payload.Session.ID, keepalive: keepalive, conn: conn}, nil
+}
+
+// readFrames forwards every message on the session to frames until the
+// connection fails or ctx is done.
+func (s *eventSubSession) readFrames(ctx context.Context, frames chan<- eventSubFrame) {
+	for {
+		_, data, err := s.conn.Read(ctx)
+		select {
+		case frames <- eventSubFrame{session: s, data: data, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// keepaliveTimeout is how long the session may stay silent before it is
+// considered dead.
+func (s *eventSubSession) keepaliveTimeout() time.Duration {
+	return s.keepalive + eventSubKeepaliveGrace
+}
+
+func (s *eventSubSession) close() {
+	_ = s.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+type eventSubHandoff struct {
+	session *eventSubSession
+	err     error
+}
+
+// serveShard runs one EventSub session for shard until it fails, is asked to
+// reconnect, or ctx is done. Twitch's session_reconnect is handled in place:
+// the new socket is dialled and the shard re-pointed at it before the old one
+// is closed, so no notifications are dropped. The returned bool reports
+// whether a session was established.
+func (b *Bot) serveShard(ctx context.Context, shard *eventSubShard, url string) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session, err := dialEventSub(ctx, url)
+	if err != nil {
+		return false, err
+	}
+	defer func() { session.close() }()
+
+	if err := b.attachSession(ctx, shard, session); err != nil {
+		return false, fmt.Errorf("subscribe events: %w", err)
+	}
+	b.logger.Info("connected to twitch eventsub", "shard_id", shard.id, "session_id", session.id)
+	// Redemptions made while this shard was down were never delivered.
+	b.triggerRedemptionCatchUp()
+
+	frames := make(chan eventSubFrame)
+	handoffs := make(chan eventSubHandoff)
+	go session.readFrames(ctx, frames)
+
+	keepalive := time.NewTimer(session.keepaliveTimeout())
+	defer keepalive.Stop()
+	handingOff := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case err := <-shard.reconnect:
+			return true, err
+		case <-keepalive.C:
+			return true, fmt.Errorf("session %s missed its keepalive", session.id)
+		case result := <-handoffs:
+			handingOff = false
+			if result.err != nil {
+				return true, fmt.Errorf("reconnect handoff: %w", result.err)
+			}
+			old := session
+			session = result.session
+			go session.readFrames(ctx, frames)
+			go old.close()
+			keepalive.Reset(session.keepaliveTimeout())
+			b.logger.Info("eventsub session handed off",
+				"shard_id", shard.id,
+				"old_session_id", old.id,
+				"session_id", session.id,
+			)
+		case frame := <-frames:
+			if frame.session != session {
+				// Drain what the replaced session delivered before it closed.
+				if frame.err == nil {
+					b.handleEventSubMessage(shard, frame.data)
+				}
+				continue
+			}
+			if frame.err != nil {
+				return true, fmt.Errorf("read session %s: %w", session.id, frame.err)
+			}
+			keepalive.Reset(session.keepaliveTimeout())
+			reconnectURL := b.handleEventSubMessage(shard, frame.data)
+			if reconnectURL != "" && !handingOff {
+				handingOff = true
+				go b.handoff(ctx, shard, reconnectURL, handoffs)
+			}
+		}
+	}
+}
+
+// handoff dials the reconnect URL and points the shard at the new session.
+func (b *Bot) handoff(ctx context.Context, shard *eventSubShard, url string, result chan<- eventSubHandoff) {
+	session, err := dialEventSub(ctx, url)
+	if err == nil {
+		if err = b.attachSession(ctx, shard, session); err != nil {
+			session.close()
+			session = nil
+		}
+	}
+	select {
+	case result <- eventSubHandoff{session: session, err: err}:
+	case <-ctx.Done():
+		if session != nil {
+			session.close()
+		}
+	}
+}
+
+func (b *Bot) attachSession(ctx context.Context, shard *eventSubShard, session *eventSubSession) error {
+	if err := b.subscribeEvents(ctx, shard.id, session.id); err != nil {
+		return err
+	}
+	shard.setSession(session.id)
+	return nil
+}
+
+// handleEventSubMessage dispatches one EventSub message and returns the
+// reconnect URL when Twitch asks the session to move.
+func (b *Bot) handleEventSubMessage(shard *eventSubShard, data []byte) string {
+	var envelope struct {
+		Metadata twitch.MessageMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		b.logger.Error("failed to decode eventsub message", "shard_id", shard.id, "err", err)
+		return ""
+	}
+
+	switch envelope.Metadata.MessageType {
+	case "session_keepalive":
+	case "notification":
+		var message twitch.NotificationMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			b.logger.Error("failed to decode eventsub notification", "shard_id", shard.id, "err", err)
+			return ""
+		}
+		b.dispatchNotification(message)
+	case "session_reconnect":
+		var message twitch.ReconnectMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			b.logger.Error("failed to decode eventsub reconnect", "shard_id", shard.id, "err", err)
+			return ""
+		}
+		b.logger.Info("eventsub session reconnect requested", "shard_id", shard.id)
+		return message.Payload.Session.ReconnectUrl
+	case "revocation":
+		var message twitch.RevokeMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			b.logger.Error("failed to decode eventsub revocation", "shard_id", shard.id, "err", err)
+			return ""
+		}
+		b.logger.Warn("subscription revoked", "subscription", message.Payload.Subscription)
+		b.subscriptions.Revoked(message.Payload.Subscription)
+	default:
+		b.logger.Warn("unexpected eventsub message", "shard_id", shard.id, "type", envelope.Metadata.MessageType)
+	}
+	return ""
+}
+
+func (b *Bot) dispatchNotification(message twitch.NotificationMessage) {
+	subType := message.Payload.Subscription.Type
+	b.logger.Debug("eventsub notification", "type", subType)
+	if message.Payload.Event == nil {
+		return
+	}
+	if !b.firstDelivery(dedupe.KindNotification, message.Metadata.MessageID) {
+		return
+	}
+	data := []byte(*message.Payload.Event)
+
+	switch subType {
+	case twitch.SubChannelChatMessage:
+		var event twitch.EventChannelChatMessage
+		if b.decodeEvent(subType, data, &event) {
+			b.wg.Go(func() {
+				b.onMessage(event)
+			})
+		}
+	case twitch.SubChannelChannelPointsCustomRewardRedemptionAdd:
+		var event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd
+		if b.decodeEvent(subType, data, &event) {
+			b.wg.Go(func() {
+				b.onChannelPointRedemption(event)
+			})
+		}
+	case twitch.SubChannelRaid:
+		var event twitch.EventChannelRaid
+		if b.decodeEvent(subType, data, &event) {
+			b.wg.Go(func() {
+				b.onChannelRaid(event)
+			})
+		}
+	case twitch.SubConduitShardDisabled:
+		var event twitch.EventConduitShardDisabled
+		if b.decodeEvent(subType, data, &event) {
+			b.onConduitShardDisabled(event)
+		}
+	default:
+		b.logger.Debug("unhandled eventsub notification", "type", subType)
+	}
+}
+
+func (b *Bot) decodeEvent(subType twitch.EventSubscription, data []byte, event any) bool {
+	if err := json.Unmarshal(data, event); err != nil {
+		b.logger.Error("failed to decode eventsub event", "type", subType, "err", err)
+		return false
+	}
+	return true
+}
+
+// onConduitShardDisabled reconnects the shard Twitch disabled. The
+// notification can arrive on any shard, so look up the one it names.
+func (b *Bot) onConduitShardDisabled(event twitch.EventConduitShardDisabled) {
+	if event.ConduitId != b.conduitID {
+		return
+	}
+	b.logger.Warn("conduit shard disabled, reconnecting",
+		"conduit_id", event.ConduitId,
+		"shard_id", event.ShardId,
+		"status", event.Status,
+	)
+	disabled := b.findShard(event.ShardId)
+	if disabled == nil {
+		b.logger.Warn("conduit shard disabled for unknown shard", "shard_id", event.ShardId)
+		return
+	}
+	disabled.requestReconnect(
+		event.Transport.SessionId,
+		fmt.Errorf("%w: conduit shard disabled (%s)", errReconnectRequested, event.Status),
+	)
+}
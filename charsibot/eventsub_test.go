@@ -0,0 +1,139 @@
+package charsibot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+)
+
+func eventSubMessage(messageType, payload string) string {
+	return fmt.Sprintf(`{"metadata":{"message_id":"m-%s","message_type":%q},"payload":%s}`,
+		messageType, messageType, payload)
+}
+
+func welcomeMessage(sessionID string) string {
+	return eventSubMessage("session_welcome",
+		fmt.Sprintf(`{"session":{"id":%q,"status":"connected","keepalive_timeout_seconds":10}}`, sessionID))
+}
+
+// fakeEventSub serves two sessions: the first asks the client to reconnect to
+// the second, which then delivers a redemption notification.
+type fakeEventSub struct {
+	srv       *httptest.Server
+	oldClosed chan struct{}
+}
+
+func newFakeEventSub(t *testing.T) *fakeEventSub {
+	t.Helper()
+	f := &fakeEventSub{oldClosed: make(chan struct{})}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Errorf("accept: %v", err)
+			return
+		}
+		defer conn.CloseNow()
+		ctx := r.Context()
+
+		if r.URL.Path == "/reconnect" {
+			_ = conn.Write(ctx, websocket.MessageText, []byte(welcomeMessage("session-2")))
+			// Only deliver events once the old session has been released.
+			<-f.oldClosed
+			_ = conn.Write(ctx, websocket.MessageText, []byte(eventSubMessage("notification", `{
+				"subscription":{"type":"channel.channel_points_custom_reward_redemption.add","version":"1"},
+				"event":{"id":"r-1","user_name":"Viewer","reward":{"id":"reward-1","title":"Test Reward"}}
+			}`)))
+			_, _, _ = conn.Read(ctx)
+			return
+		}
+
+		_ = conn.Write(ctx, websocket.MessageText, []byte(welcomeMessage("session-1")))
+		reconnectURL := "ws" + strings.TrimPrefix(f.srv.URL, "http") + "/reconnect"
+		_ = conn.Write(ctx, websocket.MessageText, []byte(eventSubMessage("session_reconnect",
+			fmt.Sprintf(`{"session":{"id":"session-1","status":"reconnecting","reconnect_url":%q}}`, reconnectURL))))
+		_, _, err = conn.Read(ctx)
+		if websocket.CloseStatus(err) == websocket.StatusNormalClosure {
+			close(f.oldClosed)
+		}
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func TestServeShardHandsOffReconnectBeforeClosingOldSession(t *testing.T) {
+	eventSub := newFakeEventSub(t)
+
+	var mu sync.Mutex
+	var shardSessions []string
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		var req updateShardsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		mu.Lock()
+		shardSessions = append(shardSessions, req.Shards[0].ID+"/"+req.Shards[0].Transport.SessionID)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	})
+
+	redeemed := make(chan string, 1)
+	b := &Bot{
+		config:        Config{ChannelUserID: "channel-1"},
+		logger:        slog.New(slog.DiscardHandler),
+		twitchAPI:     api,
+		conduitID:     "conduit-1",
+		subscriptions: newSubscriptionReconciler(nil, slog.New(slog.DiscardHandler)),
+		rewardRegistry: newTestRewardRegistry(t, map[string]catalog.Reward{
+			"reward-1": {Key: "test-reward", Title: "Test Reward"},
+		}),
+		redemptions: map[string]RedemptionFunc{
+			"test-reward": func(_ context.Context, _ *Bot, event twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
+				redeemed <- event.UserName
+				return nil
+			},
+		},
+	}
+	shard := newEventSubShards(1)[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.serveShard(ctx, shard, "ws"+strings.TrimPrefix(eventSub.srv.URL, "http")+"/ws")
+		done <- err
+	}()
+
+	select {
+	case name := <-redeemed:
+		if name != "Viewer" {
+			t.Fatalf("redeemed by %q, want Viewer", name)
+		}
+	case err := <-done:
+		t.Fatalf("serveShard returned early: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a notification on the new session")
+	}
+
+	mu.Lock()
+	got := strings.Join(shardSessions, ",")
+	mu.Unlock()
+	if got != "0/session-1,0/session-2" {
+		t.Fatalf("shard updates = %s, want 0/session-1,0/session-2", got)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("serveShard() = %v", err)
+	}
+}
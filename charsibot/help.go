@@ -0,0 +1,78 @@
+package charsibot
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+)
+
+// helpCommands returns the commands that describe the other commands. Both
+// only mention commands the caller is allowed to run.
+func helpCommands() map[string]Command {
+	return map[string]Command{
+		"commands": {
+			Description:  "List the commands you can use.",
+			UserCooldown: 30 * time.Second,
+			OnCooldown:   CooldownReply,
+			Execute: func(_ context.Context, b *Bot, event twitch.EventChannelChatMessage, _ Args) {
+				prefix := b.commandPrefix()
+				names := b.allowedCommands(event)
+				for i, name := range names {
+					names[i] = prefix + name
+				}
+				// The chat queue splits the list across messages when it is
+				// too long, preferring to cut after a comma.
+				b.replyWith(event, catalog.MessageCommandList, catalog.CommandListData{
+					Commands: names,
+					Help:     prefix + "help",
+				})
+			},
+		},
+		"help": {
+			Description:  "Explain what a command does and how to use it.",
+			Args:         []Arg{{Name: "command"}},
+			UserCooldown: 5 * time.Second,
+			Execute: func(_ context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				prefix := b.commandPrefix()
+				name := strings.ToLower(args.Word("command"))
+				if prefixed, ok := b.commandName(name); ok {
+					name = prefixed
+				}
+				target, command, ok := b.lookupCommand(name)
+				if !ok || !b.allowed(command.Permission, event) {
+					b.replyWith(event, catalog.MessageUnknownCommand, catalog.UnknownCommandData{
+						Command:  prefix + name,
+						Commands: prefix + "commands",
+					})
+					return
+				}
+				help := command.Usage(prefix, target)
+				if command.Description != "" {
+					help += " - " + command.Description
+				}
+				if len(command.Aliases) > 0 {
+					help += " Also " + prefix + strings.Join(command.Aliases, ", "+prefix) + "."
+				}
+				b.reply(event, help)
+			},
+		},
+	}
+}
+
+// allowedCommands returns the names of the commands the chatter may run,
+// sorted.
+func (b *Bot) allowedCommands(event twitch.EventChannelChatMessage) []string {
+	names := make([]string, 0, len(b.commands))
+	for name, command := range b.commands {
+		if b.allowed(command.Permission, event) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package charsibot
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lukeramljak/charsibot/blindbox"
+)
+
+func TestHelpCommandsOnlyShowAllowedCommands(t *testing.T) {
+	b := createTestBot(t)
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.commands = Commands([]blindbox.SeriesConfig{{Series: "coobubu", Name: "Coobubu"}})
+	message := func(text string, badges ...string) {
+		event := chatFrom("u1", badges...)
+		event.MessageId = "m1"
+		event.Message.Text = text
+		b.processCommand(event)
+	}
+
+	message("!commands")
+	message("!commands", "moderator")
+	if len(b.chat.replies) != 2 {
+		t.Fatalf("replies = %+v, want two command lists", b.chat.replies)
+	}
+	viewer, mod := b.chat.replies[0].Message, b.chat.replies[1].Message
+	if !strings.Contains(viewer, "!coobubu") || strings.Contains(viewer, "!setstat") {
+		t.Fatalf("viewer commands = %q", viewer)
+	}
+	if !strings.Contains(mod, "!setstat") {
+		t.Fatalf("moderator commands = %q", mod)
+	}
+
+	message("!help !stats")
+	message("!help setstat")
+	if got, want := b.chat.replies[2].Message, "!stats [@user] - Show your stats, or another chatter's."; got != want {
+		t.Fatalf("help = %q, want %q", got, want)
+	}
+	if got := b.chat.replies[3].Message; !strings.Contains(got, "no !setstat command") {
+		t.Fatalf("help for a mod command = %q, want it hidden from viewers", got)
+	}
+}
@@ -0,0 +1,27 @@
+package charsibot
+
+import (
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+// say renders a catalog message and sends it to chat. Templates are checked
+// when the catalog loads, so a message that fails to render is only logged.
+func (b *Bot) say(key string, data any) {
+	message, err := b.messages.Render(key, data)
+	if err != nil {
+		b.logger.Error("failed to render message", "err", err, "key", key)
+		return
+	}
+	b.SendMessage(SendMessageParams{Message: message})
+}
+
+// replyWith renders a catalog message as a reply to the chatter who sent
+// event.
+func (b *Bot) replyWith(event twitch.EventChannelChatMessage, key string, data any) {
+	message, err := b.messages.Render(key, data)
+	if err != nil {
+		b.logger.Error("failed to render message", "err", err, "key", key)
+		return
+	}
+	b.reply(event, message)
+}
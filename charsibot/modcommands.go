@@ -0,0 +1,170 @@
+package charsibot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// modCommands returns the moderator-only commands. They call the same
+// services as the admin API so mods can fix viewer state from chat.
+func modCommands(seriesConfigs []blindbox.SeriesConfig) map[string]Command {
+	return map[string]Command{
+		"givebox": {
+			Description: "Open a random blind box for a chatter.",
+			Permission:  PermissionModerator,
+			Args:        []Arg{{Name: "user", Kind: ArgUser}, {Name: "series"}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				cfg, ok := b.modSeries(event, seriesConfigs, args.Word("series"))
+				if !ok {
+					return
+				}
+				user, _ := args.User("user")
+				if err := redeemBlindBox(ctx, b, user.ID, user.DisplayName, cfg); err != nil {
+					b.logger.Error("failed to give blind box", "err", err, "user", user.Login, "series", cfg.Series)
+					b.replyWith(event, catalog.MessageGiveBoxFailed, struct{}{})
+					return
+				}
+				b.logger.Info("moderator gave blind box",
+					"moderator", event.ChatterUserName, "user", user.Login, "series", cfg.Series)
+			},
+		},
+		"giveplushie": {
+			Description: "Give a chatter a specific plushie.",
+			Permission:  PermissionModerator,
+			Args:        []Arg{{Name: "user", Kind: ArgUser}, {Name: "series"}, {Name: "plushie"}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				cfg, ok := b.modSeries(event, seriesConfigs, args.Word("series"))
+				if !ok {
+					return
+				}
+				plushie, ok := findPlushie(cfg, args.Word("plushie"))
+				if !ok {
+					b.replyWith(event, catalog.MessageUnknownPlushie, catalog.PlushieData{Key: args.Word("plushie"), Series: cfg})
+					return
+				}
+				user, _ := args.User("user")
+				isNew, collection, err := b.blindboxService.AddPlushieToCollection(
+					ctx, user.ID, user.DisplayName, cfg.Series, plushie.Key,
+				)
+				if err != nil {
+					b.logger.Error("failed to give plushie", "err", err, "user", user.Login, "plushie", plushie.Key)
+					b.replyWith(event, catalog.MessageGivePlushieFailed, struct{}{})
+					return
+				}
+				b.broadcast(server.OverlayEvent{
+					Type: server.EventTypeBlindBoxRedemption,
+					Data: blindbox.BlindBoxRedemptionData{
+						Username:   user.DisplayName,
+						Plushie:    plushie,
+						IsNew:      isNew,
+						Collection: collection,
+						Config:     cfg,
+					},
+				})
+				b.logger.Info("moderator gave plushie",
+					"moderator", event.ChatterUserName, "user", user.Login, "series", cfg.Series, "plushie", plushie.Key)
+			},
+		},
+		"setstat": {
+			Description: "Set one of a chatter's stats.",
+			Permission:  PermissionModerator,
+			Args:        []Arg{{Name: "user", Kind: ArgUser}, {Name: "stat"}, {Name: "value", Kind: ArgInt}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				definition, ok := findStat(b.statsService.Definitions(), args.Word("stat"))
+				if !ok {
+					b.replyWith(event, catalog.MessageUnknownStat, catalog.UnknownStatData{Stat: args.Word("stat")})
+					return
+				}
+				user, _ := args.User("user")
+				value := args.Int("value")
+				if _, err := b.statsService.GetOrCreateStats(ctx, user.ID, user.DisplayName); err != nil {
+					b.logger.Error("failed to initialize stats", "err", err, "user", user.Login)
+					b.replyWith(event, catalog.MessageSetStatFailed, struct{}{})
+					return
+				}
+				if err := b.statsService.SetStatValue(ctx, user.ID, definition.Name, value); err != nil {
+					b.logger.Error("failed to set stat", "err", err, "user", user.Login, "stat", definition.Name)
+					b.replyWith(event, catalog.MessageSetStatFailed, struct{}{})
+					return
+				}
+				b.logger.Info("moderator set stat",
+					"moderator", event.ChatterUserName, "user", user.Login, "stat", definition.Name, "value", value)
+				values, err := b.statsService.GetUserStats(ctx, user.ID)
+				if err != nil {
+					b.logger.Error("failed to get stats", "err", err, "user", user.Login)
+					return
+				}
+				b.replyWith(event, catalog.MessageStats, catalog.StatsData{User: user.DisplayName, Stats: values})
+			},
+		},
+		"resetcollection": {
+			Description: "Empty a chatter's collection for a series.",
+			Permission:  PermissionModerator,
+			Args:        []Arg{{Name: "user", Kind: ArgUser}, {Name: "series"}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				cfg, ok := b.modSeries(event, seriesConfigs, args.Word("series"))
+				if !ok {
+					return
+				}
+				user, _ := args.User("user")
+				if err := b.blindboxService.ResetCollection(ctx, user.ID, cfg.Series); err != nil {
+					b.logger.Error("failed to reset collection", "err", err, "user", user.Login, "series", cfg.Series)
+					b.replyWith(event, catalog.MessageResetCollectionFailed, struct{}{})
+					return
+				}
+				b.logger.Info("moderator reset collection",
+					"moderator", event.ChatterUserName, "user", user.Login, "series", cfg.Series)
+				b.replyWith(event, catalog.MessageCollectionReset, catalog.CollectionData{User: user.DisplayName, Series: cfg})
+			},
+		},
+	}
+}
+
+// reply answers the chatter who sent event.
+func (b *Bot) reply(event twitch.EventChannelChatMessage, message string) {
+	b.SendMessage(SendMessageParams{Message: message, ReplyParentMessageID: event.MessageId})
+}
+
+// modSeries finds a series by name, telling the mod which exist when it
+// cannot.
+func (b *Bot) modSeries(
+	event twitch.EventChannelChatMessage,
+	seriesConfigs []blindbox.SeriesConfig,
+	name string,
+) (blindbox.SeriesConfig, bool) {
+	names := make([]string, len(seriesConfigs))
+	for i, cfg := range seriesConfigs {
+		if strings.EqualFold(cfg.Series, name) {
+			return cfg, true
+		}
+		names[i] = cfg.Series
+	}
+	b.replyWith(event, catalog.MessageUnknownSeries, catalog.UnknownSeriesData{Series: name, Choices: names})
+	return blindbox.SeriesConfig{}, false
+}
+
+func findPlushie(cfg blindbox.SeriesConfig, key string) (blindbox.Plushie, bool) {
+	for _, plushie := range cfg.Plushies {
+		if strings.EqualFold(plushie.Key, key) {
+			return plushie, true
+		}
+	}
+	return blindbox.Plushie{}, false
+}
+
+// findStat matches a stat by name or short name, ignoring case.
+func findStat(definitions []stats.Definition, name string) (stats.Definition, bool) {
+	for _, definition := range definitions {
+		if strings.EqualFold(definition.Name, name) || strings.EqualFold(definition.ShortName, name) {
+			return definition, true
+		}
+	}
+	return stats.Definition{}, false
+}
@@ -0,0 +1,126 @@
+package charsibot
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// createTestBotForModCommands returns a bot with real services and a fake
+// Helix that knows the login "friend".
+func createTestBotForModCommands(t *testing.T) (*Bot, chan server.OverlayEvent) {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	appCatalog := testCatalog(t)
+	statsService, err := stats.NewService(queries, appCatalog.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindboxService, err := blindbox.NewService(queries, appCatalog.Series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users" || r.URL.Query().Get("login") != "friend" {
+			_, _ = w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"friend-1","login":"friend","display_name":"Friend"}]}`))
+	})
+
+	broadcast, events := newBroadcast()
+	b := createTestBot(t)
+	b.statsService = statsService
+	b.blindboxService = blindboxService
+	b.twitchAPI = api
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.commands = Commands(appCatalog.Series)
+	b.broadcast = broadcast
+	return b, events
+}
+
+func modMessage(text string) twitch.EventChannelChatMessage {
+	event := chatFrom("mod-1", "moderator")
+	event.ChatterUserName = "mod"
+	event.MessageId = "m1"
+	event.Message.Text = text
+	return event
+}
+
+func TestModCommandsGivePlushie(t *testing.T) {
+	b, events := createTestBotForModCommands(t)
+
+	b.processCommand(modMessage("!giveplushie @Friend coobubu CUTEY"))
+
+	collection, err := b.blindboxService.GetCollection(context.Background(), "friend-1", "coobubu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(collection, "cutey") {
+		t.Fatalf("collection = %v, want cutey", collection)
+	}
+	event := <-events
+	if data, ok := event.Data.(blindbox.BlindBoxRedemptionData); !ok || data.Username != "Friend" || !data.IsNew {
+		t.Fatalf("overlay event = %+v", event)
+	}
+}
+
+func TestModCommandsSetStatAndResetCollection(t *testing.T) {
+	b, _ := createTestBotForModCommands(t)
+	ctx := context.Background()
+
+	b.processCommand(modMessage("!setstat @friend LUCK 7"))
+	values, err := b.statsService.GetUserStats(ctx, "friend-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := slices.IndexFunc(values, func(stat stats.UserStat) bool { return stat.Name == "luck" })
+	if idx < 0 || values[idx].Value != 7 {
+		t.Fatalf("stats = %+v, want luck 7", values)
+	}
+
+	if _, _, err := b.blindboxService.AddPlushieToCollection(ctx, "friend-1", "Friend", "coobubu", "cutey"); err != nil {
+		t.Fatal(err)
+	}
+	b.processCommand(modMessage("!resetcollection @friend coobubu"))
+	collection, err := b.blindboxService.GetCollection(ctx, "friend-1", "coobubu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection) != 0 {
+		t.Fatalf("collection = %v, want it reset", collection)
+	}
+}
+
+func TestModCommandsReplyToBadArguments(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "!givebox @friend", want: "Usage: !givebox"},
+		{text: "!givebox @friend nope", want: "Unknown series"},
+		{text: "!giveplushie @friend coobubu nope", want: "Unknown coobubu plushie"},
+		{text: "!setstat @friend luck lots", want: "not a whole number"},
+		{text: "!resetcollection @stranger coobubu", want: "Couldn't find a user called @stranger"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			b, _ := createTestBotForModCommands(t)
+			b.processCommand(modMessage(tt.text))
+			if len(b.chat.replies) != 1 || !strings.Contains(b.chat.replies[0].Message, tt.want) {
+				t.Fatalf("replies = %+v, want one containing %q", b.chat.replies, tt.want)
+			}
+		})
+	}
+}
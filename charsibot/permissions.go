@@ -0,0 +1,85 @@
+package charsibot
+
+import (
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+// Permission is the lowest chat role allowed to use a command or trigger.
+// Each tier includes the ones below it.
+type Permission int
+
+const (
+	PermissionEveryone Permission = iota
+	PermissionSubscriber
+	PermissionVIP
+	PermissionModerator
+	PermissionBroadcaster
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermissionEveryone:
+		return "everyone"
+	case PermissionSubscriber:
+		return "subscriber"
+	case PermissionVIP:
+		return "vip"
+	case PermissionModerator:
+		return "moderator"
+	case PermissionBroadcaster:
+		return "broadcaster"
+	default:
+		return "unknown"
+	}
+}
+
+// permissionNamed returns the tier whose String is name, or everyone's when
+// name is empty. A name that matches no tier gets the broadcaster's, so a typo
+// never opens something up.
+func permissionNamed(name string) Permission {
+	if name == "" {
+		return PermissionEveryone
+	}
+	for p := PermissionEveryone; p <= PermissionBroadcaster; p++ {
+		if p.String() == name {
+			return p
+		}
+	}
+	return PermissionBroadcaster
+}
+
+// badgePermission returns the tier a chat badge set ID grants.
+func badgePermission(setID string) Permission {
+	switch setID {
+	case "broadcaster":
+		return PermissionBroadcaster
+	case "lead_moderator", "moderator":
+		return PermissionModerator
+	case "vip":
+		return PermissionVIP
+	case "subscriber", "founder":
+		return PermissionSubscriber
+	default:
+		return PermissionEveryone
+	}
+}
+
+// chatterPermission returns the highest tier the chatter's badges grant in
+// this channel. The broadcaster is recognised by ID as well, since badges can
+// be hidden.
+func (b *Bot) chatterPermission(event twitch.EventChannelChatMessage) Permission {
+	if event.ChatterUserId != "" && event.ChatterUserId == b.config.ChannelUserID {
+		return PermissionBroadcaster
+	}
+	permission := PermissionEveryone
+	for _, badge := range event.Badges {
+		permission = max(permission, badgePermission(badge.SetId))
+	}
+	return permission
+}
+
+// allowed reports whether the chatter may use something that requires the
+// given permission.
+func (b *Bot) allowed(required Permission, event twitch.EventChannelChatMessage) bool {
+	return required == PermissionEveryone || b.chatterPermission(event) >= required
+}
@@ -0,0 +1,79 @@
+package charsibot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+func chatFrom(userID string, badges ...string) twitch.EventChannelChatMessage {
+	event := twitch.EventChannelChatMessage{
+		Chatter: twitch.Chatter{ChatterUserId: userID},
+		Message: twitch.ChatMessage{Text: "!test"},
+	}
+	for _, badge := range badges {
+		event.Badges = append(event.Badges, twitch.ChatMessageUserBadge{SetId: badge})
+	}
+	return event
+}
+
+func TestChatterPermission(t *testing.T) {
+	b := createTestBot(t)
+	tests := []struct {
+		name  string
+		event twitch.EventChannelChatMessage
+		want  Permission
+	}{
+		{name: "no badges", event: chatFrom("u1"), want: PermissionEveryone},
+		{name: "unrelated badge", event: chatFrom("u1", "premium"), want: PermissionEveryone},
+		{name: "founder counts as subscriber", event: chatFrom("u1", "founder"), want: PermissionSubscriber},
+		{name: "highest badge wins", event: chatFrom("u1", "subscriber", "moderator", "vip"), want: PermissionModerator},
+		{name: "broadcaster by badge", event: chatFrom("u1", "broadcaster"), want: PermissionBroadcaster},
+		{name: "broadcaster by ID", event: chatFrom(b.config.ChannelUserID), want: PermissionBroadcaster},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.chatterPermission(tt.event); got != tt.want {
+				t.Fatalf("chatterPermission = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessCommandEnforcesPermission(t *testing.T) {
+	runs := 0
+	b := createTestBot(t)
+	b.commands = map[string]Command{
+		"test": {
+			Permission: PermissionModerator,
+			Execute:    func(context.Context, *Bot, twitch.EventChannelChatMessage, Args) { runs++ },
+		},
+	}
+
+	b.processCommand(chatFrom("u1", "subscriber", "vip"))
+	if runs != 0 {
+		t.Fatal("a VIP should not be able to run a moderator command")
+	}
+	b.processCommand(chatFrom("u1", "moderator"))
+	b.processCommand(chatFrom(b.config.ChannelUserID))
+	if runs != 2 {
+		t.Fatalf("runs = %d, want moderators and the broadcaster to run the command", runs)
+	}
+}
+
+func TestProcessTriggersEnforcesPermission(t *testing.T) {
+	runs := 0
+	b := createTestBot(t)
+	b.triggers = []Trigger{{
+		Permission:    PermissionSubscriber,
+		ShouldTrigger: func(twitch.EventChannelChatMessage) bool { return true },
+		Execute:       func(context.Context, *Bot, twitch.EventChannelChatMessage) { runs++ },
+	}}
+
+	b.processTriggers(chatFrom("u1"))
+	b.processTriggers(chatFrom("u1", "subscriber"))
+	if runs != 1 {
+		t.Fatalf("runs = %d, want only the subscriber to fire the trigger", runs)
+	}
+}
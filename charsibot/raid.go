@@ -0,0 +1,458 @@
+package charsibot
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/raids"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+// raidRoundDelay is the pause between rounds of a raid, so chat and the
+// overlay can follow the fight.
+const raidRoundDelay = 5 * time.Second
+
+// The stats a raid fighter uses: the better of STR and INT adds to damage,
+// LUCK makes critical hits likelier and DEX dodges the boss's attacks.
+const (
+	raidStatStrength     = "strength"
+	raidStatIntelligence = "intelligence"
+	raidStatLuck         = "luck"
+	raidStatDexterity    = "dexterity"
+)
+
+// Overlay phases of a raid.
+const (
+	raidPhaseJoining = "joining"
+	raidPhaseRound   = "round"
+	raidPhaseVictory = "victory"
+	raidPhaseDefeat  = "defeat"
+)
+
+// raidState is where the raid state machine is: idle, then joining while a
+// boss waits for chatters, then fighting until the boss falls, the fighters
+// all fall or the boss escapes, and back to idle.
+type raidState int
+
+const (
+	raidIdle raidState = iota
+	raidJoining
+	raidFighting
+)
+
+// raid is the boss raid in progress, if any.
+type raid struct {
+	mu        sync.Mutex
+	state     raidState
+	boss      catalog.Boss
+	startedBy string
+	fighters  []*raidFighter
+}
+
+type raidFighter struct {
+	userID     string
+	username   string
+	stats      []stats.UserStat
+	damage     int64
+	knockedOut bool
+}
+
+// raidRound is what happened in one round of a raid.
+type raidRound struct {
+	number int
+	hits   []raidHit
+	// knockedOut holds the indexes of the fighters the boss knocked out.
+	knockedOut []int
+	hpLeft     int64
+}
+
+type raidHit struct {
+	fighter  int
+	damage   int64
+	critical bool
+}
+
+// raidCommands returns !boss, which lets a moderator summon a boss, and
+// !join, which signs chatters up to fight it.
+func raidCommands() map[string]Command {
+	return map[string]Command{
+		"boss": {
+			Description: "Summon a boss for chatters to raid together.",
+			Permission:  PermissionModerator,
+			Args:        []Arg{{Name: "boss", Optional: true}},
+			Execute: func(_ context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				b.startRaid(event, args.Word("boss"))
+			},
+		},
+		"join": {
+			Description: "Join the boss raid that is gathering.",
+			Execute: func(_ context.Context, b *Bot, event twitch.EventChannelChatMessage, _ Args) {
+				b.joinRaid(event)
+			},
+		},
+	}
+}
+
+// startRaid summons the named boss, or a random one, and opens the raid for
+// chatters to join until the boss's join window closes. The raid is dropped
+// if the bot shuts down first.
+func (b *Bot) startRaid(event twitch.EventChannelChatMessage, key string) {
+	if len(b.bosses) == 0 {
+		b.replyWith(event, catalog.MessageBossNone, struct{}{})
+		return
+	}
+	boss := b.bosses[rand.IntN(len(b.bosses))]
+	if key != "" {
+		keys := make([]string, len(b.bosses))
+		found := false
+		for i, candidate := range b.bosses {
+			if strings.EqualFold(candidate.Key, key) {
+				boss, found = candidate, true
+			}
+			keys[i] = candidate.Key
+		}
+		if !found {
+			b.replyWith(event, catalog.MessageBossUnknown, catalog.UnknownBossData{Boss: key, Choices: keys})
+			return
+		}
+	}
+
+	b.raid.mu.Lock()
+	if b.raid.state != raidIdle {
+		b.raid.mu.Unlock()
+		b.replyWith(event, catalog.MessageBossBusy, struct{}{})
+		return
+	}
+	b.raid.state = raidJoining
+	b.raid.boss = boss
+	b.raid.startedBy = event.ChatterUserName
+	b.raid.fighters = nil
+	b.raid.mu.Unlock()
+
+	b.logger.Info("boss raid started", "boss", boss.Key, "moderator", event.ChatterUserName)
+	b.wg.Go(func() {
+		ctx := b.runContext()
+		if err := sleepContext(ctx, boss.JoinWindow); err != nil {
+			return
+		}
+		b.fightRaid(ctx)
+	})
+	b.say(catalog.MessageBossAppeared, catalog.BossData{
+		Boss:    boss,
+		Join:    b.commandPrefix() + "join",
+		Seconds: int(boss.JoinWindow / time.Second),
+	})
+	hp := raidMaxHP(boss, 0)
+	b.broadcast(raidEvent(raidPhaseJoining, boss, nil, hp, hp))
+}
+
+// joinRaid signs the chatter up for the raid that is gathering. Chatters who
+// already joined, or who join when no raid is gathering, are ignored.
+func (b *Bot) joinRaid(event twitch.EventChannelChatMessage) {
+	b.raid.mu.Lock()
+	if b.raid.state != raidJoining {
+		b.raid.mu.Unlock()
+		return
+	}
+	for _, fighter := range b.raid.fighters {
+		if fighter.userID == event.ChatterUserId {
+			b.raid.mu.Unlock()
+			return
+		}
+	}
+	b.raid.fighters = append(b.raid.fighters, &raidFighter{
+		userID:   event.ChatterUserId,
+		username: event.ChatterUserName,
+	})
+	boss, fighters := b.raid.boss, b.raid.fighters
+	b.raid.mu.Unlock()
+
+	hp := raidMaxHP(boss, len(fighters))
+	b.broadcast(raidEvent(raidPhaseJoining, boss, fighters, hp, hp))
+}
+
+// fightRaid closes the raid to new fighters, fights the boss and rewards
+// the survivors if it falls. It does nothing unless a raid is gathering, and
+// stops between rounds once ctx is done.
+func (b *Bot) fightRaid(ctx context.Context) {
+	b.raid.mu.Lock()
+	if b.raid.state != raidJoining {
+		b.raid.mu.Unlock()
+		return
+	}
+	b.raid.state = raidFighting
+	boss, startedBy, fighters := b.raid.boss, b.raid.startedBy, b.raid.fighters
+	b.raid.mu.Unlock()
+	defer func() {
+		b.raid.mu.Lock()
+		b.raid.state = raidIdle
+		b.raid.fighters = nil
+		b.raid.mu.Unlock()
+	}()
+
+	result := raids.Result{Boss: boss.Key, StartedBy: startedBy, MaxHP: raidMaxHP(boss, len(fighters))}
+	if len(fighters) == 0 {
+		result.HPLeft = result.MaxHP
+		b.recordRaid(ctx, result)
+		b.say(catalog.MessageBossUnopposed, catalog.BossData{Boss: boss})
+		b.broadcast(raidEvent(raidPhaseDefeat, boss, nil, result.MaxHP, result.MaxHP))
+		return
+	}
+
+	b.loadRaidStats(ctx, fighters)
+	rounds := fightBoss(boss, fighters, rollDie)
+	down := make([]bool, len(fighters))
+	for i, round := range rounds {
+		if i > 0 {
+			if err := sleepContext(ctx, b.raidRoundDelay); err != nil {
+				b.logger.Info("boss raid stopped", "boss", boss.Key, "round", round.number, "err", err)
+				return
+			}
+		}
+		b.announceRaidRound(boss, fighters, round, result.MaxHP, down)
+	}
+	result.Rounds = len(rounds)
+	result.HPLeft = rounds[len(rounds)-1].hpLeft
+	result.Victory = result.HPLeft == 0
+
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+	data := catalog.BossResultData{Boss: boss, Rounds: result.Rounds}
+	for _, fighter := range fighters {
+		outcome := raids.Fighter{
+			UserID:   fighter.userID,
+			Username: fighter.username,
+			Damage:   fighter.damage,
+			Survived: !fighter.knockedOut,
+		}
+		if !fighter.knockedOut {
+			data.Survivors = append(data.Survivors, fighter.username)
+			if result.Victory {
+				reward, err := b.raidReward(ctx, boss.Reward, fighter)
+				if err != nil {
+					b.logger.Error("failed to reward raid survivor", "err", err, "user", fighter.username)
+				} else {
+					data.Rewards = append(data.Rewards, reward)
+					outcome.Reward = describeRaidReward(reward)
+				}
+			}
+		}
+		result.Fighters = append(result.Fighters, outcome)
+	}
+	b.recordRaid(ctx, result)
+
+	key, phase := catalog.MessageBossDefeat, raidPhaseDefeat
+	if result.Victory {
+		key, phase = catalog.MessageBossVictory, raidPhaseVictory
+	}
+	b.logger.Info("boss raid ended", "boss", boss.Key, "victory", result.Victory, "rounds", result.Rounds)
+	b.say(key, data)
+	b.broadcast(raidEvent(phase, boss, fighters, result.HPLeft, result.MaxHP))
+}
+
+// loadRaidStats fetches each fighter's stats. A fighter whose stats cannot
+// be loaded fights without modifiers rather than being turned away.
+func (b *Bot) loadRaidStats(ctx context.Context, fighters []*raidFighter) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+	for _, fighter := range fighters {
+		userStats, err := b.statsService.GetOrCreateStats(ctx, fighter.userID, fighter.username)
+		if err != nil {
+			b.logger.Error("failed to get stats", "err", err, "user", fighter.username)
+			continue
+		}
+		fighter.stats = userStats
+	}
+}
+
+// announceRaidRound tells chat and the overlay what happened in a round.
+// down tracks who has been knocked out so far, and is updated.
+func (b *Bot) announceRaidRound(
+	boss catalog.Boss,
+	fighters []*raidFighter,
+	round raidRound,
+	maxHP int64,
+	down []bool,
+) {
+	data := catalog.BossRoundData{Boss: boss, Round: round.number, HP: round.hpLeft, MaxHP: maxHP}
+	for _, i := range round.knockedOut {
+		down[i] = true
+		data.KnockedOut = append(data.KnockedOut, fighters[i].username)
+	}
+	overlay := server.BossRaidData{
+		Phase:    raidPhaseRound,
+		Boss:     boss.Key,
+		Name:     boss.Name,
+		HP:       round.hpLeft,
+		MaxHP:    maxHP,
+		Round:    round.number,
+		Fighters: make([]server.RaidFighter, len(fighters)),
+	}
+	for i, fighter := range fighters {
+		overlay.Fighters[i] = server.RaidFighter{Username: fighter.username, KnockedOut: down[i]}
+	}
+	for _, hit := range round.hits {
+		data.Damage += hit.damage
+		overlay.Fighters[hit.fighter].Damage = hit.damage
+		overlay.Fighters[hit.fighter].Critical = hit.critical
+		if hit.critical {
+			data.Criticals = append(data.Criticals, fighters[hit.fighter].username)
+		}
+	}
+	b.say(catalog.MessageBossRound, data)
+	b.broadcast(server.OverlayEvent{Type: server.EventTypeBossRaid, Data: overlay})
+}
+
+// raidReward gives a survivor of a defeated boss a free blind box from a
+// random series, or else some of a random stat.
+func (b *Bot) raidReward(
+	ctx context.Context,
+	reward catalog.BossReward,
+	fighter *raidFighter,
+) (catalog.BossRewardData, error) {
+	if len(b.series) > 0 && rand.IntN(100) < reward.BlindBoxChance {
+		cfg := b.series[rand.IntN(len(b.series))]
+		if err := redeemBlindBox(ctx, b, fighter.userID, fighter.username, cfg); err != nil {
+			return catalog.BossRewardData{}, err
+		}
+		return catalog.BossRewardData{User: fighter.username, BlindBox: cfg.RedemptionTitle}, nil
+	}
+	definition, err := b.statsService.GetRandomStatDefinition(ctx)
+	if err != nil {
+		return catalog.BossRewardData{}, fmt.Errorf("get random stat definition: %w", err)
+	}
+	delta := reward.StatMin + rand.Int64N(reward.StatMax-reward.StatMin+1)
+	if err := b.statsService.ModifyStatValue(ctx, fighter.userID, definition.Name, delta); err != nil {
+		return catalog.BossRewardData{}, fmt.Errorf("modify stat: %w", err)
+	}
+	return catalog.BossRewardData{User: fighter.username, Stat: definition, Delta: delta}, nil
+}
+
+// describeRaidReward is how a reward is kept in the raid history, like
+// "+1 STR".
+func describeRaidReward(reward catalog.BossRewardData) string {
+	if reward.BlindBox != "" {
+		return reward.BlindBox
+	}
+	return fmt.Sprintf("%+d %s", reward.Delta, reward.Stat.ShortName)
+}
+
+func (b *Bot) recordRaid(ctx context.Context, result raids.Result) {
+	if b.raids == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+	if err := b.raids.Record(ctx, result); err != nil {
+		b.logger.Error("failed to record raid", "err", err, "boss", result.Boss)
+	}
+}
+
+// raidEvent is an overlay event showing the whole raid, with each fighter's
+// total damage so far.
+func raidEvent(phase string, boss catalog.Boss, fighters []*raidFighter, hp, maxHP int64) server.OverlayEvent {
+	data := server.BossRaidData{
+		Phase:    phase,
+		Boss:     boss.Key,
+		Name:     boss.Name,
+		HP:       hp,
+		MaxHP:    maxHP,
+		Fighters: make([]server.RaidFighter, len(fighters)),
+	}
+	for i, fighter := range fighters {
+		data.Fighters[i] = server.RaidFighter{
+			Username:   fighter.username,
+			Damage:     fighter.damage,
+			KnockedOut: fighter.knockedOut,
+		}
+	}
+	return server.OverlayEvent{Type: server.EventTypeBossRaid, Data: data}
+}
+
+// raidMaxHP is the boss's hit points against the given number of fighters.
+func raidMaxHP(boss catalog.Boss, fighters int) int64 {
+	return boss.HP + boss.HPPerFighter*int64(fighters)
+}
+
+// fightBoss fights boss round by round and returns what happened in each.
+// Every fighter still standing attacks: a natural 1 misses, a hit deals 1d6
+// plus the better of their STR and INT modifiers, and 1d20 plus LUCK of 20
+// or more is a critical hit for double damage. Then, unless it has fallen,
+// the boss strikes boss.Attacks random fighters, knocking out those who fail
+// to dodge with 1d20 plus DEX against its accuracy. Fighters' damage and
+// whether they were knocked out are updated. die rolls a single die.
+func fightBoss(boss catalog.Boss, fighters []*raidFighter, die func(sides int64) int64) []raidRound {
+	hp := raidMaxHP(boss, len(fighters))
+	var rounds []raidRound
+	for number := 1; number <= boss.Rounds && hp > 0; number++ {
+		standing := standingFighters(fighters)
+		if len(standing) == 0 {
+			break
+		}
+		round := raidRound{number: number}
+		for _, i := range standing {
+			fighter := fighters[i]
+			hit := raidHit{fighter: i}
+			if face := die(20); face > 1 {
+				power := max(raidModifier(fighter, raidStatStrength), raidModifier(fighter, raidStatIntelligence))
+				hit.damage = max(die(6)+power, 1)
+				if face+raidModifier(fighter, raidStatLuck) >= 20 {
+					hit.critical = true
+					hit.damage *= 2
+				}
+			}
+			hit.damage = min(hit.damage, hp)
+			hp -= hit.damage
+			fighter.damage += hit.damage
+			round.hits = append(round.hits, hit)
+			if hp == 0 {
+				break
+			}
+		}
+		if hp > 0 {
+			for range boss.Attacks {
+				standing = standingFighters(fighters)
+				if len(standing) == 0 {
+					break
+				}
+				target := standing[die(int64(len(standing)))-1]
+				if die(20)+raidModifier(fighters[target], raidStatDexterity) < boss.Accuracy {
+					fighters[target].knockedOut = true
+					round.knockedOut = append(round.knockedOut, target)
+				}
+			}
+		}
+		round.hpLeft = hp
+		rounds = append(rounds, round)
+	}
+	return rounds
+}
+
+// standingFighters returns the indexes of the fighters not knocked out.
+func standingFighters(fighters []*raidFighter) []int {
+	var standing []int
+	for i, fighter := range fighters {
+		if !fighter.knockedOut {
+			standing = append(standing, i)
+		}
+	}
+	return standing
+}
+
+// raidModifier is the modifier a fighter's stat adds to their rolls, which
+// is zero for a stat they do not have.
+func raidModifier(fighter *raidFighter, name string) int64 {
+	stat, ok := findUserStat(fighter.stats, name)
+	if !ok {
+		return 0
+	}
+	return statModifier(stat.Value)
+}
@@ -0,0 +1,213 @@
+package charsibot
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/raids"
+	"github.com/lukeramljak/charsibot/server"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+func TestFightBossUntilItFalls(t *testing.T) {
+	boss := catalog.Boss{HP: 10, Rounds: 3, Attacks: 1, Accuracy: 10}
+	fighters := []*raidFighter{
+		{username: "Alice", stats: []stats.UserStat{{Name: "strength", Value: 4}}},
+		{username: "Bob"},
+	}
+	// Alice rolls 10 and hits for 3+2. Bob rolls a natural 20 and crits for
+	// double 4, which the boss's last 5 HP caps.
+	rounds := fightBoss(boss, fighters, fixedDice(10, 3, 20, 4))
+
+	if len(rounds) != 1 || rounds[0].hpLeft != 0 {
+		t.Fatalf("rounds = %+v, want the boss to fall in round 1", rounds)
+	}
+	want := []raidHit{{fighter: 0, damage: 5}, {fighter: 1, damage: 5, critical: true}}
+	if !slices.Equal(rounds[0].hits, want) {
+		t.Fatalf("hits = %+v, want %+v", rounds[0].hits, want)
+	}
+	if fighters[0].damage != 5 || fighters[1].damage != 5 || fighters[0].knockedOut || fighters[1].knockedOut {
+		t.Fatalf("fighters = %+v, %+v", *fighters[0], *fighters[1])
+	}
+}
+
+func TestFightBossKnocksOutTheRaid(t *testing.T) {
+	boss := catalog.Boss{HP: 100, Rounds: 3, Attacks: 1, Accuracy: 10}
+	fighters := []*raidFighter{{username: "Alice", stats: []stats.UserStat{{Name: "dexterity", Value: 6}}}}
+	// Alice misses with a natural 1, is picked as the only target, and her
+	// dodge of 6+3 falls short of 10.
+	rounds := fightBoss(boss, fighters, fixedDice(1, 1, 6))
+
+	if len(rounds) != 1 || rounds[0].hpLeft != 100 || !slices.Equal(rounds[0].knockedOut, []int{0}) {
+		t.Fatalf("rounds = %+v, want Alice knocked out in round 1", rounds)
+	}
+	if !fighters[0].knockedOut || fighters[0].damage != 0 {
+		t.Fatalf("fighter = %+v", *fighters[0])
+	}
+}
+
+func createTestBotForRaid(t *testing.T) (*Bot, *sql.DB, chan server.OverlayEvent) {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	appCatalog := testCatalog(t)
+	statsService, err := stats.NewService(queries, appCatalog.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raidService, err := raids.NewService(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broadcast, events := newBroadcast()
+	b := createTestBot(t)
+	b.statsService = statsService
+	b.raids = raidService
+	b.broadcast = broadcast
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.commands = Commands(appCatalog.Series)
+	// The join window is closed by calling fightRaid, not by waiting for it.
+	b.bosses = []catalog.Boss{{
+		Key: "slime", Name: "the Slime", HP: 1, Rounds: 5, JoinWindow: time.Hour,
+		Reward: catalog.BossReward{StatMin: 1, StatMax: 1},
+	}}
+	return b, sqlDB, events
+}
+
+func TestRaid(t *testing.T) {
+	b, sqlDB, events := createTestBotForRaid(t)
+	ctx := context.Background()
+
+	b.processCommand(duelMessage("Alice", "!boss"))
+	if len(b.chat.messages) != 0 {
+		t.Fatalf("messages = %+v, want only moderators to summon bosses", b.chat.messages)
+	}
+	b.processCommand(modMessage("!boss slime"))
+	if len(b.chat.messages) != 1 || b.chat.messages[0].Message !=
+		"the Slime appears! Type !join in the next 3600 seconds to fight it together." {
+		t.Fatalf("messages = %+v", b.chat.messages)
+	}
+	b.processCommand(modMessage("!boss"))
+	if got := lastReply(b); got != "A boss raid is already under way." {
+		t.Fatalf("reply = %q", got)
+	}
+	for _, name := range []string{"Alice", "Alice", "Bob"} {
+		b.processCommand(duelMessage(name, "!join"))
+	}
+
+	b.fightRaid(t.Context())
+
+	last := b.chat.messages[len(b.chat.messages)-1].Message
+	if !strings.HasPrefix(last, "the Slime falls after 1 round! ") {
+		t.Fatalf("message = %q", last)
+	}
+	var final server.BossRaidData
+	for len(events) > 0 {
+		event := <-events
+		final, _ = event.Data.(server.BossRaidData)
+	}
+	if final.Phase != raidPhaseVictory || final.HP != 0 || len(final.Fighters) != 2 {
+		t.Fatalf("final overlay event = %+v", final)
+	}
+
+	for _, id := range []string{"id-Alice", "id-Bob"} {
+		userStats, err := b.statsService.GetUserStats(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every stat starts at 3, and the reward adds 1 to one of them.
+		var total int64
+		for _, stat := range userStats {
+			total += stat.Value - 3
+		}
+		if total != 1 {
+			t.Errorf("%s's stats = %+v, want one stat raised by 1", id, userStats)
+		}
+	}
+
+	var fighters, rewarded int
+	if err := sqlDB.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(NULLIF(reward, '')) FROM raid_fighters`).Scan(&fighters, &rewarded); err != nil {
+		t.Fatal(err)
+	}
+	if fighters != 2 || rewarded != 2 {
+		t.Fatalf("recorded %d fighters with %d rewards, want 2 and 2", fighters, rewarded)
+	}
+
+	// The raid is over, so a late join does nothing and a new boss can come.
+	messages := len(b.chat.messages)
+	b.processCommand(duelMessage("Carol", "!join"))
+	b.processCommand(modMessage("!boss"))
+	if len(b.chat.messages) != messages+1 {
+		t.Fatalf("messages = %+v, want a new boss", b.chat.messages[messages:])
+	}
+}
+
+func TestRaidWithoutFighters(t *testing.T) {
+	b, sqlDB, _ := createTestBotForRaid(t)
+
+	b.processCommand(modMessage("!boss"))
+	b.fightRaid(t.Context())
+	if got := b.chat.messages[len(b.chat.messages)-1].Message; !strings.Contains(got, "wanders off") {
+		t.Fatalf("message = %q", got)
+	}
+	var victory bool
+	if err := sqlDB.QueryRowContext(context.Background(), `SELECT victory FROM raids`).Scan(&victory); err != nil {
+		t.Fatal(err)
+	}
+	if victory {
+		t.Fatal("an unopposed boss must not be recorded as a victory")
+	}
+	// The join window closing after the raid is over does nothing.
+	b.fightRaid(t.Context())
+}
+
+func TestRaidUnknownBoss(t *testing.T) {
+	b, _, _ := createTestBotForRaid(t)
+
+	b.processCommand(modMessage("!boss dragon"))
+	if got := lastReply(b); got != `Unknown boss "dragon". Try one of: slime` {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+func TestRaidStopsAtShutdown(t *testing.T) {
+	b, sqlDB, _ := createTestBotForRaid(t)
+	b.bosses = []catalog.Boss{{Key: "golem", Name: "the Golem", HP: 1000, Rounds: 5, JoinWindow: time.Hour}}
+	b.raidRoundDelay = time.Hour
+	ctx, cancel := context.WithCancel(t.Context())
+
+	b.processCommand(modMessage("!boss"))
+	b.processCommand(duelMessage("Alice", "!join"))
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		b.fightRaid(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("raid kept fighting after shutdown")
+	}
+
+	var raids int
+	if err := sqlDB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM raids`).Scan(&raids); err != nil {
+		t.Fatal(err)
+	}
+	if raids != 0 {
+		t.Fatalf("recorded %d raids, want a raid stopped by shutdown left unrecorded", raids)
+	}
+	b.raid.mu.Lock()
+	defer b.raid.mu.Unlock()
+	if b.raid.state != raidIdle {
+		t.Fatalf("raid state = %v, want idle", b.raid.state)
+	}
+}
@@ -3,12 +3,17 @@ package charsibot
 import (
 	"context"
 	"log/slog"
+	"maps"
+	"slices"
 	"testing"
 
 	"github.com/joeyak/go-twitch-eventsub/v3"
 	_ "modernc.org/sqlite"
 
+	"github.com/lukeramljak/charsibot/catalog"
 	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/dedupe"
+	"github.com/lukeramljak/charsibot/rewards"
 	"github.com/lukeramljak/charsibot/stats"
 )
 
@@ -28,11 +33,12 @@ func TestDrinkAPotionCreatesStatsForNewUser(t *testing.T) {
 		logger:       slog.New(slog.DiscardHandler),
 		statsService: svc,
 	}
-	b.redemptions = Redemptions(nil)
+	b.redemptions = Redemptions(nil, catalog.Rewards)
+	registerTestRewards(t, b, "drink-a-potion")
 
 	event := twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
 		User:   twitch.User{UserID: "newuser1", UserName: "newuser"},
-		Reward: twitch.CustomChannelPointReward{Title: "Drink a Potion"},
+		Reward: twitch.CustomChannelPointReward{ID: "drink-a-potion", Title: "Drink a Potion"},
 	}
 
 	statsBefore, _ := queries.GetUserStatValues(ctx, "newuser1")
@@ -81,11 +87,12 @@ func TestDrinkAPotionModifiesStatForExistingUser(t *testing.T) {
 		logger:       slog.New(slog.DiscardHandler),
 		statsService: svc,
 	}
-	b.redemptions = Redemptions(nil)
+	b.redemptions = Redemptions(nil, catalog.Rewards)
+	registerTestRewards(t, b, "drink-a-potion")
 
 	event := twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
 		User:   twitch.User{UserID: "existinguser1", UserName: "existing"},
-		Reward: twitch.CustomChannelPointReward{Title: "Drink a Potion"},
+		Reward: twitch.CustomChannelPointReward{ID: "drink-a-potion", Title: "Drink a Potion"},
 	}
 
 	b.onChannelPointRedemption(event)
@@ -112,33 +119,33 @@ func TestDrinkAPotionModifiesStatForExistingUser(t *testing.T) {
 func TestOnChannelPointRedemption(t *testing.T) {
 	tests := []struct {
 		name           string
-		rewardTitle    string
+		rewardID       string
 		redemptions    map[string]RedemptionFunc
-		expectExecuted string // reward title key expected to fire, empty if none
+		expectExecuted string // reward key expected to fire, empty if none
 	}{
 		{
 			name:           "matching redemption executes",
-			rewardTitle:    "Special Reward",
-			expectExecuted: "Special Reward",
+			rewardID:       "special-reward",
+			expectExecuted: "special-reward",
 			redemptions: map[string]RedemptionFunc{
-				"Special Reward": nil, // replaced per-test below
+				"special-reward": nil, // replaced per-test below
 			},
 		},
 		{
-			name:        "non-matching reward does nothing",
-			rewardTitle: "Other Reward",
+			name:     "non-matching reward does nothing",
+			rewardID: "other-reward",
 			redemptions: map[string]RedemptionFunc{
-				"Special Reward": nil,
+				"special-reward": nil,
 			},
 		},
 		{
 			name:        "empty redemptions map",
-			rewardTitle: "Any Reward",
+			rewardID:    "any-reward",
 			redemptions: map[string]RedemptionFunc{},
 		},
 		{
 			name:        "nil redemptions map",
-			rewardTitle: "Any Reward",
+			rewardID:    "any-reward",
 			redemptions: nil,
 		},
 	}
@@ -150,18 +157,20 @@ func TestOnChannelPointRedemption(t *testing.T) {
 			redemptions := make(map[string]RedemptionFunc, len(tt.redemptions))
 			for key := range tt.redemptions {
 				k := key
-				redemptions[k] = func(_ context.Context, _ *Bot, _ twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) {
+				redemptions[k] = func(_ context.Context, _ *Bot, _ twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
 					executed[k] = true
+					return nil
 				}
 			}
 
 			b := createTestBotForRedemption(t)
 			b.redemptions = redemptions
+			registerTestRewards(t, b, slices.Collect(maps.Keys(tt.redemptions))...)
 
 			event := twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
 				User: twitch.User{UserName: "testuser"},
 				Reward: twitch.CustomChannelPointReward{
-					Title: tt.rewardTitle,
+					ID: tt.rewardID,
 				},
 			}
 
@@ -182,20 +191,24 @@ func TestOnChannelPointRedemption(t *testing.T) {
 	}
 }
 
-func TestRedemptionTitleMatching(t *testing.T) {
+func TestRedemptionRewardIDMatching(t *testing.T) {
 	b := createTestBotForRedemption(t)
 
 	executed := false
 	b.redemptions = map[string]RedemptionFunc{
-		"Special Reward": func(_ context.Context, _ *Bot, _ twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) {
+		"special-reward": func(_ context.Context, _ *Bot, _ twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
 			executed = true
+			return nil
 		},
 	}
+	b.rewardRegistry = newTestRewardRegistry(t, map[string]catalog.Reward{
+		"reward-1": {Key: "special-reward", Title: "Special Reward"},
+	})
 
-	// Reward that should trigger
+	// Reward that should trigger, even after being renamed on Twitch
 	b.onChannelPointRedemption(twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
 		User:   twitch.User{UserName: "testuser"},
-		Reward: twitch.CustomChannelPointReward{Title: "Special Reward"},
+		Reward: twitch.CustomChannelPointReward{ID: "reward-1", Title: "Renamed Reward"},
 	})
 
 	if !executed {
@@ -207,12 +220,26 @@ func TestRedemptionTitleMatching(t *testing.T) {
 	// Reward that should not trigger
 	b.onChannelPointRedemption(twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
 		User:   twitch.User{UserName: "testuser"},
-		Reward: twitch.CustomChannelPointReward{Title: "Different Reward"},
+		Reward: twitch.CustomChannelPointReward{ID: "reward-2", Title: "Different Reward"},
 	})
 
 	if executed {
 		t.Error("redemption should not have executed for non-matching reward")
 	}
+
+	// A reward that is not registered yet falls back to its title and is
+	// registered by ID for next time.
+	b.onChannelPointRedemption(twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
+		User:   twitch.User{UserName: "testuser"},
+		Reward: twitch.CustomChannelPointReward{ID: "reward-3", Title: "special reward"},
+	})
+
+	if !executed {
+		t.Error("redemption should have executed for an unregistered reward with a matching title")
+	}
+	if registration, ok := b.rewardRegistry.Lookup("reward-3"); !ok || registration.HandlerKey != "special-reward" {
+		t.Errorf("reward-3 registration = %+v, %v", registration, ok)
+	}
 }
 
 func createTestBotForRedemption(t *testing.T) *Bot {
@@ -224,7 +251,73 @@ func createTestBotForRedemption(t *testing.T) *Bot {
 	}
 
 	return &Bot{
-		config: cfg,
-		logger: slog.New(slog.DiscardHandler),
+		config:   cfg,
+		logger:   slog.New(slog.DiscardHandler),
+		messages: testCatalog(t).Messages,
+	}
+}
+
+// registerTestRewards gives the bot a reward registry with a handler for each
+// key, registered under the key as its Twitch reward ID.
+func registerTestRewards(t *testing.T, b *Bot, keys ...string) {
+	t.Helper()
+	registered := make(map[string]catalog.Reward, len(keys))
+	for _, key := range keys {
+		registered[key] = catalog.Reward{Key: key, Title: key}
+	}
+	b.rewardRegistry = newTestRewardRegistry(t, registered)
+}
+
+// newTestRewardRegistry returns a registry with a handler for each catalog
+// reward, registered under the reward ID it is keyed by.
+func newTestRewardRegistry(t *testing.T, registered map[string]catalog.Reward) *rewards.Service {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	handlers := make([]catalog.Reward, 0, len(registered))
+	for _, reward := range registered {
+		handlers = append(handlers, reward)
+	}
+	registry, err := rewards.NewService(t.Context(), queries, handlers, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, reward := range registered {
+		if _, err := registry.Register(t.Context(), id, reward.Key, reward.Title); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+func TestRedeliveredRedemptionIsHandledOnce(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	dedupeService, err := dedupe.NewService(queries, dedupe.DefaultRetention, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	b := createTestBotForRedemption(t)
+	b.dedupe = dedupeService
+	b.redemptions = map[string]RedemptionFunc{
+		"mystery-box": func(context.Context, *Bot, twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
+			calls++
+			return nil
+		},
+	}
+	registerTestRewards(t, b, "mystery-box")
+
+	event := twitch.EventChannelChannelPointsCustomRewardRedemptionAdd{
+		ID:     "redemption-1",
+		User:   twitch.User{UserID: "viewer-1", UserName: "viewer"},
+		Reward: twitch.CustomChannelPointReward{ID: "mystery-box", Title: "Mystery Box"},
+	}
+	b.onChannelPointRedemption(event)
+	b.onChannelPointRedemption(event)
+
+	if calls != 1 {
+		t.Fatalf("redemption handled %d times, want 1", calls)
 	}
 }
@@ -0,0 +1,163 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+)
+
+// syncRewards makes sure every catalog reward exists on Twitch and is
+// registered to its handler. A reward is found by its registered IDs first and
+// by title otherwise; missing rewards are created, and rewards created by this
+// client ID are updated when their settings have drifted from the catalog.
+// Rewards made in the Twitch dashboard are registered but cannot be updated.
+func (b *Bot) syncRewards(ctx context.Context) error {
+	if b.streamerAPI == nil || b.rewardRegistry == nil || len(b.rewards) == 0 {
+		return nil
+	}
+	all, err := b.streamerAPI.listCustomRewards(ctx, b.config.ChannelUserID, false)
+	if err != nil {
+		return err
+	}
+	manageable, err := b.streamerAPI.listCustomRewards(ctx, b.config.ChannelUserID, true)
+	if err != nil {
+		return err
+	}
+	state := rewardSyncState{
+		byID:       make(map[string]customReward, len(all)),
+		byTitle:    make(map[string]customReward, len(all)),
+		manageable: make(map[string]bool, len(manageable)),
+	}
+	for _, reward := range all {
+		state.byID[reward.ID] = reward
+		state.byTitle[strings.ToLower(reward.Title)] = reward
+	}
+	for _, reward := range manageable {
+		state.manageable[reward.ID] = true
+	}
+
+	var errs []error
+	for _, reward := range b.rewards {
+		if err := b.syncReward(ctx, reward, state); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("sync rewards: %w", err)
+	}
+	return nil
+}
+
+type rewardSyncState struct {
+	byID       map[string]customReward
+	byTitle    map[string]customReward
+	manageable map[string]bool
+}
+
+// syncReward creates or updates a single reward and registers it.
+func (b *Bot) syncReward(ctx context.Context, reward catalog.Reward, state rewardSyncState) error {
+	request := rewardRequest(reward)
+
+	current, found := customReward{}, false
+	for _, id := range b.rewardRegistry.RewardIDs(reward.Key) {
+		if current, found = state.byID[id]; found {
+			break
+		}
+	}
+	if !found {
+		current, found = state.byTitle[strings.ToLower(reward.Title)]
+	}
+
+	switch {
+	case !found:
+		created, err := b.streamerAPI.createCustomReward(ctx, b.config.ChannelUserID, request)
+		if err != nil {
+			return err
+		}
+		b.logger.Info("created channel point reward", "reward", reward.Title, "reward_id", created.ID)
+		current = created
+	case !state.manageable[current.ID]:
+		b.logger.Warn("channel point reward was not created by the bot, so it cannot be updated or refunded",
+			"reward", current.Title, "reward_id", current.ID)
+	case !rewardMatches(current, request):
+		if _, err := b.streamerAPI.updateCustomReward(ctx, b.config.ChannelUserID, current.ID, request); err != nil {
+			return err
+		}
+		b.logger.Info("updated channel point reward", "reward", reward.Title, "reward_id", current.ID)
+	}
+
+	// Leave existing registrations alone, including ones an admin pointed at a
+	// different handler.
+	if _, ok := b.rewardRegistry.Lookup(current.ID); ok {
+		return nil
+	}
+	if _, err := b.rewardRegistry.Register(ctx, current.ID, reward.Key, current.Title); err != nil {
+		return fmt.Errorf("register reward %q: %w", reward.Title, err)
+	}
+	return nil
+}
+
+// redemptionHandler returns the handler for a redeemed reward, matched by
+// reward ID or, for rewards not registered yet, by title. Without a registry
+// every reward is matched by title, so rewards made in the Twitch dashboard
+// keep working.
+func (b *Bot) redemptionHandler(
+	ctx context.Context,
+	reward twitch.CustomChannelPointReward,
+) (RedemptionFunc, bool) {
+	var key string
+	var ok bool
+	if b.rewardRegistry != nil {
+		key, ok = b.rewardRegistry.Resolve(ctx, reward.ID, reward.Title)
+	} else {
+		key, ok = b.rewardKeyByTitle(reward.Title)
+	}
+	if !ok {
+		return nil, false
+	}
+	fn, ok := b.redemptions[key]
+	return fn, ok
+}
+
+// rewardKeyByTitle returns the key of the catalog reward titled title,
+// ignoring case.
+func (b *Bot) rewardKeyByTitle(title string) (string, bool) {
+	for _, reward := range b.rewards {
+		if strings.EqualFold(reward.Title, title) {
+			return reward.Key, true
+		}
+	}
+	return "", false
+}
+
+func rewardRequest(reward catalog.Reward) customRewardRequest {
+	cooldown := int(reward.Cooldown.Seconds())
+	return customRewardRequest{
+		Title:                   reward.Title,
+		Prompt:                  reward.Prompt,
+		Cost:                    reward.Cost,
+		IsEnabled:               reward.Enabled,
+		BackgroundColor:         reward.BackgroundColor,
+		IsGlobalCooldownEnabled: cooldown > 0,
+		GlobalCooldownSeconds:   cooldown,
+	}
+}
+
+// rewardMatches reports whether a reward on Twitch already has the requested
+// settings. Twitch assigns a colour when none is requested, so an empty
+// colour matches any.
+func rewardMatches(current customReward, want customRewardRequest) bool {
+	return current.Title == want.Title &&
+		current.Prompt == want.Prompt &&
+		current.Cost == want.Cost &&
+		current.IsEnabled == want.IsEnabled &&
+		current.SkipRequestQueue == want.SkipRequestQueue &&
+		(want.BackgroundColor == "" || strings.EqualFold(current.BackgroundColor, want.BackgroundColor)) &&
+		current.GlobalCooldownSetting.IsEnabled == want.IsGlobalCooldownEnabled &&
+		(!want.IsGlobalCooldownEnabled || current.GlobalCooldownSetting.Seconds == want.GlobalCooldownSeconds)
+}
@@ -0,0 +1,144 @@
+package charsibot
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/rewards"
+)
+
+func TestSyncRewardsCreatesUpdatesAndRegistersRewards(t *testing.T) {
+	var calls []string
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("broadcaster_id") != "channel456" {
+			t.Errorf("broadcaster_id = %q", query.Get("broadcaster_id"))
+		}
+		var body customRewardRequest
+		if r.Method != http.MethodGet {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode reward: %v", err)
+			}
+			if body.SkipRequestQueue {
+				t.Errorf("%s %q skips the request queue", r.Method, body.Title)
+			}
+		}
+		calls = append(calls, r.Method+" "+body.Title)
+		switch r.Method {
+		case http.MethodGet:
+			// The potion was renamed on Twitch and the dice reward was made
+			// in the dashboard, so only the potion is manageable.
+			potion := `{"id":"potion-id","title":"Old Potion","prompt":"Old","cost":100,"is_enabled":true}`
+			if query.Get("only_manageable_rewards") == "true" {
+				_, _ = w.Write([]byte(`{"data":[` + potion + `]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":[` + potion + `,
+				{"id":"dice-id","title":"tempt the dice","prompt":"Roll","cost":50,"is_enabled":true}
+			]}`))
+		case http.MethodPatch:
+			if query.Get("id") != "potion-id" {
+				t.Errorf("updated reward %q, want potion-id", query.Get("id"))
+			}
+			_, _ = w.Write([]byte(`{"data":[{"id":"potion-id"}]}`))
+		case http.MethodPost:
+			if body.Cost != 1000 || !body.IsGlobalCooldownEnabled || body.GlobalCooldownSeconds != 300 {
+				t.Errorf("created reward = %+v", body)
+			}
+			_, _ = w.Write([]byte(`{"data":[{"id":"box-id"}]}`))
+		}
+	})
+
+	b := createTestBotForRedemption(t)
+	b.streamerAPI = api
+	b.rewards = []catalog.Reward{
+		{
+			Key:      catalog.BlindBoxRewardKey("coobubu"),
+			Title:    "Mystery Box",
+			Cost:     1000,
+			Cooldown: 5 * time.Minute,
+			Enabled:  true,
+		},
+		{Key: "drink-a-potion", Title: "Drink a Potion", Prompt: "New", Cost: 100, Enabled: true},
+		{Key: "tempt-the-dice", Title: "Tempt the Dice", Prompt: "Roll", Cost: 75, Enabled: true},
+	}
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	registry, err := rewards.NewService(t.Context(), queries, b.rewards, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Register(t.Context(), "potion-id", "drink-a-potion", "Drink a Potion"); err != nil {
+		t.Fatal(err)
+	}
+	b.rewardRegistry = registry
+
+	if err := b.syncRewards(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"GET ", "GET ", "POST Mystery Box", "PATCH Drink a Potion"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for id, key := range map[string]string{
+		"box-id":    catalog.BlindBoxRewardKey("coobubu"),
+		"potion-id": "drink-a-potion",
+		"dice-id":   "tempt-the-dice",
+	} {
+		if registration, ok := registry.Lookup(id); !ok || registration.HandlerKey != key {
+			t.Errorf("Lookup(%q) = %+v, %v, want %q", id, registration, ok, key)
+		}
+	}
+}
+
+func TestNewRejectsRewardsWithoutHandlers(t *testing.T) {
+	catalogRewards := []catalog.Reward{{Key: "unknown", Title: "Unknown", Cost: 1}}
+	_, err := New(Config{}, Deps{Rewards: catalogRewards}, slog.New(slog.DiscardHandler))
+	if err == nil {
+		t.Fatal("expected an error for a reward without a handler")
+	}
+}
+
+func TestCatalogRewardsHaveHandlers(t *testing.T) {
+	cat := testCatalog(t)
+	_, err := New(Config{}, Deps{
+		Series:   cat.Series,
+		Rewards:  cat.Rewards,
+		Triggers: cat.Triggers,
+		Bosses:   cat.Bosses,
+		Messages: cat.Messages,
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedemptionHandlerMatchesTitleWithoutRegistry(t *testing.T) {
+	b := createTestBotForRedemption(t)
+	b.rewards = []catalog.Reward{{Key: "mystery-box", Title: "Mystery Box"}}
+	b.redemptions = map[string]RedemptionFunc{
+		"mystery-box": func(context.Context, *Bot, twitch.EventChannelChannelPointsCustomRewardRedemptionAdd) error {
+			return nil
+		},
+	}
+
+	if _, ok := b.redemptionHandler(t.Context(), twitch.CustomChannelPointReward{
+		ID:    "dashboard-reward",
+		Title: "mystery box",
+	}); !ok {
+		t.Fatal("reward made in the dashboard was not matched by title")
+	}
+	if _, ok := b.redemptionHandler(t.Context(), twitch.CustomChannelPointReward{Title: "Other"}); ok {
+		t.Fatal("unknown reward matched a handler")
+	}
+}
@@ -0,0 +1,128 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+const defaultDice = "1d20"
+
+// rollCommands returns !roll, which rolls dice with the chatter's stats as
+// modifiers.
+func rollCommands() map[string]Command {
+	return map[string]Command{
+		"roll": {
+			Description:  "Roll dice, like 1d20+STR. Stats add half their value.",
+			UserCooldown: 10 * time.Second,
+			OnCooldown:   CooldownReply,
+			Args:         []Arg{{Name: "dice", Optional: true}},
+			Execute: func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage, args Args) {
+				text := args.Word("dice")
+				if text == "" {
+					text = defaultDice
+				}
+				expr, err := parseDice(text)
+				if err == nil {
+					err = expr.checkStats(b.statsService.Definitions())
+				}
+				if err != nil {
+					key, data := diceErrorMessage(err)
+					b.replyWith(event, key, data)
+					return
+				}
+				roll, err := b.rollDice(ctx, event.ChatterUserId, event.ChatterUserName, expr)
+				if err != nil {
+					b.logger.Error("failed to roll dice", "err", err, "user", event.ChatterUserName, "dice", text)
+					return
+				}
+				b.replyWith(event, catalog.MessageRoll, catalog.RollMessageData{
+					User: event.ChatterUserName,
+					Roll: roll.data(),
+				})
+			},
+		},
+	}
+}
+
+// diceErrorMessage returns the catalog message that tells the chatter what is
+// wrong with the dice they typed.
+func diceErrorMessage(err error) (string, any) {
+	var diceErr *diceError
+	if !errors.As(err, &diceErr) {
+		return catalog.MessageCommandFailed, struct{}{}
+	}
+	switch diceErr.problem {
+	case diceNone:
+		return catalog.MessageRollNoDice, catalog.RollErrorData{}
+	case diceTooMany:
+		return catalog.MessageRollTooManyDice, catalog.RollErrorData{Max: maxDice}
+	case diceBadSides:
+		return catalog.MessageRollBadSides, catalog.RollErrorData{Term: diceErr.term, Max: maxDieSides}
+	case diceNumberTooBig:
+		return catalog.MessageRollNumberTooBig, catalog.RollErrorData{Term: diceErr.term, Max: maxDiceTerm}
+	case diceBadTerm:
+		return catalog.MessageRollBadTerm, catalog.RollErrorData{Term: diceErr.term}
+	case diceUnknownStat:
+		return catalog.MessageUnknownStat, catalog.UnknownStatData{Stat: diceErr.term}
+	default:
+		return catalog.MessageRollMalformed, catalog.RollErrorData{}
+	}
+}
+
+// rollDice rolls expr with the chatter's stats as modifiers and shows the
+// roll on the overlay.
+func (b *Bot) rollDice(ctx context.Context, userID, username string, expr diceExpr) (diceRoll, error) {
+	userStats, err := b.statsService.GetOrCreateStats(ctx, userID, username)
+	if err != nil {
+		return diceRoll{}, fmt.Errorf("get or create stats: %w", err)
+	}
+	roll, err := expr.roll(userStats, rollDie)
+	if err != nil {
+		return diceRoll{}, err
+	}
+	b.broadcast(roll.overlayEvent(username))
+	return roll, nil
+}
+
+// checkRewardDice parses the dice of every roll effect, so that a bad
+// expression stops the bot at startup rather than refunding redemptions.
+// Stat names are only checked when definitions are given.
+func checkRewardDice(rewards []catalog.Reward, definitions []stats.Definition) error {
+	for _, reward := range rewards {
+		if err := checkEffectDice(reward.Effects, definitions); err != nil {
+			return fmt.Errorf("reward %q: %w", reward.Key, err)
+		}
+	}
+	return nil
+}
+
+func checkEffectDice(effects []catalog.Effect, definitions []stats.Definition) error {
+	for _, effect := range effects {
+		if effect.Type == catalog.EffectRoll {
+			expr, err := parseDice(effect.Dice)
+			if err != nil {
+				return fmt.Errorf("dice %q: %w", effect.Dice, err)
+			}
+			if definitions != nil {
+				if err := expr.checkStats(definitions); err != nil {
+					return fmt.Errorf("dice %q: %w", effect.Dice, err)
+				}
+			}
+		}
+		for _, outcomes := range [][]catalog.Outcome{effect.Outcomes, effect.CriticalSuccess, effect.CriticalFailure} {
+			for _, outcome := range outcomes {
+				if err := checkEffectDice(outcome.Effects, definitions); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
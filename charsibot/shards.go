@@ -0,0 +1,101 @@
+package charsibot
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = 2 * time.Minute
+)
+
+// eventSubShard tracks the WebSocket session currently assigned to one conduit
+// shard so the shard can be reconnected on its own.
+type eventSubShard struct {
+	id        string
+	reconnect chan error
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newEventSubShards(count int) []*eventSubShard {
+	shards := make([]*eventSubShard, count)
+	for i := range shards {
+		shards[i] = &eventSubShard{id: strconv.Itoa(i), reconnect: make(chan error, 1)}
+	}
+	return shards
+}
+
+func (s *eventSubShard) setSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionID = sessionID
+}
+
+// requestReconnect asks the shard's connect loop to start a new session. When
+// sessionID is set, the request is ignored unless it still matches the shard's
+// current session, so a stale notification cannot drop a healthy replacement.
+func (s *eventSubShard) requestReconnect(sessionID string, err error) bool {
+	s.mu.Lock()
+	current := s.sessionID
+	s.mu.Unlock()
+	if current == "" || (sessionID != "" && sessionID != current) {
+		return false
+	}
+	select {
+	case s.reconnect <- err:
+		return true
+	default:
+		return false
+	}
+}
+
+// shardCount returns how many conduit shards to run. The local mock server
+// only understands a single session.
+func (b *Bot) shardCount() int {
+	if b.config.UseMockServer || b.config.ConduitShardCount < 1 {
+		return 1
+	}
+	return b.config.ConduitShardCount
+}
+
+func (b *Bot) findShard(shardID string) *eventSubShard {
+	b.shardsMu.Lock()
+	defer b.shardsMu.Unlock()
+	for _, shard := range b.shards {
+		if shard.id == shardID {
+			return shard
+		}
+	}
+	return nil
+}
+
+// runShard keeps one conduit shard connected until ctx is done. Failed
+// connection attempts back off exponentially with jitter; the delay resets
+// once a session has been established.
+func (b *Bot) runShard(ctx context.Context, shard *eventSubShard, url string) {
+	failures := 0
+	for {
+		established, err := b.serveShard(ctx, shard, url)
+		if ctx.Err() != nil || b.shuttingDown.Load() {
+			return
+		}
+		if established {
+			failures = 0
+		}
+		delay := backoffDelay(reconnectBaseDelay, reconnectMaxDelay, failures)
+		failures++
+		b.logger.Error("eventsub disconnected, reconnecting",
+			"shard_id", shard.id,
+			"err", err,
+			"delay", delay,
+		)
+		if sleepContext(ctx, delay) != nil {
+			return
+		}
+	}
+}
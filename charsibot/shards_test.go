@@ -0,0 +1,50 @@
+package charsibot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShardReconnectIgnoresStaleSession(t *testing.T) {
+	shard := newEventSubShards(2)[1]
+	reconnect := shard.reconnect
+	shard.setSession("session-2")
+
+	if shard.requestReconnect("session-1", errReconnectRequested) {
+		t.Fatal("reconnected for a stale session")
+	}
+	select {
+	case err := <-reconnect:
+		t.Fatalf("unexpected reconnect request: %v", err)
+	default:
+	}
+
+	shard.requestReconnect("session-2", errReconnectRequested)
+	select {
+	case err := <-reconnect:
+		if !errors.Is(err, errReconnectRequested) {
+			t.Fatalf("reconnect err = %v", err)
+		}
+	default:
+		t.Fatal("expected a reconnect request for the current session")
+	}
+}
+
+func TestShardCount(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{name: "default", want: 1},
+		{name: "configured", cfg: Config{ConduitShardCount: 3}, want: 3},
+		{name: "mock server", cfg: Config{ConduitShardCount: 3, UseMockServer: true}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&Bot{config: tt.cfg}).shardCount(); got != tt.want {
+				t.Fatalf("shardCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/server"
+)
+
+const (
+	subscriptionRetryBaseDelay = 5 * time.Second
+	subscriptionRetryMaxDelay  = 10 * time.Minute
+
+	subscriptionStatusEnabled = "enabled"
+	subscriptionStatusPending = "pending"
+	subscriptionStatusFailed  = "failed"
+)
+
+type subscriptionSpec struct {
+	Type      twitch.EventSubscription
+	Version   string
+	Condition map[string]string
+}
+
+// subscriptionKey identifies a subscription by type, version and the non-empty
+// condition fields. Twitch echoes every condition field back, including the
+// ones left blank on creation, so empty values are ignored.
+func subscriptionKey(subType, version string, condition map[string]string) string {
+	var b strings.Builder
+	b.WriteString(subType)
+	b.WriteString("@")
+	b.WriteString(version)
+	for _, name := range slices.Sorted(maps.Keys(condition)) {
+		if condition[name] == "" {
+			continue
+		}
+		fmt.Fprintf(&b, ";%s=%s", name, condition[name])
+	}
+	return b.String()
+}
+
+func (s subscriptionSpec) key() string {
+	return subscriptionKey(string(s.Type), s.Version, s.Condition)
+}
+
+func (b *Bot) desiredSubscriptions() []subscriptionSpec {
+	return []subscriptionSpec{
+		{
+			Type:    twitch.SubChannelChatMessage,
+			Version: "1",
+			Condition: map[string]string{
+				"broadcaster_user_id": b.config.ChannelUserID,
+				"user_id":             b.config.BotUserID,
+			},
+		},
+		{
+			Type:    twitch.SubChannelChannelPointsCustomRewardRedemptionAdd,
+			Version: "1",
+			Condition: map[string]string{
+				"broadcaster_user_id": b.config.ChannelUserID,
+			},
+		},
+		{
+			Type:    twitch.SubChannelRaid,
+			Version: "1",
+			Condition: map[string]string{
+				"to_broadcaster_user_id": b.config.ChannelUserID,
+			},
+		},
+		{
+			Type:    twitch.SubConduitShardDisabled,
+			Version: "1",
+			Condition: map[string]string{
+				"client_id": b.config.ClientID,
+			},
+		},
+	}
+}
+
+// subscriptionReconciler keeps the conduit's EventSub subscriptions equal to
+// the configured set. It deletes subscriptions that no longer belong, creates
+// missing ones and retries failed or revoked ones with backoff.
+type subscriptionReconciler struct {
+	// api and conduitID are set once the conduit is known, before Run starts.
+	api       *twitchAPI
+	conduitID string
+	logger    *slog.Logger
+	now       func() time.Time
+
+	specs   []subscriptionSpec
+	trigger chan struct{}
+
+	mu    sync.Mutex
+	state map[string]*server.EventSubSubscription
+}
+
+func newSubscriptionReconciler(specs []subscriptionSpec, logger *slog.Logger) *subscriptionReconciler {
+	r := &subscriptionReconciler{
+		logger:  logger,
+		now:     time.Now,
+		specs:   specs,
+		trigger: make(chan struct{}, 1),
+		state:   make(map[string]*server.EventSubSubscription, len(specs)),
+	}
+	for _, spec := range specs {
+		r.state[spec.key()] = &server.EventSubSubscription{
+			Type:      string(spec.Type),
+			Version:   spec.Version,
+			Condition: maps.Clone(spec.Condition),
+			Status:    subscriptionStatusPending,
+			UpdatedAt: r.now(),
+		}
+	}
+	return r
+}
+
+// Run reconciles whenever Trigger is called and whenever a scheduled retry is
+// due, until ctx is done.
+func (r *subscriptionReconciler) Run(ctx context.Context) {
+	for {
+		if err := r.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("eventsub subscription reconcile failed", "err", err)
+		}
+
+		var retry <-chan time.Time
+		if next, ok := r.nextRetry(); ok {
+			retry = time.After(max(next.Sub(r.now()), 0))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.trigger:
+		case <-retry:
+		}
+	}
+}
+
+// Trigger requests a reconcile pass without blocking.
+func (r *subscriptionReconciler) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Revoked records that Twitch revoked a subscription and schedules it to be
+// recreated after a backoff delay.
+func (r *subscriptionReconciler) Revoked(sub twitch.PayloadSubscription) {
+	r.mu.Lock()
+	state, ok := r.state[subscriptionKey(string(sub.Type), sub.Version, sub.Condition)]
+	if ok {
+		state.ID = ""
+		state.Status = sub.Status
+		state.LastError = "revoked: " + sub.Status
+		r.scheduleRetryLocked(state)
+	}
+	r.mu.Unlock()
+
+	if ok {
+		r.Trigger()
+	}
+}
+
+// Reconcile performs a single pass against the subscriptions Twitch reports
+// for the conduit.
+func (r *subscriptionReconciler) Reconcile(ctx context.Context) error {
+	existing, err := r.api.listConduitSubscriptions(ctx, r.conduitID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	active := make(map[string]bool, len(r.specs))
+	for _, sub := range existing {
+		key := subscriptionKey(sub.Type, sub.Version, sub.Condition)
+		wanted := r.isWanted(key)
+		if wanted && sub.Status == subscriptionStatusEnabled && !active[key] {
+			active[key] = true
+			r.markEnabled(key, sub.ID)
+			continue
+		}
+
+		r.logger.Info("deleting eventsub subscription",
+			"subscription_id", sub.ID,
+			"type", sub.Type,
+			"status", sub.Status,
+			"wanted", wanted,
+		)
+		if err := r.api.deleteSubscription(ctx, sub.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, spec := range r.specs {
+		key := spec.key()
+		if active[key] || !r.retryDue(key) {
+			continue
+		}
+		r.logger.Info("creating eventsub subscription", "type", spec.Type)
+		id, err := r.api.createConduitSubscription(
+			ctx,
+			r.conduitID,
+			string(spec.Type),
+			spec.Version,
+			spec.Condition,
+		)
+		if err != nil {
+			r.markFailed(key, err)
+			errs = append(errs, fmt.Errorf("subscribe to %s: %w", spec.Type, err))
+			continue
+		}
+		r.markEnabled(key, id)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Snapshot returns the current state of every configured subscription in the
+// order they are declared.
+func (r *subscriptionReconciler) Snapshot() []server.EventSubSubscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]server.EventSubSubscription, 0, len(r.specs))
+	for _, spec := range r.specs {
+		state := *r.state[spec.key()]
+		state.Condition = maps.Clone(state.Condition)
+		if state.NextRetryAt != nil {
+			next := *state.NextRetryAt
+			state.NextRetryAt = &next
+		}
+		out = append(out, state)
+	}
+	return out
+}
+
+func (r *subscriptionReconciler) isWanted(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.state[key]
+	return ok
+}
+
+func (r *subscriptionReconciler) retryDue(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := r.state[key].NextRetryAt
+	return next == nil || !r.now().Before(*next)
+}
+
+func (r *subscriptionReconciler) nextRetry() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var next time.Time
+	for _, state := range r.state {
+		if state.NextRetryAt != nil && (next.IsZero() || state.NextRetryAt.Before(next)) {
+			next = *state.NextRetryAt
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (r *subscriptionReconciler) markEnabled(key, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := r.state[key]
+	if id != "" {
+		state.ID = id
+	}
+	state.Status = subscriptionStatusEnabled
+	state.LastError = ""
+	state.Attempts = 0
+	state.NextRetryAt = nil
+	state.UpdatedAt = r.now()
+}
+
+func (r *subscriptionReconciler) markFailed(key string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := r.state[key]
+	state.Status = subscriptionStatusFailed
+	state.LastError = err.Error()
+	r.scheduleRetryLocked(state)
+}
+
+func (r *subscriptionReconciler) scheduleRetryLocked(state *server.EventSubSubscription) {
+	next := r.now().Add(backoffDelay(subscriptionRetryBaseDelay, subscriptionRetryMaxDelay, state.Attempts))
+	state.Attempts++
+	state.NextRetryAt = &next
+	state.UpdatedAt = r.now()
+}
@@ -0,0 +1,161 @@
+package charsibot
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+type fakeSubscriptions struct {
+	mu      sync.Mutex
+	data    []eventSubSubscription
+	deleted []string
+	created []string
+	fail    bool
+}
+
+func (f *fakeSubscriptions) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(subscriptionListResponse{Data: f.data})
+	case http.MethodDelete:
+		f.deleted = append(f.deleted, r.URL.Query().Get("id"))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		if f.fail {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var req conduitSubscriptionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.created = append(f.created, req.Type)
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(subscriptionListResponse{
+			Data: []eventSubSubscription{{ID: "new-" + req.Type, Status: "enabled", Type: req.Type}},
+		})
+	}
+}
+
+func newTestReconciler(t *testing.T, fake *fakeSubscriptions) *subscriptionReconciler {
+	t.Helper()
+	api, _ := newFakeHelix(t, fake.handle)
+	bot := &Bot{config: Config{ClientID: "client-1", ChannelUserID: "channel-1", BotUserID: "bot-1"}}
+	r := newSubscriptionReconciler(bot.desiredSubscriptions(), slog.New(slog.DiscardHandler))
+	r.api = api
+	r.conduitID = "conduit-1"
+	return r
+}
+
+func conduitSub(id, subType, status string, condition map[string]string) eventSubSubscription {
+	return eventSubSubscription{
+		ID:        id,
+		Status:    status,
+		Type:      subType,
+		Version:   "1",
+		Condition: condition,
+		Transport: conduitTransport{Method: "conduit", ConduitID: "conduit-1"},
+	}
+}
+
+func TestReconcileDeletesStaleAndCreatesMissing(t *testing.T) {
+	fake := &fakeSubscriptions{data: []eventSubSubscription{
+		// Still wanted; Twitch echoes blank condition fields.
+		conduitSub("raid", "channel.raid", "enabled", map[string]string{
+			"from_broadcaster_user_id": "",
+			"to_broadcaster_user_id":   "channel-1",
+		}),
+		// Left over from a previous channel ID.
+		conduitSub("old-chat", "channel.chat.message", "enabled", map[string]string{
+			"broadcaster_user_id": "old-channel",
+			"user_id":             "bot-1",
+		}),
+		// Revoked by Twitch and should be replaced.
+		conduitSub("revoked", "conduit.shard.disabled", "authorization_revoked", map[string]string{
+			"client_id": "client-1",
+		}),
+		// Belongs to another conduit and must be left alone.
+		{ID: "other", Status: "enabled", Type: "channel.follow", Transport: conduitTransport{
+			Method: "conduit", ConduitID: "conduit-2",
+		}},
+	}}
+	r := newTestReconciler(t, fake)
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"old-chat", "revoked"}; !slices.Equal(fake.deleted, want) {
+		t.Fatalf("deleted = %v, want %v", fake.deleted, want)
+	}
+	wantCreated := []string{
+		"channel.chat.message",
+		"channel.channel_points_custom_reward_redemption.add",
+		"conduit.shard.disabled",
+	}
+	if !slices.Equal(fake.created, wantCreated) {
+		t.Fatalf("created = %v, want %v", fake.created, wantCreated)
+	}
+	for _, state := range r.Snapshot() {
+		if state.Status != subscriptionStatusEnabled || state.ID == "" {
+			t.Fatalf("state = %#v, want enabled with ID", state)
+		}
+	}
+}
+
+func TestReconcileBacksOffFailedAndRevokedSubscriptions(t *testing.T) {
+	fake := &fakeSubscriptions{fail: true}
+	r := newTestReconciler(t, fake)
+	now := time.Unix(1_700_000_000, 0)
+	r.now = func() time.Time { return now }
+
+	if err := r.Reconcile(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	for _, state := range r.Snapshot() {
+		if state.Status != subscriptionStatusFailed || state.Attempts != 1 || state.NextRetryAt == nil {
+			t.Fatalf("state = %#v, want failed with a retry scheduled", state)
+		}
+	}
+
+	// Nothing is retried before the backoff has elapsed.
+	attempts := len(fake.created)
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.created) != attempts {
+		t.Fatal("retried before backoff elapsed")
+	}
+
+	fake.fail = false
+	now = now.Add(subscriptionRetryMaxDelay)
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Revoked(twitch.PayloadSubscription{
+		SubscriptionRequest: twitch.SubscriptionRequest{
+			Type:      twitch.SubChannelRaid,
+			Version:   "1",
+			Condition: map[string]string{"to_broadcaster_user_id": "channel-1", "from_broadcaster_user_id": ""},
+		},
+		ID:     "new-channel.raid",
+		Status: "authorization_revoked",
+	})
+	for _, state := range r.Snapshot() {
+		if state.Type != string(twitch.SubChannelRaid) {
+			continue
+		}
+		if state.Status != "authorization_revoked" || state.NextRetryAt == nil || !state.NextRetryAt.After(now) {
+			t.Fatalf("revoked state = %#v, want a scheduled retry", state)
+		}
+	}
+}
@@ -2,49 +2,55 @@ package charsibot
 
 import (
 	"context"
-	"slices"
+	"math/rand/v2"
 	"strings"
+	"time"
 
 	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
 )
 
 type Trigger struct {
 	Chance        int
 	ShouldTrigger func(event twitch.EventChannelChatMessage) bool
 	Execute       func(ctx context.Context, b *Bot, event twitch.EventChannelChatMessage)
-}
 
-// Triggers returns all chat message triggers.
-func Triggers() []Trigger {
-	const comeTriggerChance = 20
+	// Permission is the lowest chat role whose messages can fire the trigger.
+	Permission Permission
 
-	comeTriggerWords := []string{"come", "coming", "cum", "came"}
+	// Key identifies the trigger's Cooldown, the time after it fires during
+	// which it cannot fire again.
+	Key      string
+	Cooldown time.Duration
+}
 
-	return []Trigger{
-		{
-			Chance: comeTriggerChance,
+// Triggers returns the chat message triggers described by the catalog.
+func Triggers(catalogTriggers []catalog.Trigger) []Trigger {
+	triggers := make([]Trigger, 0, len(catalogTriggers))
+	for _, t := range catalogTriggers {
+		triggers = append(triggers, Trigger{
+			Key:        t.Key,
+			Chance:     t.Chance,
+			Cooldown:   t.Cooldown,
+			Permission: permissionNamed(t.Permission),
 			ShouldTrigger: func(event twitch.EventChannelChatMessage) bool {
-				if strings.ToLower(event.Message.Text) == "no coming" {
-					return false
-				}
-
-				words := strings.FieldsFunc(strings.ToLower(event.Message.Text), func(r rune) bool {
-					return (r < 'a' || r > 'z') && (r < '0' || r > '9')
-				})
-
-				for _, word := range words {
-					if slices.Contains(comeTriggerWords, word) {
-						return true
-					}
-				}
-				return false
+				return t.Matches(event.Message.Text)
 			},
 			Execute: func(_ context.Context, b *Bot, event twitch.EventChannelChatMessage) {
-				b.SendMessage(SendMessageParams{
-					Message:              "no coming",
-					ReplyParentMessageID: event.MessageId,
-				})
+				var response strings.Builder
+				data := catalog.TriggerData{User: event.ChatterUserName, Message: event.Message.Text}
+				if err := t.Responses[rand.IntN(len(t.Responses))].Execute(&response, data); err != nil {
+					b.logger.Error("failed to render trigger response", "err", err, "trigger", t.Key)
+					return
+				}
+				params := SendMessageParams{Message: response.String()}
+				if t.Reply {
+					params.ReplyParentMessageID = event.MessageId
+				}
+				b.SendMessage(params)
 			},
-		},
+		})
 	}
+	return triggers
 }
@@ -4,8 +4,13 @@ import (
 	"context"
 	"log/slog"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/joeyak/go-twitch-eventsub/v3"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/cooldowns"
 )
 
 func TestProcessTriggers(t *testing.T) {
@@ -298,3 +303,57 @@ func createTestBotForTrigger(t *testing.T) *Bot {
 		logger: slog.New(slog.DiscardHandler),
 	}
 }
+
+func TestCatalogTriggerRespondsAndCoolsDown(t *testing.T) {
+	b := createTestBotForTrigger(t)
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.cooldowns = cooldowns.NewTracker(nil, slog.New(slog.DiscardHandler))
+	b.triggers = Triggers([]catalog.Trigger{{
+		Key:       "greeting",
+		Words:     []string{"hello"},
+		Chance:    100,
+		Cooldown:  time.Hour,
+		Reply:     true,
+		Responses: []*template.Template{template.Must(template.New("greeting").Parse("hi {{.User}}"))},
+	}})
+	event := twitch.EventChannelChatMessage{
+		Chatter:   twitch.Chatter{ChatterUserName: "alice"},
+		MessageId: "m1",
+		Message:   twitch.ChatMessage{Text: "hello there"},
+	}
+
+	b.processTriggers(event)
+	b.processTriggers(event)
+
+	if len(b.chat.replies) != 1 {
+		t.Fatalf("replies = %+v, want one before the cooldown", b.chat.replies)
+	}
+	if reply := b.chat.replies[0]; reply.Message != "hi alice" || reply.ReplyParentMessageID != "m1" {
+		t.Fatalf("reply = %+v", reply)
+	}
+}
+
+func TestCatalogTriggerPermission(t *testing.T) {
+	b := createTestBotForTrigger(t)
+	b.chat = newChatQueue(nil, chatRateLimit, slog.New(slog.DiscardHandler))
+	b.triggers = Triggers([]catalog.Trigger{{
+		Key:        "mods-only",
+		Words:      []string{"hello"},
+		Chance:     100,
+		Permission: "moderator",
+		Responses:  []*template.Template{template.Must(template.New("mods-only").Parse("hi {{.User}}"))},
+	}})
+
+	viewer := chatFrom("u1")
+	viewer.ChatterUserName = "viewer"
+	viewer.Message.Text = "hello"
+	moderator := chatFrom("u2", "moderator")
+	moderator.ChatterUserName = "mod"
+	moderator.Message.Text = "hello"
+	b.processTriggers(viewer)
+	b.processTriggers(moderator)
+
+	if len(b.chat.messages) != 1 || b.chat.messages[0].Message != "hi mod" {
+		t.Fatalf("messages = %+v, want only the moderator answered", b.chat.messages)
+	}
+}
@@ -0,0 +1,201 @@
+package charsibot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	twitchAPIBase          = "https://api.twitch.tv/helix"
+	twitchRequestTimeout   = 10 * time.Second
+	twitchMaxRetries       = 3
+	twitchRetryBaseDelay   = 500 * time.Millisecond
+	twitchRetryMaxDelay    = 30 * time.Second
+	twitchMaxErrorBodySize = 1 << 10
+)
+
+// twitchAPI is a small Helix client for the endpoints the helix library does
+// not cover. It honours Twitch's rate-limit headers and retries 429 and 5xx
+// responses with jittered exponential backoff.
+type twitchAPI struct {
+	baseURL    string
+	clientID   string
+	httpClient *http.Client
+	timeout    time.Duration
+	maxRetries int
+	retryDelay time.Duration
+	now        func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) error
+
+	mu          sync.Mutex
+	rateLimited time.Time // zero unless the bucket is empty until this time
+}
+
+func newTwitchAPI(httpClient *http.Client, baseURL, clientID string) *twitchAPI {
+	if baseURL == "" {
+		baseURL = twitchAPIBase
+	}
+	return &twitchAPI{
+		baseURL:    baseURL,
+		clientID:   clientID,
+		httpClient: httpClient,
+		timeout:    twitchRequestTimeout,
+		maxRetries: twitchMaxRetries,
+		retryDelay: twitchRetryBaseDelay,
+		now:        time.Now,
+		sleep:      sleepContext,
+	}
+}
+
+// Do sends a JSON request to endpoint and decodes the JSON response into
+// result when non-nil. It returns the final HTTP status code.
+func (c *twitchAPI) Do(ctx context.Context, method, endpoint string, payload, result any) (int, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return 0, fmt.Errorf("marshal request body: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForRateLimit(ctx); err != nil {
+			return 0, err
+		}
+
+		status, retryAfter, err := c.attempt(ctx, method, endpoint, body, result)
+		if err == nil || attempt >= c.maxRetries || !isRetryable(ctx, status, err) {
+			return status, err
+		}
+
+		delay := backoffDelay(c.retryDelay, twitchRetryMaxDelay, attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if sleepErr := c.sleep(ctx, delay); sleepErr != nil {
+			return status, err
+		}
+	}
+}
+
+// attempt performs a single request and returns how long Twitch asked us to
+// wait before retrying, if at all.
+func (c *twitchAPI) attempt(
+	ctx context.Context,
+	method, endpoint string,
+	body []byte,
+	result any,
+) (int, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, bodyReader)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Client-Id", c.clientID)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	retryAfter := c.recordRateLimit(resp.Header)
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, retryAfter, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		if len(respBody) > twitchMaxErrorBodySize {
+			respBody = respBody[:twitchMaxErrorBodySize]
+		}
+		return resp.StatusCode, retryAfter, fmt.Errorf(
+			"%s %s failed (%d): %s", method, endpoint, resp.StatusCode, respBody,
+		)
+	}
+	if result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return resp.StatusCode, 0, fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return resp.StatusCode, 0, nil
+}
+
+// recordRateLimit remembers when the bucket refills if Twitch reports it as
+// empty, and returns how long until then.
+func (c *twitchAPI) recordRateLimit(header http.Header) time.Duration {
+	remaining, err := strconv.Atoi(header.Get("Ratelimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return 0
+	}
+	reset, err := strconv.ParseInt(header.Get("Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	resetAt := time.Unix(reset, 0)
+	c.mu.Lock()
+	c.rateLimited = resetAt
+	c.mu.Unlock()
+	return max(resetAt.Sub(c.now()), 0)
+}
+
+func (c *twitchAPI) waitForRateLimit(ctx context.Context) error {
+	c.mu.Lock()
+	wait := c.rateLimited.Sub(c.now())
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	return c.sleep(ctx, min(wait, twitchRetryMaxDelay))
+}
+
+func isRetryable(ctx context.Context, status int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if status == 0 {
+		// Transport error, including a per-request timeout.
+		return !errors.Is(err, context.Canceled)
+	}
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// backoffDelay returns an exponential delay for the given attempt with full
+// jitter applied to its upper half, capped at maxDelay.
+func backoffDelay(base, maxDelay time.Duration, attempt int) time.Duration {
+	delay := maxDelay
+	if attempt < 32 && base<<attempt > 0 && base<<attempt < maxDelay {
+		delay = base << attempt
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
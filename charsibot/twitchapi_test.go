@@ -0,0 +1,158 @@
+package charsibot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFakeHelix starts a local Helix server and returns a client pointed at it
+// that records backoff delays instead of sleeping.
+func newFakeHelix(t *testing.T, handler http.HandlerFunc) (*twitchAPI, *[]time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	var slept []time.Duration
+	api := newTwitchAPI(srv.Client(), srv.URL, "client-1")
+	api.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return api, &slept
+}
+
+func TestTwitchAPIRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	api, slept := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Client-Id") != "client-1" {
+			t.Errorf("Client-Id = %q", r.Header.Get("Client-Id"))
+		}
+		if calls.Add(1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"conduit-1","shard_count":1}]}`))
+	})
+
+	conduitID, err := api.getOrCreateConduit(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conduitID != "conduit-1" {
+		t.Fatalf("conduit = %q, want conduit-1", conduitID)
+	}
+	if calls.Load() != 3 || len(*slept) != 2 {
+		t.Fatalf("calls = %d, sleeps = %d; want 3 calls and 2 sleeps", calls.Load(), len(*slept))
+	}
+}
+
+func TestTwitchAPIDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		http.Error(w, `{"message":"invalid"}`, http.StatusBadRequest)
+	})
+
+	status, err := api.Do(context.Background(), http.MethodGet, "/eventsub/conduits", nil, nil)
+	if err == nil || status != http.StatusBadRequest {
+		t.Fatalf("status = %d, err = %v; want 400 error", status, err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestTwitchAPIWaitsForRateLimitReset(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	reset := now.Add(20 * time.Second)
+	var calls atomic.Int32
+	api, slept := newFakeHelix(t, func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Ratelimit-Remaining", "0")
+			w.Header().Set("Ratelimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	api.now = func() time.Time { return now }
+
+	status, err := api.Do(context.Background(), http.MethodPost, "/eventsub/subscriptions", struct{}{}, nil)
+	if err != nil || status != http.StatusAccepted {
+		t.Fatalf("status = %d, err = %v; want 202", status, err)
+	}
+	// One wait for the 429 retry, then one more before the next attempt
+	// because the bucket was reported empty until the reset time.
+	if len(*slept) != 2 || (*slept)[0] != 20*time.Second || (*slept)[1] != 20*time.Second {
+		t.Fatalf("slept = %v, want [20s 20s]", *slept)
+	}
+}
+
+func TestTwitchAPIStopsWhenContextCanceled(t *testing.T) {
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	api.sleep = func(context.Context, time.Duration) error {
+		cancel()
+		return context.Canceled
+	}
+
+	if _, err := api.Do(ctx, http.MethodGet, "/eventsub/conduits", nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCreateConduitSubscriptionTreatsConflictAsSuccess(t *testing.T) {
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		var req conduitSubscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if req.Transport.ConduitID != "conduit-1" || req.Type != "channel.raid" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		http.Error(w, "subscription already exists", http.StatusConflict)
+	})
+
+	id, err := api.createConduitSubscription(
+		context.Background(), "conduit-1", "channel.raid", "1", map[string]string{"to_broadcaster_user_id": "1"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "" {
+		t.Fatalf("id = %q, want empty for an existing subscription", id)
+	}
+}
+
+func TestGetOrCreateConduitResizesExistingConduit(t *testing.T) {
+	var resized updateConduitRequest
+	api, _ := newFakeHelix(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"data":[{"id":"conduit-1","shard_count":1}]}`))
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&resized); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+			_, _ = w.Write([]byte(`{"data":[{"id":"conduit-1","shard_count":3}]}`))
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+	})
+
+	conduitID, err := api.getOrCreateConduit(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conduitID != "conduit-1" || resized.ID != "conduit-1" || resized.ShardCount != 3 {
+		t.Fatalf("conduit = %q, resize = %+v", conduitID, resized)
+	}
+}
@@ -0,0 +1,65 @@
+package charsibot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+// errUserNotFound is returned when a login does not belong to a Twitch user.
+var errUserNotFound = errors.New("user not found")
+
+type twitchUser struct {
+	ID          string `json:"id"`
+	Login       string `json:"login"`
+	DisplayName string `json:"display_name"`
+}
+
+type userListResponse struct {
+	Data []twitchUser `json:"data"`
+}
+
+// getUserByLogin looks up a Twitch user by login name.
+func (c *twitchAPI) getUserByLogin(ctx context.Context, login string) (twitchUser, error) {
+	query := url.Values{"login": {login}}
+	var list userListResponse
+	if _, err := c.Do(ctx, http.MethodGet, "/users?"+query.Encode(), nil, &list); err != nil {
+		return twitchUser{}, fmt.Errorf("get user %q: %w", login, err)
+	}
+	if len(list.Data) == 0 {
+		return twitchUser{}, fmt.Errorf("get user %q: %w", login, errUserNotFound)
+	}
+	return list.Data[0], nil
+}
+
+// resolveUser resolves a chat @mention or bare login to a Twitch user. A
+// mention Twitch already resolved in the message's fragments is used as is;
+// anything else is looked up through Helix.
+func (b *Bot) resolveUser(
+	ctx context.Context,
+	event twitch.EventChannelChatMessage,
+	mention string,
+) (twitchUser, error) {
+	login := strings.ToLower(strings.TrimPrefix(mention, "@"))
+	if login == "" {
+		return twitchUser{}, errUserNotFound
+	}
+	for _, fragment := range event.Message.Fragments {
+		if fragment.Mention != nil && strings.EqualFold(fragment.Mention.UserLogin, login) {
+			return twitchUser{
+				ID:          fragment.Mention.UserID,
+				Login:       fragment.Mention.UserLogin,
+				DisplayName: fragment.Mention.UserName,
+			}, nil
+		}
+	}
+	if b.twitchAPI == nil {
+		return twitchUser{}, errors.New("twitch API is not initialised")
+	}
+	return b.twitchAPI.getUserByLogin(ctx, login)
+}
@@ -0,0 +1,31 @@
+package charsibot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// userTokenSource supplies a stored, refreshed user access token.
+type userTokenSource interface {
+	AccessToken(ctx context.Context, account string) (string, error)
+}
+
+// userTokenTransport authorizes requests as a Twitch user, for endpoints such
+// as channel points that reject app access tokens.
+type userTokenTransport struct {
+	tokens  userTokenSource
+	account string
+	base    http.RoundTripper
+}
+
+func (t *userTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.AccessToken(req.Context(), t.account)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, fmt.Errorf("%s access token: %w", t.account, err)
+	}
+	return t.base.RoundTrip(withBearer(req, token))
+}
@@ -9,14 +9,21 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/nicklaw5/helix/v2"
 	_ "modernc.org/sqlite"
 
 	"github.com/lukeramljak/charsibot/blindbox"
 	"github.com/lukeramljak/charsibot/catalog"
 	"github.com/lukeramljak/charsibot/charsibot"
+	"github.com/lukeramljak/charsibot/cooldowns"
 	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/dedupe"
+	"github.com/lukeramljak/charsibot/duels"
+	"github.com/lukeramljak/charsibot/raids"
+	"github.com/lukeramljak/charsibot/rewards"
 	"github.com/lukeramljak/charsibot/server"
 	"github.com/lukeramljak/charsibot/stats"
+	"github.com/lukeramljak/charsibot/tokens"
 )
 
 func main() {
@@ -38,7 +45,7 @@ func run() error {
 	}
 	defer sqlDB.Close()
 
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load(cfg.MessagesPath)
 	if err != nil {
 		return fmt.Errorf("load catalog: %w", err)
 	}
@@ -53,28 +60,87 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("stats service: %w", err)
 	}
+	rewardRegistry, err := rewards.NewService(context.Background(), queries, appCatalog.Rewards, logger)
+	if err != nil {
+		return fmt.Errorf("reward registry: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dedupeService, err := dedupe.NewService(queries, dedupe.DefaultRetention, logger)
+	if err != nil {
+		return fmt.Errorf("dedupe service: %w", err)
+	}
+	go dedupeService.Run(ctx)
+
+	cooldownTracker := cooldowns.NewTracker(queries, logger)
+	if err = cooldownTracker.Load(ctx); err != nil {
+		return fmt.Errorf("load cooldowns: %w", err)
+	}
+
+	duelService, err := duels.NewService(queries)
+	if err != nil {
+		return fmt.Errorf("duel service: %w", err)
+	}
+
+	raidService, err := raids.NewService(queries)
+	if err != nil {
+		return fmt.Errorf("raid service: %w", err)
+	}
+
+	tokenService, err := newTokenService(cfg, queries, logger)
+	if err != nil {
+		return err
+	}
+	if tokenService != nil {
+		go tokenService.Run(ctx)
+	}
 
 	srv := server.NewServer(server.ServerConfig{
-		Port:             cfg.ServerPort,
-		ClientID:         cfg.ClientID,
-		ClientSecret:     cfg.ClientSecret,
-		OAuthRedirectURI: cfg.OAuthRedirectURI,
-		StatsService:     statsService,
-		BlindBoxService:  blindboxService,
-		Series:           appCatalog.Series,
+		Port:              cfg.ServerPort,
+		ClientID:          cfg.ClientID,
+		ClientSecret:      cfg.ClientSecret,
+		OAuthRedirectURI:  cfg.OAuthRedirectURI,
+		BotUserID:         cfg.BotUserID,
+		BroadcasterUserID: cfg.ChannelUserID,
+		AdminUserIDs:      cfg.AdminUserIDs,
+		SessionSecret:     cfg.AdminSessionSecret,
+		Tokens:            tokenService,
+		StatsService:      statsService,
+		BlindBoxService:   blindboxService,
+		RewardRegistry:    rewardRegistry,
+		Series:            appCatalog.Series,
+		Messages:          appCatalog.Messages,
 	}, logger)
 	if err = srv.Start(); err != nil {
 		return fmt.Errorf("start server: %w", err)
 	}
 	defer srv.Stop()
 
-	bot, err := charsibot.New(cfg, logger, statsService, blindboxService, appCatalog.Series, srv.Broadcast)
+	bot, err := charsibot.New(cfg, charsibot.Deps{
+		StatsService:    statsService,
+		BlindBoxService: blindboxService,
+		Dedupe:          dedupeService,
+		Tokens:          tokenService,
+		RewardRegistry:  rewardRegistry,
+		Cooldowns:       cooldownTracker,
+		Duels:           duelService,
+		Raids:           raidService,
+		Series:          appCatalog.Series,
+		Rewards:         appCatalog.Rewards,
+		Triggers:        appCatalog.Triggers,
+		Bosses:          appCatalog.Bosses,
+		Messages:        appCatalog.Messages,
+		Broadcast:       srv.Broadcast,
+	}, logger)
 	if err != nil {
 		return fmt.Errorf("create bot: %w", err)
 	}
 	srv.SetAdminChatMessage(func(message string) {
 		bot.SendMessage(charsibot.SendMessageParams{Message: message})
 	})
+	srv.SetSubscriptionStatus(bot.SubscriptionStatus)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -95,3 +161,28 @@ func run() error {
 	logger.Info("bot shutdown complete")
 	return nil
 }
+
+// newTokenService returns nil when no encryption key is configured, in which
+// case OAuth tokens cannot be stored.
+func newTokenService(cfg charsibot.Config, queries *db.Queries, logger *slog.Logger) (*tokens.Service, error) {
+	if cfg.TokenEncryptionKey == "" {
+		logger.Warn("TOKEN_ENCRYPTION_KEY is not set; OAuth tokens will not be stored")
+		return nil, nil //nolint:nilnil // Token storage is optional.
+	}
+	key, err := tokens.ParseKey(cfg.TokenEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	authClient, err := helix.NewClient(&helix.Options{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create auth helix client: %w", err)
+	}
+	tokenService, err := tokens.NewService(queries, key, authClient, logger)
+	if err != nil {
+		return nil, fmt.Errorf("token service: %w", err)
+	}
+	return tokenService, nil
+}
@@ -0,0 +1,137 @@
+// Package cooldowns tracks how long chat commands stay unavailable after use.
+// Cooldowns live in memory and, when given a database, are persisted so that a
+// restart does not reset them.
+package cooldowns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lukeramljak/charsibot/db"
+)
+
+// sweepInterval is how often expired cooldowns are forgotten.
+const sweepInterval = time.Minute
+
+// Cooldown makes Key unavailable for Duration once acquired.
+type Cooldown struct {
+	Key      string
+	Duration time.Duration
+}
+
+type Tracker struct {
+	queries *db.Queries
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu        sync.Mutex
+	expiresAt map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewTracker returns a Tracker that persists cooldowns through queries, or
+// keeps them in memory only when queries is nil.
+func NewTracker(queries *db.Queries, logger *slog.Logger) *Tracker {
+	return &Tracker{
+		queries:   queries,
+		logger:    logger,
+		now:       time.Now,
+		expiresAt: make(map[string]time.Time),
+	}
+}
+
+// Load restores the persisted cooldowns that have not expired yet.
+func (t *Tracker) Load(ctx context.Context) error {
+	if t.queries == nil {
+		return nil
+	}
+	now := t.now()
+	if _, err := t.queries.DeleteCommandCooldownsBefore(ctx, now); err != nil {
+		return fmt.Errorf("delete expired cooldowns: %w", err)
+	}
+	stored, err := t.queries.ListCommandCooldownsAfter(ctx, now)
+	if err != nil {
+		return fmt.Errorf("load cooldowns: %w", err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, cooldown := range stored {
+		t.expiresAt[cooldown.Key] = cooldown.ExpiresAt
+	}
+	return nil
+}
+
+// Acquire starts every cooldown if none of them is active, and reports true.
+// Otherwise it starts nothing and returns the longest time remaining.
+func (t *Tracker) Acquire(ctx context.Context, cooldowns ...Cooldown) (bool, time.Duration) {
+	now := t.now()
+
+	t.mu.Lock()
+	t.sweep(ctx, now)
+	var remaining time.Duration
+	for _, cooldown := range cooldowns {
+		if left := t.expiresAt[cooldown.Key].Sub(now); left > remaining {
+			remaining = left
+		}
+	}
+	if remaining > 0 {
+		t.mu.Unlock()
+		return false, remaining
+	}
+	started := make([]db.CommandCooldown, 0, len(cooldowns))
+	for _, cooldown := range cooldowns {
+		if cooldown.Duration <= 0 {
+			continue
+		}
+		expiresAt := now.Add(cooldown.Duration)
+		t.expiresAt[cooldown.Key] = expiresAt
+		started = append(started, db.CommandCooldown{Key: cooldown.Key, ExpiresAt: expiresAt})
+	}
+	t.mu.Unlock()
+
+	t.persist(ctx, started)
+	return true, 0
+}
+
+// Remaining returns how long key stays on cooldown.
+func (t *Tracker) Remaining(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return max(0, t.expiresAt[key].Sub(t.now()))
+}
+
+// persist stores started cooldowns. A failure only means they would not
+// survive a restart, so it is logged rather than returned.
+func (t *Tracker) persist(ctx context.Context, started []db.CommandCooldown) {
+	if t.queries == nil {
+		return
+	}
+	for _, cooldown := range started {
+		if err := t.queries.UpsertCommandCooldown(ctx, cooldown); err != nil {
+			t.logger.Error("failed to store cooldown", "err", err, "key", cooldown.Key)
+		}
+	}
+}
+
+// sweep forgets expired cooldowns at most once per sweepInterval. t.mu must
+// be held.
+func (t *Tracker) sweep(ctx context.Context, now time.Time) {
+	if now.Sub(t.lastSweep) < sweepInterval {
+		return
+	}
+	t.lastSweep = now
+	for key, expiresAt := range t.expiresAt {
+		if !expiresAt.After(now) {
+			delete(t.expiresAt, key)
+		}
+	}
+	if t.queries == nil {
+		return
+	}
+	if _, err := t.queries.DeleteCommandCooldownsBefore(ctx, now); err != nil {
+		t.logger.Error("failed to delete expired cooldowns", "err", err)
+	}
+}
@@ -0,0 +1,67 @@
+package cooldowns
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/lukeramljak/charsibot/db"
+)
+
+func newTestTracker(queries *db.Queries, now *time.Time) *Tracker {
+	tracker := NewTracker(queries, slog.New(slog.DiscardHandler))
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func TestAcquireStartsAllCooldownsOrNone(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := newTestTracker(nil, &now)
+	global := Cooldown{Key: "command:stats", Duration: 10 * time.Second}
+	user := Cooldown{Key: "command:stats:user:1", Duration: time.Minute}
+
+	if ok, _ := tracker.Acquire(t.Context(), global, user); !ok {
+		t.Fatal("first use should not be on cooldown")
+	}
+
+	now = now.Add(20 * time.Second)
+	ok, remaining := tracker.Acquire(t.Context(), global, user)
+	if ok || remaining != 40*time.Second {
+		t.Fatalf("Acquire during user cooldown = %v, %v; want false, 40s", ok, remaining)
+	}
+
+	// Another user is only held back by the global cooldown, which has expired.
+	other := Cooldown{Key: "command:stats:user:2", Duration: time.Minute}
+	if ok, _ := tracker.Acquire(t.Context(), global, other); !ok {
+		t.Fatal("another user should not be on cooldown")
+	}
+	if remaining := tracker.Remaining(global.Key); remaining != 10*time.Second {
+		t.Fatalf("global cooldown remaining = %v, want it restarted at 10s", remaining)
+	}
+}
+
+func TestLoadRestoresPersistedCooldowns(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	now := time.Unix(1000, 0)
+
+	tracker := newTestTracker(queries, &now)
+	tracker.Acquire(t.Context(),
+		Cooldown{Key: "short", Duration: time.Second},
+		Cooldown{Key: "long", Duration: time.Hour},
+	)
+
+	now = now.Add(time.Minute)
+	restarted := newTestTracker(queries, &now)
+	if err := restarted.Load(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if remaining := restarted.Remaining("long"); remaining != 59*time.Minute {
+		t.Fatalf("long cooldown remaining after restart = %v, want 59m", remaining)
+	}
+	if remaining := restarted.Remaining("short"); remaining != 0 {
+		t.Fatalf("expired cooldown remaining after restart = %v, want 0", remaining)
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// CommandCooldown is a cooldown that lasts until ExpiresAt.
+type CommandCooldown struct {
+	Key       string
+	ExpiresAt time.Time
+}
+
+func (q *Queries) UpsertCommandCooldown(ctx context.Context, cooldown CommandCooldown) error {
+	_, err := q.db.ExecContext(ctx, `
+INSERT INTO command_cooldowns (key, expires_at) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET expires_at = excluded.expires_at`,
+		cooldown.Key, cooldown.ExpiresAt.UnixMilli())
+	return err
+}
+
+// ListCommandCooldownsAfter returns the cooldowns that expire after the given time.
+func (q *Queries) ListCommandCooldownsAfter(ctx context.Context, after time.Time) ([]CommandCooldown, error) {
+	rows, err := q.db.QueryContext(ctx, `
+SELECT key, expires_at FROM command_cooldowns WHERE expires_at > ? ORDER BY key`, after.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cooldowns := []CommandCooldown{}
+	for rows.Next() {
+		var cooldown CommandCooldown
+		var expiresAt int64
+		if err := rows.Scan(&cooldown.Key, &expiresAt); err != nil {
+			return nil, err
+		}
+		cooldown.ExpiresAt = time.UnixMilli(expiresAt)
+		cooldowns = append(cooldowns, cooldown)
+	}
+	return cooldowns, rows.Err()
+}
+
+func (q *Queries) DeleteCommandCooldownsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM command_cooldowns WHERE expires_at <= ?`, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
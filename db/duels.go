@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Duel is a finished duel. Stat is empty for a duel of all stats, and Wager
+// is the amount of Stat the loser paid the winner.
+type Duel struct {
+	ChallengerID   string
+	ChallengerName string
+	OpponentID     string
+	OpponentName   string
+	WinnerID       string
+	Stat           string
+	Wager          int64
+	FoughtAt       time.Time
+}
+
+// DuelStanding is a viewer's duel record. Username is the name they last
+// duelled under.
+type DuelStanding struct {
+	UserID   string
+	Username string
+	Wins     int64
+	Losses   int64
+}
+
+func (q *Queries) InsertDuel(ctx context.Context, duel Duel) error {
+	_, err := q.db.ExecContext(ctx, `
+INSERT INTO duels (challenger_id, challenger_name, opponent_id, opponent_name, winner_id, stat, wager, fought_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		duel.ChallengerID,
+		duel.ChallengerName,
+		duel.OpponentID,
+		duel.OpponentName,
+		duel.WinnerID,
+		duel.Stat,
+		duel.Wager,
+		duel.FoughtAt.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// duelStandingsQuery counts each viewer's wins and losses from both sides of
+// their duels. SQLite takes the bare username column from the row holding
+// MAX(fought_at), which is the viewer's latest name.
+const duelStandingsQuery = `
+SELECT user_id, username, SUM(won), SUM(1 - won), MAX(fought_at)
+FROM (
+  SELECT challenger_id AS user_id, challenger_name AS username, winner_id = challenger_id AS won, fought_at
+  FROM duels
+  UNION ALL
+  SELECT opponent_id, opponent_name, winner_id = opponent_id, fought_at
+  FROM duels
+)`
+
+// ListDuelStandings returns the viewers with the most duel wins, breaking ties
+// by fewer losses.
+func (q *Queries) ListDuelStandings(ctx context.Context, limit int) ([]DuelStanding, error) {
+	rows, err := q.db.QueryContext(ctx, duelStandingsQuery+`
+GROUP BY user_id
+ORDER BY SUM(won) DESC, SUM(1 - won) ASC, user_id
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	standings := []DuelStanding{}
+	for rows.Next() {
+		var standing DuelStanding
+		var foughtAt string
+		if err := rows.Scan(
+			&standing.UserID, &standing.Username, &standing.Wins, &standing.Losses, &foughtAt,
+		); err != nil {
+			return nil, err
+		}
+		standings = append(standings, standing)
+	}
+	return standings, rows.Err()
+}
+
+// GetDuelStanding returns a viewer's duel record, which is empty when they
+// have never duelled.
+func (q *Queries) GetDuelStanding(ctx context.Context, userID string) (DuelStanding, error) {
+	standing := DuelStanding{UserID: userID}
+	var foughtAt string
+	err := q.db.QueryRowContext(ctx, duelStandingsQuery+`
+WHERE user_id = ?
+GROUP BY user_id`, userID).Scan(&standing.UserID, &standing.Username, &standing.Wins, &standing.Losses, &foughtAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DuelStanding{UserID: userID}, nil
+	}
+	if err != nil {
+		return DuelStanding{}, err
+	}
+	return standing, nil
+}
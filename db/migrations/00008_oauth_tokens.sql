@@ -0,0 +1,13 @@
+-- +goose Up
+-- Access and refresh tokens are encrypted by the application before storage.
+CREATE TABLE oauth_tokens (
+  account       TEXT PRIMARY KEY,
+  user_id       TEXT NOT NULL,
+  login         TEXT NOT NULL,
+  access_token  BLOB NOT NULL,
+  refresh_token BLOB NOT NULL,
+  scopes        TEXT NOT NULL,
+  expires_at    TEXT NOT NULL,
+  validated_at  TEXT,
+  updated_at    TEXT NOT NULL
+);
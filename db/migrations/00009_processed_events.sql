@@ -0,0 +1,15 @@
+-- +goose Up
+-- EventSub delivers at least once; rows here mark notifications, chat messages
+-- and redemptions that have already been handled. processed_at is unix
+-- milliseconds. outcome is what a redemption still owes on Twitch: the status
+-- to give it, or IN_PROGRESS while its handler runs. It is kept until settled,
+-- so a retry repeats the status update and never the handler.
+CREATE TABLE processed_events (
+  kind         TEXT NOT NULL,
+  id           TEXT NOT NULL,
+  processed_at INTEGER NOT NULL,
+  outcome      TEXT,
+  PRIMARY KEY (kind, id)
+);
+
+CREATE INDEX processed_events_processed_at_idx ON processed_events(processed_at);
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Maps Twitch channel point reward IDs to the handler a redemption runs, so
+-- renaming a reward on Twitch does not break it.
+CREATE TABLE reward_handlers (
+  reward_id   TEXT PRIMARY KEY,
+  handler_key TEXT NOT NULL,
+  title       TEXT NOT NULL,
+  updated_at  TEXT NOT NULL
+);
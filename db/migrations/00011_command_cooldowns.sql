@@ -0,0 +1,7 @@
+-- +goose Up
+-- Active chat command cooldowns, so a restart does not reset them. expires_at
+-- is unix milliseconds.
+CREATE TABLE command_cooldowns (
+  key        TEXT PRIMARY KEY,
+  expires_at INTEGER NOT NULL
+);
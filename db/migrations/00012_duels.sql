@@ -0,0 +1,17 @@
+-- +goose Up
+-- Finished duels between viewers. Names are kept as they were at the time so
+-- the leaderboard does not need the stats tables.
+CREATE TABLE duels (
+  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+  challenger_id   TEXT NOT NULL,
+  challenger_name TEXT NOT NULL,
+  opponent_id     TEXT NOT NULL,
+  opponent_name   TEXT NOT NULL,
+  winner_id       TEXT NOT NULL,
+  stat            TEXT NOT NULL DEFAULT '',
+  wager           INTEGER NOT NULL DEFAULT 0,
+  fought_at       TEXT NOT NULL
+);
+
+CREATE INDEX duels_challenger_id ON duels (challenger_id);
+CREATE INDEX duels_opponent_id ON duels (opponent_id);
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Finished boss raids, and how each chatter who joined fared. Names are kept
+-- as they were at the time.
+CREATE TABLE raids (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  boss       TEXT NOT NULL,
+  started_by TEXT NOT NULL,
+  max_hp     INTEGER NOT NULL,
+  hp_left    INTEGER NOT NULL,
+  rounds     INTEGER NOT NULL,
+  victory    INTEGER NOT NULL,
+  fought_at  TEXT NOT NULL
+);
+
+CREATE TABLE raid_fighters (
+  raid_id  INTEGER NOT NULL REFERENCES raids (id) ON DELETE CASCADE,
+  user_id  TEXT NOT NULL,
+  username TEXT NOT NULL,
+  damage   INTEGER NOT NULL,
+  survived INTEGER NOT NULL,
+  reward   TEXT NOT NULL DEFAULT '',
+  PRIMARY KEY (raid_id, user_id)
+);
+
+CREATE INDEX raid_fighters_user_id ON raid_fighters (user_id);
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OAuthToken is a stored Twitch user token. AccessToken and RefreshToken hold
+// ciphertext; callers are responsible for encryption.
+type OAuthToken struct {
+	Account      string
+	UserID       string
+	Login        string
+	AccessToken  []byte
+	RefreshToken []byte
+	Scopes       []string
+	ExpiresAt    time.Time
+	ValidatedAt  *time.Time
+	UpdatedAt    time.Time
+}
+
+func (q *Queries) UpsertOAuthToken(ctx context.Context, token OAuthToken) error {
+	_, err := q.db.ExecContext(ctx, `
+INSERT INTO oauth_tokens (account, user_id, login, access_token, refresh_token, scopes, expires_at, validated_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(account) DO UPDATE SET
+  user_id = excluded.user_id,
+  login = excluded.login,
+  access_token = excluded.access_token,
+  refresh_token = excluded.refresh_token,
+  scopes = excluded.scopes,
+  expires_at = excluded.expires_at,
+  validated_at = excluded.validated_at,
+  updated_at = excluded.updated_at`,
+		token.Account,
+		token.UserID,
+		token.Login,
+		token.AccessToken,
+		token.RefreshToken,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt.UTC().Format(time.RFC3339Nano),
+		formatNullTime(token.ValidatedAt),
+		token.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+func (q *Queries) GetOAuthToken(ctx context.Context, account string) (OAuthToken, error) {
+	row := q.db.QueryRowContext(ctx, `
+SELECT account, user_id, login, access_token, refresh_token, scopes, expires_at, validated_at, updated_at
+FROM oauth_tokens WHERE account = ?`, account)
+	return scanOAuthToken(row)
+}
+
+func (q *Queries) ListOAuthTokens(ctx context.Context) ([]OAuthToken, error) {
+	rows, err := q.db.QueryContext(ctx, `
+SELECT account, user_id, login, access_token, refresh_token, scopes, expires_at, validated_at, updated_at
+FROM oauth_tokens ORDER BY account`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tokens := []OAuthToken{}
+	for rows.Next() {
+		token, err := scanOAuthToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOAuthToken(row rowScanner) (OAuthToken, error) {
+	var token OAuthToken
+	var scopes, expiresAt, updatedAt string
+	var validatedAt sql.NullString
+	if err := row.Scan(
+		&token.Account,
+		&token.UserID,
+		&token.Login,
+		&token.AccessToken,
+		&token.RefreshToken,
+		&scopes,
+		&expiresAt,
+		&validatedAt,
+		&updatedAt,
+	); err != nil {
+		return OAuthToken{}, err
+	}
+	token.Scopes = strings.Fields(scopes)
+	var err error
+	if token.ExpiresAt, err = time.Parse(time.RFC3339Nano, expiresAt); err != nil {
+		return OAuthToken{}, fmt.Errorf("parse token expiry: %w", err)
+	}
+	if token.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+		return OAuthToken{}, fmt.Errorf("parse token update time: %w", err)
+	}
+	if validatedAt.Valid {
+		value, err := time.Parse(time.RFC3339Nano, validatedAt.String)
+		if err != nil {
+			return OAuthToken{}, fmt.Errorf("parse token validation time: %w", err)
+		}
+		token.ValidatedAt = &value
+	}
+	return token, nil
+}
+
+func formatNullTime(value *time.Time) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: value.UTC().Format(time.RFC3339Nano), Valid: true}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ClaimProcessedEvent records that the event identified by kind and id has been
+// handled, with the outcome it still owes, if any. It reports false if the
+// event was already recorded.
+func (q *Queries) ClaimProcessedEvent(ctx context.Context, kind, id, outcome string, at time.Time) (bool, error) {
+	result, err := q.db.ExecContext(ctx, `
+INSERT INTO processed_events (kind, id, processed_at, outcome) VALUES (?, ?, ?, NULLIF(?, ''))
+ON CONFLICT(kind, id) DO NOTHING`, kind, id, at.UnixMilli(), outcome)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+// SetProcessedEventOutcome stores the outcome still owed for a processed event.
+// An empty outcome clears it.
+func (q *Queries) SetProcessedEventOutcome(ctx context.Context, kind, id, outcome string) error {
+	_, err := q.db.ExecContext(ctx, `
+UPDATE processed_events SET outcome = NULLIF(?, '') WHERE kind = ? AND id = ?`, outcome, kind, id)
+	return err
+}
+
+// GetProcessedEventOutcome returns the outcome still owed for a processed
+// event, or an empty string when there is none.
+func (q *Queries) GetProcessedEventOutcome(ctx context.Context, kind, id string) (string, error) {
+	var outcome sql.NullString
+	err := q.db.QueryRowContext(ctx, `
+SELECT outcome FROM processed_events WHERE kind = ? AND id = ?`, kind, id).Scan(&outcome)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return outcome.String, err
+}
+
+// DeleteProcessedEventsBefore forgets events processed before the given time,
+// keeping those that still owe an outcome.
+func (q *Queries) DeleteProcessedEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, `
+DELETE FROM processed_events WHERE processed_at < ? AND outcome IS NULL`, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Raid is a finished boss raid. Boss is the catalog key of the boss, and
+// HPLeft is zero when the fighters won.
+type Raid struct {
+	Boss      string
+	StartedBy string
+	MaxHP     int64
+	HPLeft    int64
+	Rounds    int64
+	Victory   bool
+	FoughtAt  time.Time
+}
+
+// RaidFighter is how one chatter fared in a raid. Reward describes what they
+// won, and is empty when they won nothing.
+type RaidFighter struct {
+	UserID   string
+	Username string
+	Damage   int64
+	Survived bool
+	Reward   string
+}
+
+// InsertRaid stores a raid and returns its ID.
+func (q *Queries) InsertRaid(ctx context.Context, raid Raid) (int64, error) {
+	result, err := q.db.ExecContext(ctx, `
+INSERT INTO raids (boss, started_by, max_hp, hp_left, rounds, victory, fought_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		raid.Boss,
+		raid.StartedBy,
+		raid.MaxHP,
+		raid.HPLeft,
+		raid.Rounds,
+		raid.Victory,
+		raid.FoughtAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// InsertRaidFighters stores the fighters of a raid in one statement.
+func (q *Queries) InsertRaidFighters(ctx context.Context, raidID int64, fighters []RaidFighter) error {
+	if len(fighters) == 0 {
+		return nil
+	}
+	values := make([]string, len(fighters))
+	args := make([]any, 0, len(fighters)*6)
+	for i, fighter := range fighters {
+		values[i] = "(?, ?, ?, ?, ?, ?)"
+		args = append(args, raidID, fighter.UserID, fighter.Username, fighter.Damage, fighter.Survived, fighter.Reward)
+	}
+	_, err := q.db.ExecContext(ctx, `
+INSERT INTO raid_fighters (raid_id, user_id, username, damage, survived, reward)
+VALUES `+strings.Join(values, ", "), args...)
+	return err
+}
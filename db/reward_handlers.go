@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RewardHandler registers the handler that runs for a channel point reward.
+// Title is the reward's title when it was registered, for display only.
+type RewardHandler struct {
+	RewardID   string
+	HandlerKey string
+	Title      string
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) UpsertRewardHandler(ctx context.Context, handler RewardHandler) error {
+	_, err := q.db.ExecContext(ctx, `
+INSERT INTO reward_handlers (reward_id, handler_key, title, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(reward_id) DO UPDATE SET
+  handler_key = excluded.handler_key,
+  title = excluded.title,
+  updated_at = excluded.updated_at`,
+		handler.RewardID,
+		handler.HandlerKey,
+		handler.Title,
+		handler.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+func (q *Queries) ListRewardHandlers(ctx context.Context) ([]RewardHandler, error) {
+	rows, err := q.db.QueryContext(ctx, `
+SELECT reward_id, handler_key, title, updated_at FROM reward_handlers ORDER BY handler_key, reward_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	handlers := []RewardHandler{}
+	for rows.Next() {
+		var handler RewardHandler
+		var updatedAt string
+		if err := rows.Scan(&handler.RewardID, &handler.HandlerKey, &handler.Title, &updatedAt); err != nil {
+			return nil, err
+		}
+		if handler.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+			return nil, fmt.Errorf("parse reward handler update time: %w", err)
+		}
+		handlers = append(handlers, handler)
+	}
+	return handlers, rows.Err()
+}
+
+// DeleteRewardHandler reports whether a registration was removed.
+func (q *Queries) DeleteRewardHandler(ctx context.Context, rewardID string) (bool, error) {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM reward_handlers WHERE reward_id = ?`, rewardID)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
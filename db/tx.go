@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// InTx runs fn with Queries that write in one transaction, which is committed
+// when fn succeeds and rolled back otherwise. q must have been created from a
+// *sql.DB rather than from another transaction.
+func (q *Queries) InTx(ctx context.Context, fn func(*Queries) error) error {
+	sqlDB, ok := q.db.(*sql.DB)
+	if !ok {
+		return errors.New("queries cannot begin a transaction")
+	}
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	if err := fn(q.WithTx(tx)); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package dedupe records which EventSub deliveries have been handled so that
+// Twitch's at-least-once redelivery never runs a handler twice.
+package dedupe
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lukeramljak/charsibot/db"
+)
+
+// Kind namespaces the IDs being de-duplicated.
+type Kind string
+
+const (
+	// KindNotification is an EventSub notification message ID.
+	KindNotification Kind = "eventsub_notification"
+	// KindChatMessage is a chat message ID.
+	KindChatMessage Kind = "chat_message"
+	// KindRedemption is a channel point redemption ID.
+	KindRedemption Kind = "redemption"
+)
+
+const (
+	// DefaultRetention is how long processed IDs are remembered.
+	DefaultRetention = 7 * 24 * time.Hour
+	// pruneInterval is how often Run deletes IDs older than the retention window.
+	pruneInterval = time.Hour
+)
+
+type Service struct {
+	queries   *db.Queries
+	retention time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// NewService creates a Service that remembers processed IDs for retention.
+func NewService(queries *db.Queries, retention time.Duration, logger *slog.Logger) (*Service, error) {
+	if queries == nil {
+		return nil, errors.New("queries must not be nil")
+	}
+	if retention <= 0 {
+		return nil, errors.New("retention must be positive")
+	}
+	return &Service{queries: queries, retention: retention, logger: logger, now: time.Now}, nil
+}
+
+// Claim records id as processed and reports whether this is its first
+// delivery. Callers should skip the event when it returns false.
+func (s *Service) Claim(ctx context.Context, kind Kind, id string) (bool, error) {
+	return s.ClaimWithOutcome(ctx, kind, id, "")
+}
+
+// ClaimWithOutcome is Claim, but also sets the outcome id owes in the same
+// write, so a claim is never left without it.
+func (s *Service) ClaimWithOutcome(ctx context.Context, kind Kind, id, outcome string) (bool, error) {
+	if id == "" {
+		return true, nil
+	}
+	return s.queries.ClaimProcessedEvent(ctx, string(kind), id, outcome, s.now())
+}
+
+// SetOutcome remembers the outcome that is still to be applied for a claimed
+// id, such as the status a redemption has to be given on Twitch. An id with an
+// outcome is kept past the retention window until SetOutcome clears it with
+// an empty outcome.
+func (s *Service) SetOutcome(ctx context.Context, kind Kind, id, outcome string) error {
+	return s.queries.SetProcessedEventOutcome(ctx, string(kind), id, outcome)
+}
+
+// Outcome returns the outcome still to be applied for id, or an empty string
+// when there is none.
+func (s *Service) Outcome(ctx context.Context, kind Kind, id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	return s.queries.GetProcessedEventOutcome(ctx, string(kind), id)
+}
+
+// Prune forgets IDs processed before the retention window, except those that
+// still have an outcome to apply.
+func (s *Service) Prune(ctx context.Context) (int64, error) {
+	return s.queries.DeleteProcessedEventsBefore(ctx, s.now().Add(-s.retention))
+}
+
+// Run prunes expired IDs periodically until ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		s.prune(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) prune(ctx context.Context) {
+	deleted, err := s.Prune(ctx)
+	if err != nil {
+		s.logger.Error("prune processed events", "err", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Debug("pruned processed events", "count", deleted)
+	}
+}
@@ -0,0 +1,104 @@
+package dedupe_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/dedupe"
+)
+
+func newService(t *testing.T, retention time.Duration) *dedupe.Service {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	service, err := dedupe.NewService(queries, retention, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestClaimReportsFirstDeliveryOnly(t *testing.T) {
+	service := newService(t, dedupe.DefaultRetention)
+
+	first, err := service.Claim(t.Context(), dedupe.KindRedemption, "redemption-1")
+	if err != nil || !first {
+		t.Fatalf("first claim = %v, %v; want true", first, err)
+	}
+	again, err := service.Claim(t.Context(), dedupe.KindRedemption, "redemption-1")
+	if err != nil || again {
+		t.Fatalf("repeat claim = %v, %v; want false", again, err)
+	}
+	// The same ID under another kind is a different event.
+	other, err := service.Claim(t.Context(), dedupe.KindChatMessage, "redemption-1")
+	if err != nil || !other {
+		t.Fatalf("claim under another kind = %v, %v; want true", other, err)
+	}
+}
+
+func TestPruneForgetsExpiredIDs(t *testing.T) {
+	service := newService(t, time.Nanosecond)
+
+	if _, err := service.Claim(t.Context(), dedupe.KindNotification, "message-1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	deleted, err := service.Prune(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", deleted)
+	}
+	first, err := service.Claim(t.Context(), dedupe.KindNotification, "message-1")
+	if err != nil || !first {
+		t.Fatalf("claim after prune = %v, %v; want true", first, err)
+	}
+}
+
+func TestPruneKeepsIDsWithAnOutcome(t *testing.T) {
+	service := newService(t, time.Nanosecond)
+
+	if _, err := service.Claim(t.Context(), dedupe.KindRedemption, "redemption-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetOutcome(t.Context(), dedupe.KindRedemption, "redemption-1", "FULFILLED"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if deleted, err := service.Prune(t.Context()); err != nil || deleted != 0 {
+		t.Fatalf("Prune() = %d, %v; want an ID with an outcome kept", deleted, err)
+	}
+	outcome, err := service.Outcome(t.Context(), dedupe.KindRedemption, "redemption-1")
+	if err != nil || outcome != "FULFILLED" {
+		t.Fatalf("Outcome() = %q, %v; want FULFILLED", outcome, err)
+	}
+
+	if err := service.SetOutcome(t.Context(), dedupe.KindRedemption, "redemption-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, err := service.Prune(t.Context()); err != nil || deleted != 1 {
+		t.Fatalf("Prune() = %d, %v; want the cleared ID forgotten", deleted, err)
+	}
+}
+
+func TestClaimWithOutcome(t *testing.T) {
+	service := newService(t, dedupe.DefaultRetention)
+
+	first, err := service.ClaimWithOutcome(t.Context(), dedupe.KindRedemption, "redemption-1", "IN_PROGRESS")
+	if err != nil || !first {
+		t.Fatalf("ClaimWithOutcome() = %v, %v; want the first claim to succeed", first, err)
+	}
+	again, err := service.ClaimWithOutcome(t.Context(), dedupe.KindRedemption, "redemption-1", "OTHER")
+	if err != nil || again {
+		t.Fatalf("ClaimWithOutcome() = %v, %v; want a repeated claim rejected", again, err)
+	}
+	outcome, err := service.Outcome(t.Context(), dedupe.KindRedemption, "redemption-1")
+	if err != nil || outcome != "IN_PROGRESS" {
+		t.Fatalf("Outcome() = %q, %v; want the first claim's outcome kept", outcome, err)
+	}
+}
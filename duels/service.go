@@ -0,0 +1,172 @@
+// Package duels runs stat-based duels between viewers: challenges that wait
+// for the opponent to answer, resolution weighted by stats, and a persisted
+// record of every duel fought.
+package duels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+const (
+	// Timeout is how long a challenged viewer has to accept or decline.
+	Timeout = time.Minute
+	// baseWeight keeps an outmatched viewer's chance of winning above zero.
+	baseWeight = 5
+)
+
+// ErrBusy is returned when either viewer already has a duel waiting for an
+// answer.
+var ErrBusy = errors.New("already in a pending duel")
+
+// Challenge is a duel waiting for the opponent to accept or decline.
+type Challenge struct {
+	ChallengerID   string
+	ChallengerName string
+	OpponentID     string
+	OpponentName   string
+	// Stat is the stat the duel is fought with, or empty for all stats.
+	Stat string
+	// Wager is how much of Stat the loser pays the winner.
+	Wager int64
+}
+
+// Standing is a viewer's duel record.
+type Standing struct {
+	UserID   string
+	Username string
+	Wins     int64
+	Losses   int64
+}
+
+// Service holds the challenges waiting for an answer and records the duels
+// fought.
+type Service struct {
+	queries *db.Queries
+	now     func() time.Time
+
+	mu sync.Mutex
+	// pending holds the challenges waiting for an answer, by opponent ID.
+	pending map[string]*Challenge
+}
+
+// NewService creates a Service that records duels through queries.
+func NewService(queries *db.Queries) (*Service, error) {
+	if queries == nil {
+		return nil, errors.New("queries must not be nil")
+	}
+	return &Service{queries: queries, now: time.Now, pending: make(map[string]*Challenge)}, nil
+}
+
+// Challenge makes c wait for the opponent's answer. A viewer can only be in
+// one pending duel at a time, on either side. The returned challenge is the
+// one to pass to Expire.
+func (s *Service) Challenge(c Challenge) (*Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pending := range s.pending {
+		for _, id := range []string{c.ChallengerID, c.OpponentID} {
+			if id == pending.ChallengerID || id == pending.OpponentID {
+				return nil, ErrBusy
+			}
+		}
+	}
+	challenge := &c
+	s.pending[c.OpponentID] = challenge
+	return challenge, nil
+}
+
+// Answer removes and returns the challenge waiting for opponentID to answer.
+func (s *Service) Answer(opponentID string) (Challenge, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	challenge, ok := s.pending[opponentID]
+	if !ok {
+		return Challenge{}, false
+	}
+	delete(s.pending, opponentID)
+	return *challenge, true
+}
+
+// Expire removes challenge if it is still waiting for an answer, and reports
+// whether it was.
+func (s *Service) Expire(challenge *Challenge) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[challenge.OpponentID] != challenge {
+		return false
+	}
+	delete(s.pending, challenge.OpponentID)
+	return true
+}
+
+// Strength is what a viewer brings to a duel: the value of stat, or the sum
+// of all their stats when stat is empty.
+func Strength(userStats []stats.UserStat, stat string) int64 {
+	var strength int64
+	for _, userStat := range userStats {
+		if stat == "" || userStat.Name == stat {
+			strength += userStat.Value
+		}
+	}
+	return strength
+}
+
+// ChallengerWins decides a duel at random. Each side wins with a chance
+// proportional to its strength, floored at zero, plus a base weight, so
+// evenly matched viewers have even odds and the underdog always has a chance.
+func ChallengerWins(challengerStrength, opponentStrength int64) bool {
+	challenger := max(challengerStrength, 0) + baseWeight
+	opponent := max(opponentStrength, 0) + baseWeight
+	return rand.Int64N(challenger+opponent) < challenger
+}
+
+// Record stores a finished duel.
+func (s *Service) Record(ctx context.Context, c Challenge, challengerWon bool) error {
+	winnerID := c.OpponentID
+	if challengerWon {
+		winnerID = c.ChallengerID
+	}
+	if err := s.queries.InsertDuel(ctx, db.Duel{
+		ChallengerID:   c.ChallengerID,
+		ChallengerName: c.ChallengerName,
+		OpponentID:     c.OpponentID,
+		OpponentName:   c.OpponentName,
+		WinnerID:       winnerID,
+		Stat:           c.Stat,
+		Wager:          c.Wager,
+		FoughtAt:       s.now(),
+	}); err != nil {
+		return fmt.Errorf("insert duel: %w", err)
+	}
+	return nil
+}
+
+// Leaderboard returns up to limit viewers with the most duel wins.
+func (s *Service) Leaderboard(ctx context.Context, limit int) ([]Standing, error) {
+	rows, err := s.queries.ListDuelStandings(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	standings := make([]Standing, len(rows))
+	for i, row := range rows {
+		standings[i] = Standing(row)
+	}
+	return standings, nil
+}
+
+// Standing returns a viewer's duel record.
+func (s *Service) Standing(ctx context.Context, userID string) (Standing, error) {
+	row, err := s.queries.GetDuelStanding(ctx, userID)
+	if err != nil {
+		return Standing{}, err
+	}
+	return Standing(row), nil
+}
@@ -0,0 +1,139 @@
+package duels_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/duels"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+func newService(t *testing.T) *duels.Service {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	service, err := duels.NewService(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestChallengeAllowsOnePendingDuelPerViewer(t *testing.T) {
+	service := newService(t)
+
+	first, err := service.Challenge(duels.Challenge{ChallengerID: "u1", OpponentID: "u2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []duels.Challenge{
+		{ChallengerID: "u3", OpponentID: "u1"},
+		{ChallengerID: "u2", OpponentID: "u3"},
+	} {
+		if _, err := service.Challenge(c); !errors.Is(err, duels.ErrBusy) {
+			t.Fatalf("Challenge(%+v) = %v, want ErrBusy", c, err)
+		}
+	}
+
+	if _, ok := service.Answer("u1"); ok {
+		t.Fatal("the challenger must not be able to answer their own duel")
+	}
+	if answered, ok := service.Answer("u2"); !ok || answered != *first {
+		t.Fatalf("Answer() = %+v, %v", answered, ok)
+	}
+	if service.Expire(first) {
+		t.Fatal("an answered duel must not expire")
+	}
+	if _, err := service.Challenge(duels.Challenge{ChallengerID: "u3", OpponentID: "u1"}); err != nil {
+		t.Fatalf("Challenge() after the answer = %v", err)
+	}
+}
+
+func TestExpireRemovesOnlyThatChallenge(t *testing.T) {
+	service := newService(t)
+
+	first, _ := service.Challenge(duels.Challenge{ChallengerID: "u1", OpponentID: "u2"})
+	if !service.Expire(first) {
+		t.Fatal("expected the pending duel to expire")
+	}
+	second, _ := service.Challenge(duels.Challenge{ChallengerID: "u3", OpponentID: "u2"})
+	// The first duel's timer firing late must not cancel the second.
+	if service.Expire(first) {
+		t.Fatal("an expired duel must not expire twice")
+	}
+	if answered, ok := service.Answer("u2"); !ok || answered != *second {
+		t.Fatalf("Answer() = %+v, %v", answered, ok)
+	}
+}
+
+func TestStrength(t *testing.T) {
+	userStats := []stats.UserStat{{Name: "strength", Value: 7}, {Name: "luck", Value: -2}}
+	if got := duels.Strength(userStats, "strength"); got != 7 {
+		t.Errorf("Strength(strength) = %d, want 7", got)
+	}
+	if got := duels.Strength(userStats, ""); got != 5 {
+		t.Errorf("Strength(all) = %d, want 5", got)
+	}
+}
+
+func TestChallengerWinsFavoursTheStronger(t *testing.T) {
+	for range 100 {
+		if !duels.ChallengerWins(1<<40, 0) {
+			t.Fatal("a vastly stronger challenger lost")
+		}
+		if duels.ChallengerWins(-100, 1<<40) {
+			t.Fatal("a vastly weaker challenger won")
+		}
+	}
+}
+
+func TestLeaderboardAndStanding(t *testing.T) {
+	service := newService(t)
+	ctx := t.Context()
+
+	for _, fight := range []struct {
+		challenger, opponent string
+		challengerWon        bool
+	}{
+		{"u1", "u2", true},
+		{"u2", "u1", false},
+		{"u3", "u2", true},
+		{"u2", "u3", true},
+	} {
+		c := duels.Challenge{
+			ChallengerID:   fight.challenger,
+			ChallengerName: "name-" + fight.challenger,
+			OpponentID:     fight.opponent,
+			OpponentName:   "name-" + fight.opponent,
+		}
+		if err := service.Record(ctx, c, fight.challengerWon); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	leaderboard, err := service.Leaderboard(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []duels.Standing{
+		{UserID: "u1", Username: "name-u1", Wins: 2, Losses: 0},
+		{UserID: "u3", Username: "name-u3", Wins: 1, Losses: 1},
+	}
+	if !slices.Equal(leaderboard, want) {
+		t.Fatalf("Leaderboard() = %+v, want %+v", leaderboard, want)
+	}
+
+	// u2 ties u3 on wins but has more losses.
+	standing, err := service.Standing(ctx, "u2")
+	if err != nil || standing.Wins != 1 || standing.Losses != 3 {
+		t.Fatalf("Standing(u2) = %+v, %v", standing, err)
+	}
+	standing, err = service.Standing(ctx, "u4")
+	if err != nil || standing != (duels.Standing{UserID: "u4"}) {
+		t.Fatalf("Standing(u4) = %+v, %v", standing, err)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/lukeramljak/charsibot
 go 1.25.5
 
 require (
+	github.com/coder/websocket v1.8.14
 	github.com/danielgtaylor/huma/v2 v2.39.1
 	github.com/joeyak/go-twitch-eventsub/v3 v3.0.1
 	github.com/nicklaw5/helix/v2 v2.34.0
@@ -11,7 +12,6 @@ require (
 )
 
 require (
-	github.com/coder/websocket v1.8.14 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/golang-jwt/jwt/v4 v4.5.2 // indirect
 	github.com/google/uuid v1.6.0 // indirect
@@ -0,0 +1,75 @@
+// Package raids records the outcome of boss raids, in which chatters fight a
+// catalog boss together.
+package raids
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lukeramljak/charsibot/db"
+)
+
+// Result is how a raid ended.
+type Result struct {
+	// Boss is the catalog key of the boss, and StartedBy the moderator who
+	// summoned it.
+	Boss      string
+	StartedBy string
+	MaxHP     int64
+	HPLeft    int64
+	Rounds    int
+	Victory   bool
+	Fighters  []Fighter
+}
+
+// Fighter is how one chatter fared in a raid.
+type Fighter struct {
+	UserID   string
+	Username string
+	Damage   int64
+	Survived bool
+	// Reward describes what the fighter won, like "+1 STR", or is empty.
+	Reward string
+}
+
+// Service records finished raids.
+type Service struct {
+	queries *db.Queries
+	now     func() time.Time
+}
+
+// NewService creates a Service that records raids through queries.
+func NewService(queries *db.Queries) (*Service, error) {
+	if queries == nil {
+		return nil, errors.New("queries must not be nil")
+	}
+	return &Service{queries: queries, now: time.Now}, nil
+}
+
+// Record stores a finished raid and its fighters in one transaction.
+func (s *Service) Record(ctx context.Context, result Result) error {
+	fighters := make([]db.RaidFighter, len(result.Fighters))
+	for i, fighter := range result.Fighters {
+		fighters[i] = db.RaidFighter(fighter)
+	}
+	return s.queries.InTx(ctx, func(q *db.Queries) error {
+		raidID, err := q.InsertRaid(ctx, db.Raid{
+			Boss:      result.Boss,
+			StartedBy: result.StartedBy,
+			MaxHP:     result.MaxHP,
+			HPLeft:    result.HPLeft,
+			Rounds:    int64(result.Rounds),
+			Victory:   result.Victory,
+			FoughtAt:  s.now(),
+		})
+		if err != nil {
+			return fmt.Errorf("insert raid: %w", err)
+		}
+		if err := q.InsertRaidFighters(ctx, raidID, fighters); err != nil {
+			return fmt.Errorf("insert raid fighters: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,94 @@
+package raids_test
+
+import (
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/raids"
+)
+
+func TestRecordStoresTheRaidAndItsFighters(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	service, err := raids.NewService(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = service.Record(t.Context(), raids.Result{
+		Boss:      "goblin-king",
+		StartedBy: "Mod",
+		MaxHP:     30,
+		Rounds:    3,
+		Victory:   true,
+		Fighters: []raids.Fighter{
+			{UserID: "u1", Username: "Alice", Damage: 20, Survived: true, Reward: "+1 STR"},
+			{UserID: "u2", Username: "Bob", Damage: 10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var boss string
+	var victory bool
+	if err := sqlDB.QueryRowContext(t.Context(), `SELECT boss, victory FROM raids`).Scan(&boss, &victory); err != nil {
+		t.Fatal(err)
+	}
+	if boss != "goblin-king" || !victory {
+		t.Fatalf("raid = %q, %v", boss, victory)
+	}
+	var damage, survivors int64
+	if err := sqlDB.QueryRowContext(t.Context(),
+		`SELECT SUM(damage), SUM(survived) FROM raid_fighters`).Scan(&damage, &survivors); err != nil {
+		t.Fatal(err)
+	}
+	if damage != 30 || survivors != 1 {
+		t.Fatalf("fighters dealt %d damage with %d survivors, want 30 and 1", damage, survivors)
+	}
+}
+
+func TestRecordWithoutFighters(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	service, err := raids.NewService(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Record(t.Context(), raids.Result{Boss: "goblin-king", StartedBy: "Mod", MaxHP: 10}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordLeavesNoRaidWhenItsFightersFail(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	service, err := raids.NewService(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.ExecContext(t.Context(), `
+CREATE TRIGGER refuse_fighters BEFORE INSERT ON raid_fighters
+BEGIN SELECT RAISE(ABORT, 'refused'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	err = service.Record(t.Context(), raids.Result{
+		Boss:      "goblin-king",
+		StartedBy: "Mod",
+		MaxHP:     10,
+		Fighters:  []raids.Fighter{{UserID: "u1", Username: "Alice", Damage: 10}},
+	})
+	if err == nil {
+		t.Fatal("Record() = nil, want the refused fighters to fail it")
+	}
+	var raidCount int
+	if err := sqlDB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM raids`).Scan(&raidCount); err != nil {
+		t.Fatal(err)
+	}
+	if raidCount != 0 {
+		t.Fatalf("raids = %d, want the raid rolled back with its fighters", raidCount)
+	}
+}
@@ -0,0 +1,215 @@
+// Package rewards keeps the registry that maps Twitch channel point reward IDs
+// to the redemption handler each one runs, so renaming a reward on Twitch does
+// not break it.
+package rewards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+)
+
+var (
+	// ErrUnknownHandler is returned when registering a handler key that is not
+	// in the catalog.
+	ErrUnknownHandler = errors.New("unknown reward handler")
+	// ErrNotFound is returned when a reward ID is not registered.
+	ErrNotFound = errors.New("reward not registered")
+)
+
+// Registration ties a Twitch reward ID to the handler its redemptions run.
+type Registration struct {
+	RewardID   string    `json:"rewardId"`
+	HandlerKey string    `json:"handlerKey"`
+	Title      string    `json:"title"      doc:"Reward title when it was registered"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Handler is a redemption handler rewards can be registered to.
+type Handler struct {
+	Key   string `json:"key"`
+	Title string `json:"title" doc:"Catalog title of the reward"`
+}
+
+type Service struct {
+	queries *db.Queries
+	logger  *slog.Logger
+	now     func() time.Time
+
+	// handlers are the catalog rewards, whose keys are the valid handler keys.
+	handlers []catalog.Reward
+
+	mu   sync.RWMutex
+	byID map[string]Registration
+}
+
+// NewService loads the stored registrations. catalogRewards lists the handler
+// keys rewards may be registered to.
+func NewService(
+	ctx context.Context,
+	queries *db.Queries,
+	catalogRewards []catalog.Reward,
+	logger *slog.Logger,
+) (*Service, error) {
+	if queries == nil {
+		return nil, errors.New("queries must not be nil")
+	}
+	rows, err := queries.ListRewardHandlers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load reward handlers: %w", err)
+	}
+	s := &Service{
+		queries:  queries,
+		logger:   logger,
+		now:      time.Now,
+		handlers: catalogRewards,
+		byID:     make(map[string]Registration, len(rows)),
+	}
+	for _, row := range rows {
+		s.byID[row.RewardID] = Registration{
+			RewardID:   row.RewardID,
+			HandlerKey: row.HandlerKey,
+			Title:      row.Title,
+			UpdatedAt:  row.UpdatedAt,
+		}
+	}
+	return s, nil
+}
+
+// Lookup returns the registration of a reward ID.
+func (s *Service) Lookup(rewardID string) (Registration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	registration, ok := s.byID[rewardID]
+	return registration, ok
+}
+
+// Resolve returns the handler key for a redeemed reward. Rewards that are not
+// registered yet are matched to the catalog by title, ignoring case, and
+// registered so that later renames keep working.
+func (s *Service) Resolve(ctx context.Context, rewardID, title string) (string, bool) {
+	if registration, ok := s.Lookup(rewardID); ok {
+		return registration.HandlerKey, true
+	}
+	handler, ok := s.handlerByTitle(title)
+	if !ok {
+		return "", false
+	}
+	if rewardID != "" {
+		if _, err := s.Register(ctx, rewardID, handler.Key, title); err != nil {
+			s.logger.Error("failed to register reward", "err", err, "reward", title, "reward_id", rewardID)
+		} else {
+			s.logger.Info("registered reward by title", "reward", title, "reward_id", rewardID, "handler", handler.Key)
+		}
+	}
+	return handler.Key, true
+}
+
+// RewardIDs returns the reward IDs registered to a handler key.
+func (s *Service) RewardIDs(handlerKey string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for id, registration := range s.byID {
+		if registration.HandlerKey == handlerKey {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// List returns every registration ordered by handler key.
+func (s *Service) List() []Registration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	registrations := make([]Registration, 0, len(s.byID))
+	for _, registration := range s.byID {
+		registrations = append(registrations, registration)
+	}
+	slices.SortFunc(registrations, func(a, b Registration) int {
+		if c := strings.Compare(a.HandlerKey, b.HandlerKey); c != 0 {
+			return c
+		}
+		return strings.Compare(a.RewardID, b.RewardID)
+	})
+	return registrations
+}
+
+// Handlers returns the handlers rewards can be registered to.
+func (s *Service) Handlers() []Handler {
+	handlers := make([]Handler, 0, len(s.handlers))
+	for _, reward := range s.handlers {
+		handlers = append(handlers, Handler{Key: reward.Key, Title: reward.Title})
+	}
+	return handlers
+}
+
+// Register points a reward ID at a handler, replacing any earlier
+// registration of that ID.
+func (s *Service) Register(ctx context.Context, rewardID, handlerKey, title string) (Registration, error) {
+	if strings.TrimSpace(rewardID) == "" {
+		return Registration{}, errors.New("reward ID is required")
+	}
+	if !s.hasHandler(handlerKey) {
+		return Registration{}, fmt.Errorf("%w %q", ErrUnknownHandler, handlerKey)
+	}
+	registration := Registration{
+		RewardID:   rewardID,
+		HandlerKey: handlerKey,
+		Title:      title,
+		UpdatedAt:  s.now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.queries.UpsertRewardHandler(ctx, db.RewardHandler{
+		RewardID:   registration.RewardID,
+		HandlerKey: registration.HandlerKey,
+		Title:      registration.Title,
+		UpdatedAt:  registration.UpdatedAt,
+	}); err != nil {
+		return Registration{}, fmt.Errorf("store reward handler: %w", err)
+	}
+	s.byID[rewardID] = registration
+	return registration, nil
+}
+
+// Unregister removes a reward ID's registration. Its redemptions fall back to
+// title matching afterwards.
+func (s *Service) Unregister(ctx context.Context, rewardID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted, err := s.queries.DeleteRewardHandler(ctx, rewardID)
+	if err != nil {
+		return fmt.Errorf("delete reward handler: %w", err)
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	delete(s.byID, rewardID)
+	return nil
+}
+
+func (s *Service) hasHandler(key string) bool {
+	return slices.ContainsFunc(s.handlers, func(reward catalog.Reward) bool {
+		return reward.Key == key
+	})
+}
+
+func (s *Service) handlerByTitle(title string) (catalog.Reward, bool) {
+	for _, reward := range s.handlers {
+		if strings.EqualFold(reward.Title, title) {
+			return reward, true
+		}
+	}
+	return catalog.Reward{}, false
+}
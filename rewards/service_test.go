@@ -0,0 +1,85 @@
+package rewards_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/rewards"
+)
+
+var testRewards = []catalog.Reward{
+	{Key: "drink-a-potion", Title: "Drink a Potion", Cost: 1},
+	{Key: "blind-box:coobubu", Title: "Cooper Series Blind Box", Cost: 1},
+}
+
+func newService(t *testing.T) (*rewards.Service, *db.Queries) {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	service, err := rewards.NewService(t.Context(), queries, testRewards, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, queries
+}
+
+func TestResolveRegistersRewardsMatchedByTitle(t *testing.T) {
+	service, queries := newService(t)
+
+	key, ok := service.Resolve(t.Context(), "reward-1", "drink a POTION")
+	if !ok || key != "drink-a-potion" {
+		t.Fatalf("Resolve by title = %q, %v", key, ok)
+	}
+
+	// Once registered, the reward keeps its handler after being renamed.
+	key, ok = service.Resolve(t.Context(), "reward-1", "Renamed Potion")
+	if !ok || key != "drink-a-potion" {
+		t.Fatalf("Resolve after rename = %q, %v", key, ok)
+	}
+
+	// The registration survives a restart.
+	reloaded, err := rewards.NewService(t.Context(), queries, testRewards, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registration, ok := reloaded.Lookup("reward-1"); !ok || registration.HandlerKey != "drink-a-potion" {
+		t.Fatalf("Lookup after reload = %+v, %v", registration, ok)
+	}
+
+	if _, ok := service.Resolve(t.Context(), "reward-2", "Unknown Reward"); ok {
+		t.Fatal("an unregistered reward with an unknown title should not resolve")
+	}
+}
+
+func TestRegisterOverridesTitleMatching(t *testing.T) {
+	service, _ := newService(t)
+
+	if _, err := service.Register(t.Context(), "reward-1", "blind-box:coobubu", "Drink a Potion"); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := service.Resolve(t.Context(), "reward-1", "Drink a Potion"); key != "blind-box:coobubu" {
+		t.Fatalf("Resolve = %q, want the registered handler", key)
+	}
+	if ids := service.RewardIDs("blind-box:coobubu"); len(ids) != 1 || ids[0] != "reward-1" {
+		t.Fatalf("RewardIDs = %v", ids)
+	}
+
+	if _, err := service.Register(t.Context(), "reward-1", "missing", ""); !errors.Is(err, rewards.ErrUnknownHandler) {
+		t.Fatalf("Register with unknown handler = %v, want ErrUnknownHandler", err)
+	}
+
+	if err := service.Unregister(t.Context(), "reward-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Unregister(t.Context(), "reward-1"); !errors.Is(err, rewards.ErrNotFound) {
+		t.Fatalf("second Unregister = %v, want ErrNotFound", err)
+	}
+	if len(service.List()) != 0 {
+		t.Fatalf("List = %v, want empty", service.List())
+	}
+}
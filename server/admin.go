@@ -12,6 +12,7 @@ import (
 	"github.com/danielgtaylor/huma/v2"
 
 	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/catalog"
 	"github.com/lukeramljak/charsibot/stats"
 )
 
@@ -110,17 +111,22 @@ func (s *Server) registerAdminRoutes(api huma.API) {
 	admin := huma.NewGroup(api, "/api/admin")
 	admin.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
 		if err := s.requireAdmin(); err != nil {
-			status := http.StatusInternalServerError
-			var statusErr huma.StatusError
-			if errors.As(err, &statusErr) {
-				status = statusErr.GetStatus()
-			}
-			_ = huma.WriteErr(api, ctx, status, "", err)
+			writeAdminErr(api, ctx, err)
 			return
 		}
-		next(ctx)
+		cookie, _ := huma.ReadCookie(ctx, sessionCookieName)
+		session, err := s.authorizeAdmin(cookie)
+		if err != nil {
+			writeAdminErr(api, ctx, err)
+			return
+		}
+		next(huma.WithValue(ctx, adminSessionKey{}, session))
 	})
 
+	s.registerSessionRoutes(admin)
+	s.registerEventSubRoutes(admin)
+	s.registerRewardRoutes(admin)
+
 	huma.Register(
 		admin,
 		huma.Operation{
@@ -619,7 +625,11 @@ func (s *Server) displayStats(ctx context.Context, user stats.User, display bool
 	if err != nil {
 		return s.adminError("get updated stats", err)
 	}
-	s.sendAdminChatMessage(stats.FormatStats(user.Username, values))
+	message, err := s.cfg.Messages.Render(catalog.MessageStats, catalog.StatsData{User: user.Username, Stats: values})
+	if err != nil {
+		return s.adminError("render stats", err)
+	}
+	s.sendAdminChatMessage(message)
 	return nil
 }
 
@@ -630,6 +640,15 @@ func (s *Server) requireAdmin() error {
 	return nil
 }
 
+func writeAdminErr(api huma.API, ctx huma.Context, err error) {
+	status := http.StatusInternalServerError
+	var statusErr huma.StatusError
+	if errors.As(err, &statusErr) {
+		status = statusErr.GetStatus()
+	}
+	_ = huma.WriteErr(api, ctx, status, "", err)
+}
+
 func (s *Server) hasAdminChatMessage() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
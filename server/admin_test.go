@@ -68,7 +68,7 @@ func TestOverlayEventsSendsInitialHeartbeat(t *testing.T) {
 func TestAdminUserIncludesDefaultStatsForCollectionOnlyUser(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -94,6 +94,7 @@ func TestAdminUserIncludesDefaultStatsForCollectionOnlyUser(t *testing.T) {
 		StatsService:    statsService,
 		BlindBoxService: blindboxService,
 		Series:          appCatalog.Series,
+		Messages:        appCatalog.Messages,
 	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
 	request := httptest.NewRequest(http.MethodGet, "/api/admin/users/viewer-1", nil)
 	request.RemoteAddr = "127.0.0.1:12345"
@@ -120,7 +121,7 @@ func TestAdminUserIncludesDefaultStatsForCollectionOnlyUser(t *testing.T) {
 func TestAdminRandomStatIncrementsOneStat(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,6 +142,7 @@ func TestAdminRandomStatIncrementsOneStat(t *testing.T) {
 		StatsService:    statsService,
 		BlindBoxService: blindboxService,
 		Series:          appCatalog.Series,
+		Messages:        appCatalog.Messages,
 	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
 	srv.SetAdminChatMessage(func(message string) { chatMessage = message })
 	request := httptest.NewRequest(
@@ -176,7 +178,7 @@ func TestAdminRandomStatIncrementsOneStat(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if want := stats.FormatStats("viewer", userStats); chatMessage != want {
+	if want := "viewer's stats: " + stats.FormatValues(userStats); chatMessage != want {
 		t.Errorf("chat message = %q, want %q", chatMessage, want)
 	}
 }
@@ -184,7 +186,7 @@ func TestAdminRandomStatIncrementsOneStat(t *testing.T) {
 func TestAdminResetStatsRestoresDefaults(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -213,6 +215,7 @@ func TestAdminResetStatsRestoresDefaults(t *testing.T) {
 		StatsService:    statsService,
 		BlindBoxService: blindboxService,
 		Series:          appCatalog.Series,
+		Messages:        appCatalog.Messages,
 	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
 	srv.SetAdminChatMessage(func(message string) { chatMessage = message })
 	request := httptest.NewRequest(
@@ -238,7 +241,7 @@ func TestAdminResetStatsRestoresDefaults(t *testing.T) {
 			t.Errorf("%s = %d, want %d", stat.Name, stat.Value, appCatalog.Stats[i].DefaultValue)
 		}
 	}
-	if want := stats.FormatStats("viewer", userStats); chatMessage != want {
+	if want := "viewer's stats: " + stats.FormatValues(userStats); chatMessage != want {
 		t.Errorf("chat message = %q, want %q", chatMessage, want)
 	}
 }
@@ -246,7 +249,7 @@ func TestAdminResetStatsRestoresDefaults(t *testing.T) {
 func TestAdminExplodeReducesPenisAndDisplaysStats(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -272,6 +275,7 @@ func TestAdminExplodeReducesPenisAndDisplaysStats(t *testing.T) {
 		StatsService:    statsService,
 		BlindBoxService: blindboxService,
 		Series:          appCatalog.Series,
+		Messages:        appCatalog.Messages,
 	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
 	srv.SetAdminChatMessage(func(message string) { chatMessage = message })
 	request := httptest.NewRequest(http.MethodPost, "/api/admin/users/viewer-1/stats/explode", nil)
@@ -291,7 +295,7 @@ func TestAdminExplodeReducesPenisAndDisplaysStats(t *testing.T) {
 	if got := statValue(userStats, "penis"); got != -1000 {
 		t.Errorf("penis stat = %d, want -1000", got)
 	}
-	if want := stats.FormatStats("viewer", userStats); chatMessage != want {
+	if want := "viewer's stats: " + stats.FormatValues(userStats); chatMessage != want {
 		t.Errorf("chat message = %q, want %q", chatMessage, want)
 	}
 
@@ -324,7 +328,7 @@ func statValue(userStats []stats.UserStat, name string) int64 {
 func TestAdminRandomPlushieGrantsFromSeries(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -345,6 +349,7 @@ func TestAdminRandomPlushieGrantsFromSeries(t *testing.T) {
 		StatsService:    statsService,
 		BlindBoxService: blindboxService,
 		Series:          appCatalog.Series,
+		Messages:        appCatalog.Messages,
 	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
 	request := httptest.NewRequest(
 		http.MethodPost,
@@ -391,7 +396,7 @@ func TestAdminRandomPlushieGrantsFromSeries(t *testing.T) {
 func TestAdminGrantPlushieTriggersRedemptionEvent(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -412,6 +417,7 @@ func TestAdminGrantPlushieTriggersRedemptionEvent(t *testing.T) {
 		StatsService:    statsService,
 		BlindBoxService: blindboxService,
 		Series:          appCatalog.Series,
+		Messages:        appCatalog.Messages,
 	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
 	request := httptest.NewRequest(
 		http.MethodPut,
@@ -445,7 +451,7 @@ func TestAdminGrantPlushieTriggersRedemptionEvent(t *testing.T) {
 func TestAdminRemovePlushieDoesNotRequireBody(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -472,12 +478,16 @@ func TestAdminRemovePlushieDoesNotRequireBody(t *testing.T) {
 	}
 
 	srv := NewServer(ServerConfig{
-		StatsService:    statsService,
-		BlindBoxService: blindboxService,
-		Series:          appCatalog.Series,
+		BroadcasterUserID: "broadcaster-1",
+		SessionSecret:     "test-secret-0123456789abcdefghijklmnop",
+		StatsService:      statsService,
+		BlindBoxService:   blindboxService,
+		Series:            appCatalog.Series,
+		Messages:          appCatalog.Messages,
 	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
 	mux := http.NewServeMux()
 	srv.NewAPI(mux)
+	session := adminSessionCookie(t, srv, "broadcaster-1")
 	events := make(chan OverlayEvent, 1)
 	srv.clients[events] = struct{}{}
 	request := httptest.NewRequest(
@@ -486,6 +496,7 @@ func TestAdminRemovePlushieDoesNotRequireBody(t *testing.T) {
 		nil,
 	)
 	request.RemoteAddr = "127.0.0.1:12345"
+	request.AddCookie(&session)
 	response := httptest.NewRecorder()
 	mux.ServeHTTP(response, request)
 	if response.Code != http.StatusOK {
@@ -505,6 +516,7 @@ func TestAdminRemovePlushieDoesNotRequireBody(t *testing.T) {
 		nil,
 	)
 	request.RemoteAddr = "127.0.0.1:12345"
+	request.AddCookie(&session)
 	response = httptest.NewRecorder()
 	mux.ServeHTTP(response, request)
 
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	adminAccount       = "admin"
+	sessionCookieName  = "charsibot_admin_session"
+	stateCookieName    = "charsibot_oauth_state"
+	sessionTTL         = 12 * time.Hour
+	stateTTL           = 10 * time.Minute
+	oauthStateBytes    = 16
+	adminLoginRedirect = "/admin"
+	// minSessionSecretLength keeps session cookies' HMAC key out of guessing range.
+	minSessionSecretLength = 32
+)
+
+type adminSessionKey struct{}
+
+// AdminSession identifies the Twitch user behind an authenticated admin request.
+type AdminSession struct {
+	UserID    string    `json:"userId"`
+	Login     string    `json:"login"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type adminSessionOutput struct {
+	Body AdminSession
+}
+
+type adminLogoutOutput struct {
+	SetCookie http.Cookie `header:"Set-Cookie"`
+}
+
+func (s *Server) registerSessionRoutes(admin huma.API) {
+	huma.Register(
+		admin,
+		huma.Operation{
+			OperationID: "get-admin-session",
+			Method:      http.MethodGet,
+			Path:        "/session",
+			Tags:        []string{adminTag},
+		},
+		func(ctx context.Context, _ *struct{}) (*adminSessionOutput, error) {
+			session, ok := ctx.Value(adminSessionKey{}).(AdminSession)
+			if !ok {
+				return nil, huma.Error401Unauthorized("login required")
+			}
+			return &adminSessionOutput{Body: session}, nil
+		},
+	)
+	huma.Register(
+		admin,
+		huma.Operation{
+			OperationID:   "delete-admin-session",
+			Method:        http.MethodDelete,
+			Path:          "/session",
+			Tags:          []string{adminTag},
+			DefaultStatus: http.StatusNoContent,
+		},
+		func(context.Context, *struct{}) (*adminLogoutOutput, error) {
+			return &adminLogoutOutput{SetCookie: s.expiredCookie(sessionCookieName)}, nil
+		},
+	)
+}
+
+// authorizeAdmin verifies the signed session cookie and checks the user against
+// the allow-list on every request, so removing an ID revokes access immediately.
+func (s *Server) authorizeAdmin(cookie *http.Cookie) (AdminSession, error) {
+	if s.cfg.SessionSecret == "" {
+		return AdminSession{}, huma.Error503ServiceUnavailable("admin authentication is not configured")
+	}
+	if cookie == nil {
+		return AdminSession{}, huma.Error401Unauthorized("login required")
+	}
+	session, err := s.verifySession(cookie.Value)
+	if err != nil {
+		return AdminSession{}, huma.Error401Unauthorized("login required")
+	}
+	if !s.isAdminUser(session.UserID) {
+		return AdminSession{}, huma.Error403Forbidden("not an admin")
+	}
+	return session, nil
+}
+
+func (s *Server) isAdminUser(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	if userID == s.cfg.BroadcasterUserID {
+		return true
+	}
+	return slices.Contains(s.cfg.AdminUserIDs, userID)
+}
+
+func (s *Server) newSessionCookie(session AdminSession) (http.Cookie, error) {
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return http.Cookie{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return http.Cookie{
+		Name:     sessionCookieName,
+		Value:    encoded + "." + s.sign(encoded),
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		MaxAge:   int(time.Until(session.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   s.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	}, nil
+}
+
+func (s *Server) verifySession(value string) (AdminSession, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return AdminSession{}, errors.New("invalid session signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return AdminSession{}, err
+	}
+	var session AdminSession
+	if err := json.Unmarshal(payload, &session); err != nil {
+		return AdminSession{}, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return AdminSession{}, errors.New("session expired")
+	}
+	return session, nil
+}
+
+func (s *Server) sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.SessionSecret))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) secureCookies() bool {
+	return strings.HasPrefix(s.cfg.OAuthRedirectURI, "https://")
+}
+
+func (s *Server) expiredCookie(name string) http.Cookie {
+	return http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// newOAuthState returns the state parameter for an authorization request and
+// stores its nonce in a short-lived cookie so the callback can reject forged
+// responses.
+func (s *Server) newOAuthState(w http.ResponseWriter, account string) (string, error) {
+	nonce := make([]byte, oauthStateBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(nonce)
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    encoded,
+		Path:     "/oauth",
+		MaxAge:   int(stateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   s.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return account + ":" + encoded, nil
+}
+
+// checkOAuthState validates the state parameter against the nonce cookie and
+// returns the account the authorization was started for.
+func (s *Server) checkOAuthState(w http.ResponseWriter, r *http.Request) (string, bool) {
+	account, nonce, ok := strings.Cut(r.URL.Query().Get("state"), ":")
+	if !ok || nonce == "" {
+		return "", false
+	}
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(nonce)) != 1 {
+		return "", false
+	}
+	expired := s.expiredCookie(stateCookieName)
+	expired.Path = "/oauth"
+	http.SetCookie(w, &expired)
+	return account, true
+}
+
+func (s *Server) completeAdminLogin(w http.ResponseWriter, r *http.Request, userID, login string) {
+	if !s.isAdminUser(userID) {
+		s.logger.Warn("admin login rejected", "user_id", userID, "login", login)
+		http.Error(w, "this Twitch account is not allowed to use the admin page", http.StatusForbidden)
+		return
+	}
+	cookie, err := s.newSessionCookie(AdminSession{
+		UserID:    userID,
+		Login:     login,
+		ExpiresAt: time.Now().Add(sessionTTL).UTC(),
+	})
+	if err != nil {
+		s.logger.Error("failed to create admin session", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &cookie)
+	s.logger.Info("admin login", "user_id", userID, "login", login)
+	http.Redirect(w, r, adminLoginRedirect, http.StatusFound)
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/rewards"
+	"github.com/lukeramljak/charsibot/stats"
+)
+
+func adminSessionCookie(t *testing.T, srv *Server, userID string) http.Cookie {
+	t.Helper()
+	cookie, err := srv.newSessionCookie(AdminSession{
+		UserID:    userID,
+		Login:     "login-" + userID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cookie
+}
+
+func newAuthTestServer(t *testing.T) (*Server, *http.ServeMux) {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	appCatalog, err := catalog.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statsService, err := stats.NewService(queries, appCatalog.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindboxService, err := blindbox.NewService(queries, appCatalog.Series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewardRegistry, err := rewards.NewService(t.Context(), queries, appCatalog.Rewards, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(ServerConfig{
+		ClientID:          "client-1",
+		BroadcasterUserID: "broadcaster-1",
+		AdminUserIDs:      []string{"mod-1"},
+		SessionSecret:     "test-secret-0123456789abcdefghijklmnop",
+		StatsService:      statsService,
+		BlindBoxService:   blindboxService,
+		RewardRegistry:    rewardRegistry,
+		Series:            appCatalog.Series,
+	}, slog.New(slog.NewTextHandler(testWriter{t}, nil)))
+	mux := http.NewServeMux()
+	srv.NewAPI(mux)
+	return srv, mux
+}
+
+func TestAdminRoutesRequireSession(t *testing.T) {
+	srv, mux := newAuthTestServer(t)
+
+	forged := adminSessionCookie(t, srv, "mod-1")
+	forged.Value += "x"
+	viewer := adminSessionCookie(t, srv, "viewer-1")
+	moderator := adminSessionCookie(t, srv, "mod-1")
+	broadcaster := adminSessionCookie(t, srv, "broadcaster-1")
+	expired, err := srv.newSessionCookie(AdminSession{UserID: "mod-1", ExpiresAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		want   int
+	}{
+		{name: "no session", want: http.StatusUnauthorized},
+		{name: "forged signature", cookie: &forged, want: http.StatusUnauthorized},
+		{name: "expired session", cookie: &expired, want: http.StatusUnauthorized},
+		{name: "not on allow-list", cookie: &viewer, want: http.StatusForbidden},
+		{name: "moderator", cookie: &moderator, want: http.StatusOK},
+		{name: "broadcaster", cookie: &broadcaster, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/admin/users", nil)
+			if tt.cookie != nil {
+				request.AddCookie(tt.cookie)
+			}
+			response := httptest.NewRecorder()
+			mux.ServeHTTP(response, request)
+			if response.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body = %s", response.Code, tt.want, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRoutesDisabledWithoutSessionSecret(t *testing.T) {
+	srv, mux := newAuthTestServer(t)
+	cookie := adminSessionCookie(t, srv, "broadcaster-1")
+	srv.cfg.SessionSecret = ""
+
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/users", nil)
+	request.AddCookie(&cookie)
+	response := httptest.NewRecorder()
+	mux.ServeHTTP(response, request)
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestStartRejectsShortSessionSecret(t *testing.T) {
+	srv, _ := newAuthTestServer(t)
+	srv.cfg.SessionSecret = "too-short"
+
+	if err := srv.Start(); err == nil {
+		srv.Stop()
+		t.Fatal("Start() error = nil, want short secret rejected")
+	}
+}
+
+func TestAdminSessionReturnsIdentity(t *testing.T) {
+	srv, mux := newAuthTestServer(t)
+	cookie := adminSessionCookie(t, srv, "mod-1")
+
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/session", nil)
+	request.AddCookie(&cookie)
+	response := httptest.NewRecorder()
+	mux.ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", response.Code, response.Body.String())
+	}
+	var session AdminSession
+	if err := json.NewDecoder(response.Body).Decode(&session); err != nil {
+		t.Fatal(err)
+	}
+	if session.UserID != "mod-1" || session.Login != "login-mod-1" {
+		t.Fatalf("session = %#v", session)
+	}
+
+	request = httptest.NewRequest(http.MethodDelete, "/api/admin/session", nil)
+	request.AddCookie(&cookie)
+	response = httptest.NewRecorder()
+	mux.ServeHTTP(response, request)
+	if response.Code != http.StatusNoContent {
+		t.Fatalf("logout status = %d, body = %s", response.Code, response.Body.String())
+	}
+	cleared := response.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].Name != sessionCookieName || cleared[0].MaxAge >= 0 {
+		t.Fatalf("logout cookies = %#v, want cleared session", cleared)
+	}
+}
+
+func TestOAuthCallbackRejectsMismatchedState(t *testing.T) {
+	srv, _ := newAuthTestServer(t)
+
+	start := httptest.NewRecorder()
+	srv.handleOAuthStart(start, httptest.NewRequest(http.MethodGet, "/oauth/start?account=admin", nil))
+	if start.Code != http.StatusFound {
+		t.Fatalf("start status = %d", start.Code)
+	}
+	cookies := start.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != stateCookieName {
+		t.Fatalf("start cookies = %#v, want oauth state", cookies)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/oauth/callback?code=abc&state=admin:forged", nil)
+	request.AddCookie(cookies[0])
+	response := httptest.NewRecorder()
+	srv.handleOAuthCallback(response, request)
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("callback status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAdminEventSubSubscriptions(t *testing.T) {
+	srv, mux := newAuthTestServer(t)
+	cookie := adminSessionCookie(t, srv, "broadcaster-1")
+
+	get := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/admin/eventsub/subscriptions", nil)
+		request.AddCookie(&cookie)
+		response := httptest.NewRecorder()
+		mux.ServeHTTP(response, request)
+		return response
+	}
+
+	if response := get(); response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status without bot = %d, want %d", response.Code, http.StatusServiceUnavailable)
+	}
+
+	srv.SetSubscriptionStatus(func() []EventSubSubscription {
+		return []EventSubSubscription{{Type: "channel.raid", Version: "1", Status: "failed", Attempts: 2}}
+	})
+	response := get()
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", response.Code, response.Body.String())
+	}
+	var body adminSubscriptionsResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Subscriptions) != 1 || body.Subscriptions[0].Status != "failed" || body.Subscriptions[0].Attempts != 2 {
+		t.Fatalf("subscriptions = %#v", body.Subscriptions)
+	}
+}
+
+func TestAdminRewardRegistry(t *testing.T) {
+	srv, mux := newAuthTestServer(t)
+	cookie := adminSessionCookie(t, srv, "broadcaster-1")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.AddCookie(&cookie)
+		response := httptest.NewRecorder()
+		mux.ServeHTTP(response, request)
+		return response
+	}
+
+	response := do(http.MethodPut, "/api/admin/rewards/reward-1", `{"handlerKey":"missing"}`)
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("unknown handler status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+
+	response = do(http.MethodPut, "/api/admin/rewards/reward-1", `{"handlerKey":"drink-a-potion","title":"Potion"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("register status = %d, body = %s", response.Code, response.Body.String())
+	}
+
+	response = do(http.MethodGet, "/api/admin/rewards", "")
+	if response.Code != http.StatusOK {
+		t.Fatalf("list status = %d, body = %s", response.Code, response.Body.String())
+	}
+	var body adminRewardsResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Registrations) != 1 || body.Registrations[0].HandlerKey != "drink-a-potion" {
+		t.Fatalf("registrations = %#v", body.Registrations)
+	}
+	if len(body.Handlers) == 0 {
+		t.Fatal("expected the catalog handlers to be listed")
+	}
+
+	if response = do(http.MethodDelete, "/api/admin/rewards/reward-1", ""); response.Code != http.StatusNoContent {
+		t.Fatalf("unregister status = %d, body = %s", response.Code, response.Body.String())
+	}
+	if response = do(http.MethodDelete, "/api/admin/rewards/reward-1", ""); response.Code != http.StatusNotFound {
+		t.Fatalf("second unregister status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+}
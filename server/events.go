@@ -6,9 +6,66 @@ const (
 	EventTypeChatCommand        EventType = "chat_command"
 	EventTypeCollectionDisplay  EventType = "blindbox_display"
 	EventTypeBlindBoxRedemption EventType = "blindbox_redemption"
+	EventTypeRewardEffect       EventType = "reward_effect"
+	EventTypeDiceRoll           EventType = "dice_roll"
+	EventTypeBossRaid           EventType = "boss_raid"
 )
 
 type OverlayEvent struct {
 	Type EventType
 	Data any
 }
+
+// RewardEffectData is the payload of a reward_effect overlay event, sent by
+// channel point rewards whose catalog effects include an overlay step.
+type RewardEffectData struct {
+	Username string `json:"username"`
+	Reward   string `json:"reward"          doc:"Catalog key of the redeemed reward"`
+	Title    string `json:"title"`
+	Stat     string `json:"stat,omitempty"  doc:"Stat the reward changed, if any"`
+	Delta    int64  `json:"delta,omitempty"`
+}
+
+// DiceRollData is the payload of a dice_roll overlay event, sent for each roll
+// so the overlay can animate the dice.
+type DiceRollData struct {
+	Username   string         `json:"username"`
+	Expression string         `json:"expression"          doc:"Dice expression as written, like 1d20+STR"`
+	Dice       []DieRoll      `json:"dice"`
+	Modifiers  []RollModifier `json:"modifiers"`
+	Total      int64          `json:"total"`
+	Critical   string         `json:"critical,omitempty"  doc:"Set when a single d20 rolls 20 or 1" enum:"success,failure"`
+}
+
+// DieRoll is one die of a roll. Value is negative for subtracted dice.
+type DieRoll struct {
+	Sides int64 `json:"sides"`
+	Value int64 `json:"value"`
+}
+
+// RollModifier is a stat or number added to a roll.
+type RollModifier struct {
+	Name  string `json:"name,omitempty" doc:"Short name of the stat, empty for a plain number"`
+	Value int64  `json:"value"`
+}
+
+// BossRaidData is the payload of a boss_raid overlay event, sent as chatters
+// join a raid, after each round of the fight, and when it ends.
+type BossRaidData struct {
+	Phase    string        `json:"phase"           enum:"joining,round,victory,defeat"`
+	Boss     string        `json:"boss"            doc:"Catalog key of the boss"`
+	Name     string        `json:"name"`
+	HP       int64         `json:"hp"              doc:"Hit points the boss has left"`
+	MaxHP    int64         `json:"maxHp"`
+	Round    int           `json:"round,omitempty"`
+	Fighters []RaidFighter `json:"fighters"        nullable:"false"`
+}
+
+// RaidFighter is a chatter in a boss raid. Damage is what they dealt in the
+// round, or over the whole fight once it has ended.
+type RaidFighter struct {
+	Username   string `json:"username"`
+	Damage     int64  `json:"damage"`
+	Critical   bool   `json:"critical,omitempty"`
+	KnockedOut bool   `json:"knockedOut,omitempty"`
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// EventSubSubscription reports the state of one EventSub subscription the bot
+// keeps on its conduit.
+type EventSubSubscription struct {
+	Type        string            `json:"type"`
+	Version     string            `json:"version"`
+	Condition   map[string]string `json:"condition"`
+	ID          string            `json:"id,omitempty"          doc:"Twitch subscription ID, once known"`
+	Status      string            `json:"status"                doc:"Twitch status, or pending/failed while being created"`
+	LastError   string            `json:"lastError,omitempty"`
+	Attempts    int               `json:"attempts"              doc:"Consecutive failed creation attempts"`
+	NextRetryAt *time.Time        `json:"nextRetryAt,omitempty"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+type adminSubscriptionsResponse struct {
+	Subscriptions []EventSubSubscription `json:"subscriptions" nullable:"false"`
+}
+
+type adminSubscriptionsOutput struct {
+	Body adminSubscriptionsResponse
+}
+
+// SetSubscriptionStatus configures how the admin API reads the bot's EventSub
+// subscription state.
+func (s *Server) SetSubscriptionStatus(status func() []EventSubSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptionStatus = status
+}
+
+func (s *Server) registerEventSubRoutes(admin huma.API) {
+	huma.Register(
+		admin,
+		huma.Operation{
+			OperationID: "list-admin-eventsub-subscriptions",
+			Method:      http.MethodGet,
+			Path:        "/eventsub/subscriptions",
+			Tags:        []string{adminTag},
+		},
+		s.listAdminSubscriptions,
+	)
+}
+
+func (s *Server) listAdminSubscriptions(context.Context, *struct{}) (*adminSubscriptionsOutput, error) {
+	s.mu.RLock()
+	status := s.subscriptionStatus
+	s.mu.RUnlock()
+	if status == nil {
+		return nil, huma.Error503ServiceUnavailable("bot is not running")
+	}
+	return &adminSubscriptionsOutput{Body: adminSubscriptionsResponse{Subscriptions: status()}}, nil
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/lukeramljak/charsibot/rewards"
+)
+
+type adminRewardsResponse struct {
+	Registrations []rewards.Registration `json:"registrations" nullable:"false"`
+	Handlers      []rewards.Handler      `json:"handlers"      nullable:"false" doc:"Handlers a reward can be registered to"`
+}
+
+type adminRewardsOutput struct {
+	Body adminRewardsResponse
+}
+
+type adminRewardOutput struct {
+	Body rewards.Registration
+}
+
+type adminRewardInput struct {
+	RewardID string `path:"rewardID" doc:"Twitch channel point reward ID"`
+}
+
+type adminRegisterRewardInput struct {
+	RewardID string `path:"rewardID" doc:"Twitch channel point reward ID"`
+	Body     struct {
+		HandlerKey string `json:"handlerKey"`
+		Title      string `json:"title,omitempty" doc:"Reward title, for display"`
+	}
+}
+
+func (s *Server) registerRewardRoutes(admin huma.API) {
+	huma.Register(
+		admin,
+		huma.Operation{
+			OperationID: "list-admin-rewards",
+			Method:      http.MethodGet,
+			Path:        "/rewards",
+			Tags:        []string{adminTag},
+		},
+		s.listAdminRewards,
+	)
+	huma.Register(
+		admin,
+		huma.Operation{
+			OperationID: "register-admin-reward",
+			Method:      http.MethodPut,
+			Path:        "/rewards/{rewardID}",
+			Tags:        []string{adminTag},
+		},
+		s.registerAdminReward,
+	)
+	huma.Register(
+		admin,
+		huma.Operation{
+			OperationID: "unregister-admin-reward",
+			Method:      http.MethodDelete,
+			Path:        "/rewards/{rewardID}",
+			Tags:        []string{adminTag},
+		},
+		s.unregisterAdminReward,
+	)
+}
+
+func (s *Server) listAdminRewards(context.Context, *struct{}) (*adminRewardsOutput, error) {
+	registry, err := s.rewardRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return &adminRewardsOutput{Body: adminRewardsResponse{
+		Registrations: registry.List(),
+		Handlers:      registry.Handlers(),
+	}}, nil
+}
+
+func (s *Server) registerAdminReward(
+	ctx context.Context,
+	input *adminRegisterRewardInput,
+) (*adminRewardOutput, error) {
+	registry, err := s.rewardRegistry()
+	if err != nil {
+		return nil, err
+	}
+	registration, err := registry.Register(ctx, input.RewardID, input.Body.HandlerKey, input.Body.Title)
+	if errors.Is(err, rewards.ErrUnknownHandler) {
+		return nil, huma.Error400BadRequest("unknown handler")
+	}
+	if err != nil {
+		return nil, s.adminError("register reward", err)
+	}
+	return &adminRewardOutput{Body: registration}, nil
+}
+
+func (s *Server) unregisterAdminReward(ctx context.Context, input *adminRewardInput) (*struct{}, error) {
+	registry, err := s.rewardRegistry()
+	if err != nil {
+		return nil, err
+	}
+	err = registry.Unregister(ctx, input.RewardID)
+	if errors.Is(err, rewards.ErrNotFound) {
+		return nil, huma.Error404NotFound("reward not registered")
+	}
+	if err != nil {
+		return nil, s.adminError("unregister reward", err)
+	}
+	return nil, nil //nolint:nilnil // Huma uses a nil output to emit an empty successful response.
+}
+
+func (s *Server) rewardRegistry() (*rewards.Service, error) {
+	if s.cfg.RewardRegistry == nil {
+		return nil, huma.Error503ServiceUnavailable("reward registry is unavailable")
+	}
+	return s.cfg.RewardRegistry, nil
+}
@@ -18,7 +18,10 @@ import (
 	helix "github.com/nicklaw5/helix/v2"
 
 	"github.com/lukeramljak/charsibot/blindbox"
+	"github.com/lukeramljak/charsibot/catalog"
+	"github.com/lukeramljak/charsibot/rewards"
 	"github.com/lukeramljak/charsibot/stats"
+	"github.com/lukeramljak/charsibot/tokens"
 )
 
 //go:embed all:web
@@ -36,9 +39,23 @@ type ServerConfig struct {
 	ClientID         string
 	ClientSecret     string
 	OAuthRedirectURI string
-	StatsService     *stats.Service
-	BlindBoxService  *blindbox.Service
-	Series           []blindbox.SeriesConfig
+	BotUserID        string
+	// BroadcasterUserID is always allowed to use the admin API.
+	BroadcasterUserID string
+	// AdminUserIDs lists the moderators allowed to use the admin API.
+	AdminUserIDs []string
+	// SessionSecret signs admin session cookies and must be at least 32 bytes.
+	// The admin API is disabled when empty.
+	SessionSecret string
+	// Tokens stores the user tokens granted through the OAuth callback.
+	Tokens          *tokens.Service
+	StatsService    *stats.Service
+	BlindBoxService *blindbox.Service
+	// RewardRegistry maps channel point reward IDs to redemption handlers.
+	RewardRegistry *rewards.Service
+	Series         []blindbox.SeriesConfig
+	// Messages renders the chat messages the admin API sends.
+	Messages catalog.Messages
 }
 
 // Server handles SSE streaming and OAuth.
@@ -52,6 +69,8 @@ type Server struct {
 	blindbox         *blindbox.Service
 	series           []blindbox.SeriesConfig
 	adminChatMessage func(string)
+
+	subscriptionStatus func() []EventSubSubscription
 }
 
 func NewServer(cfg ServerConfig, logger *slog.Logger) *Server {
@@ -84,6 +103,10 @@ func (s *Server) sendAdminChatMessage(message string) bool {
 }
 
 func (s *Server) Start() error {
+	if s.cfg.SessionSecret != "" && len(s.cfg.SessionSecret) < minSessionSecretLength {
+		return fmt.Errorf("admin session secret must be at least %d bytes", minSessionSecretLength)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", s.handleHealth)
 	mux.HandleFunc("GET /oauth/start", s.handleOAuthStart)
@@ -164,6 +187,9 @@ func (s *Server) registerOverlayEvents(api huma.API) {
 		string(EventTypeChatCommand):        ChatCommandData{},
 		string(EventTypeCollectionDisplay):  blindbox.BlindBoxDisplayData{},
 		string(EventTypeBlindBoxRedemption): blindbox.BlindBoxRedemptionData{},
+		string(EventTypeRewardEffect):       RewardEffectData{},
+		string(EventTypeDiceRoll):           DiceRollData{},
+		string(EventTypeBossRaid):           BossRaidData{},
 	}, func(ctx context.Context, _ *struct{}, send sse.Sender) {
 		ch := make(chan OverlayEvent, eventChannelBuffer)
 		s.mu.Lock()
@@ -221,8 +247,10 @@ func (s *Server) Broadcast(event OverlayEvent) {
 
 func oauthScopes(account string) ([]string, bool) {
 	scopes := map[string][]string{
-		"streamer": {"channel:manage:redemptions", "channel:read:redemptions", "channel:bot"},
-		"bot":      {"user:read:chat", "user:write:chat", "user:bot"},
+		tokens.AccountStreamer: {"channel:manage:redemptions", "channel:read:redemptions", "channel:bot"},
+		tokens.AccountBot:      {"user:read:chat", "user:write:chat", "user:bot"},
+		// Admin login only needs the user's identity, so no scopes are requested.
+		adminAccount: {},
 	}
 	s, ok := scopes[account]
 	return s, ok
@@ -246,18 +274,29 @@ func (s *Server) handleOAuthStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	state, err := s.newOAuthState(w, account)
+	if err != nil {
+		s.logger.Error("failed to create oauth state", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	authURL := helixClient.GetAuthorizationURL(&helix.AuthorizationURLParams{
 		ResponseType: "code",
 		Scopes:       scopes,
-		State:        account,
-		ForceVerify:  true,
+		State:        state,
+		ForceVerify:  account != adminAccount,
 	})
 
 	http.Redirect(w, r, authURL, http.StatusFound)
 }
 
 func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
-	account := r.URL.Query().Get("state")
+	account, ok := s.checkOAuthState(w, r)
+	if !ok {
+		http.Error(w, "invalid state parameter", http.StatusBadRequest)
+		return
+	}
 	if _, ok := oauthScopes(account); !ok {
 		http.Error(w, "invalid state parameter", http.StatusBadRequest)
 		return
@@ -301,11 +340,74 @@ func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.logger.Info("OAuth authorization complete", "account", account)
+	if account == adminAccount {
+		valid, validateResp, err := helixClient.ValidateToken(tokenResp.Data.AccessToken)
+		if err != nil || !valid {
+			s.logger.Error("failed to validate admin login token", "err", err)
+			http.Error(w, "login failed", http.StatusUnauthorized)
+			return
+		}
+		s.completeAdminLogin(w, r, validateResp.Data.UserID, validateResp.Data.Login)
+		return
+	}
+
+	valid, validateResp, err := helixClient.ValidateToken(tokenResp.Data.AccessToken)
+	if err != nil || !valid {
+		s.logger.Error("failed to validate authorized token", "account", account, "err", err)
+		http.Error(w, "token validation failed", http.StatusInternalServerError)
+		return
+	}
+	if expected := s.expectedUserID(account); expected != "" && validateResp.Data.UserID != expected {
+		s.logger.Warn("authorized the wrong twitch account",
+			"account", account,
+			"user_id", validateResp.Data.UserID,
+			"expected_user_id", expected,
+		)
+		http.Error(w, fmt.Sprintf("signed in as %s, which is not the configured %s account",
+			validateResp.Data.Login, account), http.StatusBadRequest)
+		return
+	}
+	if s.cfg.Tokens == nil {
+		s.logger.Error("token storage is not configured", "account", account)
+		http.Error(w, "token storage is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	now := time.Now()
+	if err := s.cfg.Tokens.Save(r.Context(), tokens.Token{
+		Account:      account,
+		UserID:       validateResp.Data.UserID,
+		Login:        validateResp.Data.Login,
+		AccessToken:  tokenResp.Data.AccessToken,
+		RefreshToken: tokenResp.Data.RefreshToken,
+		Scopes:       validateResp.Data.Scopes,
+		ExpiresAt:    now.Add(time.Duration(tokenResp.Data.ExpiresIn) * time.Second),
+		ValidatedAt:  &now,
+	}); err != nil {
+		s.logger.Error("failed to store token", "account", account, "err", err)
+		http.Error(w, "failed to store token", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("OAuth authorization complete",
+		"account", account,
+		"login", validateResp.Data.Login,
+		"scopes", validateResp.Data.Scopes,
+	)
 	accountLabel := strings.ToUpper(account[:1]) + account[1:]
 	fmt.Fprintf(w, "%s authorization complete.", accountLabel)
 }
 
+func (s *Server) expectedUserID(account string) string {
+	switch account {
+	case tokens.AccountBot:
+		return s.cfg.BotUserID
+	case tokens.AccountStreamer:
+		return s.cfg.BroadcasterUserID
+	default:
+		return ""
+	}
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte("OK")); err != nil {
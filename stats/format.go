@@ -5,16 +5,12 @@ import (
 	"strings"
 )
 
-// FormatStats formats a user's stats as a human-readable chat message.
-func FormatStats(username string, stats []UserStat) string {
-	if len(stats) == 0 {
-		return fmt.Sprintf("No stats found for %s", username)
-	}
-
+// FormatValues lists a user's stat values for chat, like "STR: 5 | INT: 3".
+// Chat messages around it are catalog templates.
+func FormatValues(stats []UserStat) string {
 	parts := make([]string, 0, len(stats))
 	for _, stat := range stats {
 		parts = append(parts, fmt.Sprintf("%s: %d", stat.ShortName, stat.Value))
 	}
-
-	return fmt.Sprintf("%s's stats: %s", username, strings.Join(parts, " | "))
+	return strings.Join(parts, " | ")
 }
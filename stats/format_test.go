@@ -6,7 +6,7 @@ import (
 	"github.com/lukeramljak/charsibot/stats"
 )
 
-func TestFormatStats(t *testing.T) {
+func TestFormatValues(t *testing.T) {
 	userStats := []stats.UserStat{
 		{Name: "strength", ShortName: "STR", LongName: "Strength", Value: 5},
 		{Name: "intelligence", ShortName: "INT", LongName: "Intelligence", Value: 5},
@@ -16,15 +16,15 @@ func TestFormatStats(t *testing.T) {
 		{Name: "penis", ShortName: "PENIS", LongName: "Penis", Value: 3},
 	}
 
-	formatted := stats.FormatStats("testuser", userStats)
-	expected := "testuser's stats: STR: 5 | INT: 5 | CHA: 3 | LUCK: 3 | DEX: 3 | PENIS: 3"
+	formatted := stats.FormatValues(userStats)
+	expected := "STR: 5 | INT: 5 | CHA: 3 | LUCK: 3 | DEX: 3 | PENIS: 3"
 
 	if formatted != expected {
-		t.Errorf("FormatStats() = %q, want %q", formatted, expected)
+		t.Errorf("FormatValues() = %q, want %q", formatted, expected)
 	}
 }
 
-func TestFormatStatsNegative(t *testing.T) {
+func TestFormatValuesNegative(t *testing.T) {
 	userStats := []stats.UserStat{
 		{Name: "strength", ShortName: "STR", LongName: "Strength", Value: 3},
 		{Name: "intelligence", ShortName: "INT", LongName: "Intelligence", Value: 3},
@@ -34,19 +34,19 @@ func TestFormatStatsNegative(t *testing.T) {
 		{Name: "penis", ShortName: "PENIS", LongName: "Penis", Value: 3},
 	}
 
-	formatted := stats.FormatStats("testuser", userStats)
-	expected := "testuser's stats: STR: 3 | INT: 3 | CHA: 9 | LUCK: -2 | DEX: 3 | PENIS: 3"
+	formatted := stats.FormatValues(userStats)
+	expected := "STR: 3 | INT: 3 | CHA: 9 | LUCK: -2 | DEX: 3 | PENIS: 3"
 
 	if formatted != expected {
-		t.Errorf("FormatStats() = %q, want %q", formatted, expected)
+		t.Errorf("FormatValues() = %q, want %q", formatted, expected)
 	}
 }
 
-func TestFormatStatsEmpty(t *testing.T) {
-	formatted := stats.FormatStats("testuser", []stats.UserStat{})
-	expected := "No stats found for testuser"
+func TestFormatValuesEmpty(t *testing.T) {
+	formatted := stats.FormatValues([]stats.UserStat{})
+	expected := ""
 
 	if formatted != expected {
-		t.Errorf("FormatStats() = %q, want %q", formatted, expected)
+		t.Errorf("FormatValues() = %q, want %q", formatted, expected)
 	}
 }
@@ -187,6 +187,28 @@ func (s *Service) ModifyStatValue(ctx context.Context, userID, statName string,
 	})
 }
 
+// TransferStatValue moves amount of a stat from one user to another in one
+// transaction, so it is never taken without being given.
+func (s *Service) TransferStatValue(ctx context.Context, fromUserID, toUserID, statName string, amount int64) error {
+	return s.queries.InTx(ctx, func(q *db.Queries) error {
+		if err := q.ModifyStatValue(ctx, db.ModifyStatValueParams{
+			UserID:   fromUserID,
+			StatName: statName,
+			Value:    -amount,
+		}); err != nil {
+			return fmt.Errorf("take %s: %w", statName, err)
+		}
+		if err := q.ModifyStatValue(ctx, db.ModifyStatValueParams{
+			UserID:   toUserID,
+			StatName: statName,
+			Value:    amount,
+		}); err != nil {
+			return fmt.Errorf("give %s: %w", statName, err)
+		}
+		return nil
+	})
+}
+
 func (s *Service) SetStatValue(ctx context.Context, userID, statName string, value int64) error {
 	return s.queries.SetStatValue(ctx, db.SetStatValueParams{
 		UserID:   userID,
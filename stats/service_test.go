@@ -15,7 +15,7 @@ func TestGetOrCreateStats(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
 	ctx := context.Background()
-	catalog, err := catalog.Load()
+	catalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatalf("failed to load catalog: %v", err)
 	}
@@ -74,7 +74,7 @@ func TestGetOrCreateStats(t *testing.T) {
 func TestListUsersIncludesStatsAndCollectionUsersInCaseInsensitiveOrder(t *testing.T) {
 	queries, sqlDB := db.NewTestDB(t)
 	defer sqlDB.Close()
-	appCatalog, err := catalog.Load()
+	appCatalog, err := catalog.Load("")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -106,3 +106,51 @@ func TestListUsersIncludesStatsAndCollectionUsersInCaseInsensitiveOrder(t *testi
 		t.Errorf("user order = %#v, want alpha then Zulu", users)
 	}
 }
+
+func TestTransferStatValueIsAllOrNothing(t *testing.T) {
+	queries, sqlDB := db.NewTestDB(t)
+	defer sqlDB.Close()
+	appCatalog, err := catalog.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := stats.NewService(queries, appCatalog.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := t.Context()
+	for _, id := range []string{"loser", "winner"} {
+		if _, err := svc.GetOrCreateStats(ctx, id, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	strength := func(id string) int64 {
+		t.Helper()
+		var value int64
+		if err := sqlDB.QueryRowContext(ctx,
+			`SELECT value FROM user_stats WHERE user_id = ? AND stat_name = 'strength'`, id,
+		).Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	if err := svc.TransferStatValue(ctx, "loser", "winner", "strength", 2); err != nil {
+		t.Fatal(err)
+	}
+	if strength("loser") != 1 || strength("winner") != 5 {
+		t.Fatalf("strength = %d, %d; want 1, 5", strength("loser"), strength("winner"))
+	}
+
+	if _, err := sqlDB.ExecContext(ctx, `
+CREATE TRIGGER refuse_credit BEFORE UPDATE ON user_stats WHEN NEW.value > OLD.value
+BEGIN SELECT RAISE(ABORT, 'refused'); END`); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.TransferStatValue(ctx, "loser", "winner", "strength", 1); err == nil {
+		t.Fatal("TransferStatValue() = nil, want the refused credit to fail")
+	}
+	if strength("loser") != 1 || strength("winner") != 5 {
+		t.Fatalf("strength = %d, %d; want the debit rolled back", strength("loser"), strength("winner"))
+	}
+}
@@ -0,0 +1,62 @@
+package tokens
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const keySize = 32
+
+// ParseKey decodes a base64-encoded 32-byte AES-256 key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode token encryption key: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("token encryption key must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(key []byte) (*sealer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	return &sealer{aead: aead}, nil
+}
+
+// seal encrypts plaintext, prefixing the random nonce. The account name is
+// bound as additional data so ciphertext cannot be swapped between rows.
+func (s *sealer) seal(account, plaintext string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, []byte(plaintext), []byte(account)), nil
+}
+
+func (s *sealer) open(account string, ciphertext []byte) (string, error) {
+	if len(ciphertext) < s.aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, sealed, []byte(account))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,415 @@
+package tokens
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+
+	"github.com/lukeramljak/charsibot/db"
+)
+
+// Accounts that can be authorized through the OAuth callback.
+const (
+	AccountBot      = "bot"
+	AccountStreamer = "streamer"
+)
+
+const (
+	// refreshMargin is how long before expiry a token is proactively refreshed.
+	refreshMargin = 10 * time.Minute
+	// refreshCheckInterval is how often Run looks for tokens nearing expiry.
+	refreshCheckInterval = time.Minute
+	// validateInterval is how often Run validates tokens, as Twitch requires
+	// apps to do at least hourly.
+	validateInterval = time.Hour
+	// maxRefreshBackoff caps how long a failing refresh waits before it is
+	// tried again. The wait doubles from refreshCheckInterval after each failure.
+	maxRefreshBackoff = time.Hour
+)
+
+// ErrNotFound is returned when no token has been stored for an account.
+var ErrNotFound = errors.New("token not found")
+
+// Token is a decrypted Twitch user access token.
+type Token struct {
+	Account      string
+	UserID       string
+	Login        string
+	AccessToken  string
+	RefreshToken string
+	Scopes       []string
+	ExpiresAt    time.Time
+	ValidatedAt  *time.Time
+}
+
+// Authenticator is the subset of the Helix client used to maintain tokens.
+type Authenticator interface {
+	RefreshUserAccessToken(refreshToken string) (*helix.RefreshTokenResponse, error)
+	ValidateToken(accessToken string) (bool, *helix.ValidateTokenResponse, error)
+}
+
+// Service stores encrypted tokens and keeps them valid. Twitch is never called
+// with mu held.
+type Service struct {
+	queries *db.Queries
+	sealer  *sealer
+	auth    Authenticator
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu sync.Mutex
+	// refreshing holds the refresh in flight for each account, so concurrent
+	// callers wait for it and never spend the same single-use refresh token
+	// twice.
+	refreshing map[string]*refreshCall
+	// failures holds the accounts whose last refresh failed, and when the
+	// refresh may be tried again.
+	failures map[string]refreshFailure
+}
+
+type refreshCall struct {
+	done  chan struct{}
+	token Token
+	err   error
+}
+
+type refreshFailure struct {
+	attempts int
+	retryAt  time.Time
+	err      error
+}
+
+// NewService creates a token Service that encrypts tokens with the given AES-256 key.
+func NewService(queries *db.Queries, key []byte, auth Authenticator, logger *slog.Logger) (*Service, error) {
+	if queries == nil {
+		return nil, errors.New("queries must not be nil")
+	}
+	if auth == nil {
+		return nil, errors.New("authenticator must not be nil")
+	}
+	sealer, err := newSealer(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		queries:    queries,
+		sealer:     sealer,
+		auth:       auth,
+		logger:     logger,
+		now:        time.Now,
+		refreshing: make(map[string]*refreshCall),
+		failures:   make(map[string]refreshFailure),
+	}, nil
+}
+
+// Save encrypts and stores a token, replacing any previous token for the
+// account. A new token ends any wait after failed refreshes.
+func (s *Service) Save(ctx context.Context, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, token.Account)
+	return s.save(ctx, token)
+}
+
+func (s *Service) save(ctx context.Context, token Token) error {
+	accessToken, err := s.sealer.seal(token.Account, token.AccessToken)
+	if err != nil {
+		return fmt.Errorf("encrypt access token: %w", err)
+	}
+	refreshToken, err := s.sealer.seal(token.Account, token.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("encrypt refresh token: %w", err)
+	}
+	return s.queries.UpsertOAuthToken(ctx, db.OAuthToken{
+		Account:      token.Account,
+		UserID:       token.UserID,
+		Login:        token.Login,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scopes:       token.Scopes,
+		ExpiresAt:    token.ExpiresAt,
+		ValidatedAt:  token.ValidatedAt,
+		UpdatedAt:    s.now(),
+	})
+}
+
+// Get returns the decrypted token for an account.
+func (s *Service) Get(ctx context.Context, account string) (Token, error) {
+	row, err := s.queries.GetOAuthToken(ctx, account)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Token{}, fmt.Errorf("%s: %w", account, ErrNotFound)
+	}
+	if err != nil {
+		return Token{}, err
+	}
+	return s.decrypt(row)
+}
+
+// AccessToken returns a usable access token for an account, refreshing it
+// first if it expires soon.
+func (s *Service) AccessToken(ctx context.Context, account string) (string, error) {
+	token, err := s.Get(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	if s.now().Add(refreshMargin).Before(token.ExpiresAt) {
+		return token.AccessToken, nil
+	}
+	token, err = s.refresh(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// Run keeps stored tokens fresh until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	s.ValidateAll(ctx)
+	refreshTicker := time.NewTicker(refreshCheckInterval)
+	defer refreshTicker.Stop()
+	validateTicker := time.NewTicker(validateInterval)
+	defer validateTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-refreshTicker.C:
+			s.RefreshExpiring(ctx)
+		case <-validateTicker.C:
+			s.ValidateAll(ctx)
+		}
+	}
+}
+
+// RefreshExpiring refreshes every stored token that expires within the refresh
+// margin. Accounts whose refresh keeps failing are tried less and less often
+// until they are re-authorized.
+func (s *Service) RefreshExpiring(ctx context.Context) {
+	tokens, err := s.list(ctx)
+	if err != nil {
+		s.logger.Error("list oauth tokens", "err", err)
+		return
+	}
+	for _, token := range tokens {
+		if s.now().Add(refreshMargin).Before(token.ExpiresAt) || s.backingOff(token.Account) {
+			continue
+		}
+		if _, err := s.refresh(ctx, token); err != nil {
+			s.logger.Error("refresh oauth token failed; re-authorize the account",
+				"account", token.Account,
+				"err", err,
+			)
+		}
+	}
+}
+
+// ValidateAll checks every stored token against Twitch's validate endpoint,
+// recording the granted scopes and refreshing tokens Twitch no longer accepts.
+func (s *Service) ValidateAll(ctx context.Context) {
+	tokens, err := s.list(ctx)
+	if err != nil {
+		s.logger.Error("list oauth tokens", "err", err)
+		return
+	}
+	for _, token := range tokens {
+		if err := s.validate(ctx, token); err != nil {
+			s.logger.Error("oauth token is invalid; re-authorize the account",
+				"account", token.Account,
+				"err", err,
+			)
+		}
+	}
+}
+
+func (s *Service) validate(ctx context.Context, token Token) error {
+	valid, resp, err := s.auth.ValidateToken(token.AccessToken)
+	if err != nil {
+		return fmt.Errorf("validate token: %w", err)
+	}
+	if !valid {
+		s.logger.Warn("oauth token rejected by twitch, refreshing", "account", token.Account)
+		token, err = s.refresh(ctx, token)
+		if err != nil {
+			return err
+		}
+		if valid, resp, err = s.auth.ValidateToken(token.AccessToken); err != nil {
+			return fmt.Errorf("validate refreshed token: %w", err)
+		}
+		if !valid {
+			return errors.New("refreshed token failed validation")
+		}
+	}
+	validated := token.AccessToken
+	now := s.now()
+	token.Scopes = resp.Data.Scopes
+	token.ExpiresAt = now.Add(time.Duration(resp.Data.ExpiresIn) * time.Second)
+	token.ValidatedAt = &now
+	if _, err := s.replace(ctx, token, validated); err != nil {
+		return fmt.Errorf("save validated token: %w", err)
+	}
+	s.logger.Debug("oauth token validated", "account", token.Account, "scopes", token.Scopes)
+	return nil
+}
+
+// refresh exchanges the token's refresh token for a new token. Only one
+// refresh per account is in flight at a time: other callers wait for it and
+// share its result.
+func (s *Service) refresh(ctx context.Context, token Token) (Token, error) {
+	s.mu.Lock()
+	if call, ok := s.refreshing[token.Account]; ok {
+		s.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.token, call.err
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		}
+	}
+	if failure, ok := s.failures[token.Account]; ok && s.now().Before(failure.retryAt) {
+		s.mu.Unlock()
+		return Token{}, fmt.Errorf("%s: refresh failed, next attempt at %s: %w",
+			token.Account, failure.retryAt.Format(time.RFC3339), failure.err)
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	s.refreshing[token.Account] = call
+	s.mu.Unlock()
+
+	call.token, call.err = s.exchange(ctx, token)
+
+	s.mu.Lock()
+	delete(s.refreshing, token.Account)
+	if call.err != nil {
+		failure := s.failures[token.Account]
+		failure.attempts++
+		failure.retryAt = s.now().Add(refreshBackoff(failure.attempts))
+		failure.err = call.err
+		s.failures[token.Account] = failure
+	} else {
+		delete(s.failures, token.Account)
+	}
+	s.mu.Unlock()
+	close(call.done)
+	return call.token, call.err
+}
+
+// exchange calls Twitch to refresh token. A token that was already replaced,
+// by an earlier refresh or a re-authorization, is returned as stored instead.
+func (s *Service) exchange(ctx context.Context, token Token) (Token, error) {
+	current, err := s.Get(ctx, token.Account)
+	if err != nil {
+		return Token{}, err
+	}
+	if current.AccessToken != token.AccessToken {
+		return current, nil
+	}
+	if current.RefreshToken == "" {
+		return Token{}, fmt.Errorf("%s: no refresh token stored", token.Account)
+	}
+	resp, err := s.auth.RefreshUserAccessToken(current.RefreshToken)
+	if err != nil {
+		return Token{}, fmt.Errorf("refresh token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, fmt.Errorf("refresh token: %d %s", resp.StatusCode, resp.ErrorMessage)
+	}
+	refreshed := current
+	refreshed.AccessToken = resp.Data.AccessToken
+	if resp.Data.RefreshToken != "" {
+		refreshed.RefreshToken = resp.Data.RefreshToken
+	}
+	if len(resp.Data.Scopes) > 0 {
+		refreshed.Scopes = resp.Data.Scopes
+	}
+	refreshed.ExpiresAt = s.now().Add(time.Duration(resp.Data.ExpiresIn) * time.Second)
+	stored, err := s.replace(ctx, refreshed, current.AccessToken)
+	if err != nil {
+		return Token{}, fmt.Errorf("save refreshed token: %w", err)
+	}
+	s.logger.Info("oauth token refreshed", "account", stored.Account, "expires_at", stored.ExpiresAt)
+	return stored, nil
+}
+
+// replace stores token if the stored token still has the access token
+// previous, and returns the token now stored. A token saved in the meantime,
+// such as by a re-authorization, is kept.
+func (s *Service) replace(ctx context.Context, token Token, previous string) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.Get(ctx, token.Account)
+	if err != nil {
+		return Token{}, err
+	}
+	if current.AccessToken != previous {
+		return current, nil
+	}
+	if err := s.save(ctx, token); err != nil {
+		return Token{}, err
+	}
+	return token, nil
+}
+
+// backingOff reports whether the account's last refresh failed too recently to
+// try again.
+func (s *Service) backingOff(account string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failure, ok := s.failures[account]
+	return ok && s.now().Before(failure.retryAt)
+}
+
+// refreshBackoff returns how long to wait after the given number of failed
+// refreshes in a row.
+func refreshBackoff(attempts int) time.Duration {
+	backoff := refreshCheckInterval
+	for range attempts - 1 {
+		if backoff >= maxRefreshBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	return min(backoff, maxRefreshBackoff)
+}
+
+func (s *Service) list(ctx context.Context) ([]Token, error) {
+	rows, err := s.queries.ListOAuthTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]Token, 0, len(rows))
+	for _, row := range rows {
+		token, err := s.decrypt(row)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (s *Service) decrypt(row db.OAuthToken) (Token, error) {
+	accessToken, err := s.sealer.open(row.Account, row.AccessToken)
+	if err != nil {
+		return Token{}, fmt.Errorf("decrypt %s access token: %w", row.Account, err)
+	}
+	refreshToken, err := s.sealer.open(row.Account, row.RefreshToken)
+	if err != nil {
+		return Token{}, fmt.Errorf("decrypt %s refresh token: %w", row.Account, err)
+	}
+	return Token{
+		Account:      row.Account,
+		UserID:       row.UserID,
+		Login:        row.Login,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scopes:       row.Scopes,
+		ExpiresAt:    row.ExpiresAt,
+		ValidatedAt:  row.ValidatedAt,
+	}, nil
+}
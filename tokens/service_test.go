@@ -0,0 +1,224 @@
+package tokens_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+	_ "modernc.org/sqlite"
+
+	"github.com/lukeramljak/charsibot/db"
+	"github.com/lukeramljak/charsibot/tokens"
+)
+
+type fakeAuthenticator struct {
+	refreshes   int
+	validTokens map[string]bool
+}
+
+func (f *fakeAuthenticator) RefreshUserAccessToken(refreshToken string) (*helix.RefreshTokenResponse, error) {
+	f.refreshes++
+	if refreshToken == "revoked" {
+		return &helix.RefreshTokenResponse{
+			ResponseCommon: helix.ResponseCommon{StatusCode: http.StatusBadRequest, ErrorMessage: "Invalid refresh token"},
+		}, nil
+	}
+	resp := &helix.RefreshTokenResponse{ResponseCommon: helix.ResponseCommon{StatusCode: http.StatusOK}}
+	resp.Data.AccessToken = "refreshed-access"
+	resp.Data.RefreshToken = "refreshed-refresh"
+	resp.Data.ExpiresIn = 14400
+	f.validTokens["refreshed-access"] = true
+	return resp, nil
+}
+
+func (f *fakeAuthenticator) ValidateToken(accessToken string) (bool, *helix.ValidateTokenResponse, error) {
+	resp := &helix.ValidateTokenResponse{}
+	if !f.validTokens[accessToken] {
+		resp.StatusCode = http.StatusUnauthorized
+		return false, resp, nil
+	}
+	resp.StatusCode = http.StatusOK
+	resp.Data.Scopes = []string{"user:bot", "user:write:chat"}
+	resp.Data.ExpiresIn = 3600
+	return true, resp, nil
+}
+
+func newTestService(t *testing.T) (*tokens.Service, *fakeAuthenticator, *db.Queries) {
+	t.Helper()
+	queries, sqlDB := db.NewTestDB(t)
+	t.Cleanup(func() { sqlDB.Close() })
+	auth := &fakeAuthenticator{validTokens: map[string]bool{}}
+	svc, err := tokens.NewService(queries, bytes.Repeat([]byte{7}, 32), auth, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc, auth, queries
+}
+
+func TestSaveEncryptsTokensAtRest(t *testing.T) {
+	svc, _, queries := newTestService(t)
+	ctx := context.Background()
+	token := tokens.Token{
+		Account:      tokens.AccountBot,
+		UserID:       "bot-1",
+		Login:        "charsibot",
+		AccessToken:  "plain-access",
+		RefreshToken: "plain-refresh",
+		Scopes:       []string{"user:bot"},
+		ExpiresAt:    time.Now().Add(time.Hour).UTC(),
+	}
+	if err := svc.Save(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	row, err := queries.GetOAuthToken(ctx, tokens.AccountBot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(row.AccessToken, []byte("plain-access")) || bytes.Contains(row.RefreshToken, []byte("plain-refresh")) {
+		t.Fatal("tokens are stored in plaintext")
+	}
+
+	got, err := svc.Get(ctx, tokens.AccountBot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessToken != "plain-access" || got.RefreshToken != "plain-refresh" || got.UserID != "bot-1" {
+		t.Fatalf("Get() = %#v", got)
+	}
+}
+
+func TestGetMissingToken(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	if _, err := svc.Get(context.Background(), tokens.AccountStreamer); !errors.Is(err, tokens.ErrNotFound) {
+		t.Fatalf("Get() error = %v, want tokens.ErrNotFound", err)
+	}
+}
+
+func TestAccessTokenRefreshesBeforeExpiry(t *testing.T) {
+	svc, auth, _ := newTestService(t)
+	ctx := context.Background()
+	if err := svc.Save(ctx, tokens.Token{
+		Account:      tokens.AccountStreamer,
+		AccessToken:  "old-access",
+		RefreshToken: "old-refresh",
+		ExpiresAt:    time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.AccessToken(ctx, tokens.AccountStreamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "refreshed-access" || auth.refreshes != 1 {
+		t.Fatalf("AccessToken() = %q after %d refreshes", got, auth.refreshes)
+	}
+	stored, err := svc.Get(ctx, tokens.AccountStreamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RefreshToken != "refreshed-refresh" {
+		t.Errorf("refresh token = %q, want rotated token", stored.RefreshToken)
+	}
+
+	if _, err = svc.AccessToken(ctx, tokens.AccountStreamer); err != nil {
+		t.Fatal(err)
+	}
+	if auth.refreshes != 1 {
+		t.Errorf("refreshes = %d, want fresh token reused", auth.refreshes)
+	}
+}
+
+func TestValidateAllRecordsScopesAndRefreshesRejectedTokens(t *testing.T) {
+	svc, auth, _ := newTestService(t)
+	ctx := context.Background()
+	auth.validTokens["good-access"] = true
+	for _, token := range []tokens.Token{
+		{Account: tokens.AccountBot, AccessToken: "good-access", RefreshToken: "r1", ExpiresAt: time.Now().Add(time.Hour)},
+		{Account: tokens.AccountStreamer, AccessToken: "stale-access", RefreshToken: "r2", ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		if err := svc.Save(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc.ValidateAll(ctx)
+
+	bot, err := svc.Get(ctx, tokens.AccountBot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bot.ValidatedAt == nil || len(bot.Scopes) != 2 {
+		t.Errorf("bot token = %#v, want validation recorded", bot)
+	}
+	streamer, err := svc.Get(ctx, tokens.AccountStreamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamer.AccessToken != "refreshed-access" || streamer.ValidatedAt == nil {
+		t.Errorf("streamer token = %#v, want refreshed and validated", streamer)
+	}
+}
+
+func TestAccessTokenFailsWhenRefreshRejected(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	ctx := context.Background()
+	if err := svc.Save(ctx, tokens.Token{
+		Account:      tokens.AccountBot,
+		AccessToken:  "old",
+		RefreshToken: "revoked",
+		ExpiresAt:    time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AccessToken(ctx, tokens.AccountBot); err == nil {
+		t.Fatal("expected refresh failure")
+	}
+}
+
+func TestFailingRefreshBacksOffUntilReauthorized(t *testing.T) {
+	svc, auth, _ := newTestService(t)
+	ctx := context.Background()
+	revoked := tokens.Token{
+		Account:      tokens.AccountBot,
+		AccessToken:  "old",
+		RefreshToken: "revoked",
+		ExpiresAt:    time.Now().Add(-time.Minute),
+	}
+	if err := svc.Save(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	svc.RefreshExpiring(ctx)
+	svc.RefreshExpiring(ctx)
+	if _, err := svc.AccessToken(ctx, tokens.AccountBot); err == nil {
+		t.Fatal("expected refresh failure while backing off")
+	}
+	if auth.refreshes != 1 {
+		t.Fatalf("refreshes = %d, want 1 while backing off", auth.refreshes)
+	}
+
+	revoked.AccessToken, revoked.RefreshToken = "reauthorized", "new-refresh"
+	if err := svc.Save(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+	svc.RefreshExpiring(ctx)
+	if auth.refreshes != 2 {
+		t.Fatalf("refreshes = %d, want a re-authorized account refreshed again", auth.refreshes)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	if _, err := tokens.ParseKey("c2hvcnQ="); err == nil {
+		t.Error("expected short key to be rejected")
+	}
+	if _, err := tokens.ParseKey("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="); err != nil {
+		t.Errorf("tokens.ParseKey() error = %v", err)
+	}
+}